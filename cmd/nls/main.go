@@ -2,59 +2,425 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
 
 	"nls/internal/app"
+	"nls/internal/inventory"
+	"nls/internal/known"
+	"nls/internal/netif"
+	"nls/internal/notes"
+	"nls/internal/output"
 	"nls/internal/progress"
 	"nls/internal/scanner"
 )
 
 var version = "dev"
 
-func parseArgs(arguments []string) (showVersion bool, cidr string) {
+// cliOptions holds the parsed command-line arguments.
+type cliOptions struct {
+	showVersion bool
+	targets     []string
+	exclude     []string
+	excludeFile string
+	pick        bool
+	method      string
+	probePorts  string
+	ouiFile     string
+	output      string
+	fromXML     string
+	ports       string
+	topPorts    int
+	inventory   string
+	noInventory bool
+	watch       bool
+	interval    time.Duration
+	known       string
+	failUnknown bool
+	notes       string
+}
+
+// parseArgs parses the command line. A leading "watch" selects watch mode;
+// flags and targets follow as for a single scan. Returns the flag package's
+// error, already reported with the usage, for invalid flags.
+func parseArgs(arguments []string) (cliOptions, error) {
+	watch := len(arguments) > 0 && arguments[0] == "watch"
+	if watch {
+		arguments = arguments[1:]
+	}
+
 	fs := flag.NewFlagSet("nls", flag.ContinueOnError)
 	versionFlag := fs.Bool("version", false, "print version and exit")
 	vFlag := fs.Bool("v", false, "print version and exit")
-	_ = fs.Parse(arguments)
-	if fs.NArg() > 0 {
-		cidr = fs.Arg(0)
+	methodFlag := fs.String("method", app.MethodNmap, "host discovery method: nmap, arp, icmp or tcp")
+	probePortsFlag := fs.String("probe-ports", "", "comma-separated TCP ports for --method tcp (default 22,80,443,3389)")
+	ouiFileFlag := fs.String("oui-file", "", "MAC vendor database for --method arp, in nmap-mac-prefixes or IEEE oui.txt format (default the nmap or arp-scan file, if installed)")
+	outputUsage := "print results as " + strings.Join(output.Formats, ", ") + " instead of starting the TUI (default table when stdout is not a terminal)"
+	outputFlag := fs.String("output", "", outputUsage)
+	fs.StringVar(outputFlag, "o", "", outputUsage)
+	excludeFlag := fs.String("exclude", "", "comma-separated targets to leave out of the scan")
+	excludeFileFlag := fs.String("exclude-file", "", "file of targets to leave out of the scan, one per line")
+	pickFlag := fs.Bool("pick", false, "choose which local network to scan when no target is given")
+	fromXMLFlag := fs.String("from-xml", "", "read hosts from a saved nmap XML report (nmap -oX) instead of scanning")
+	portsFlag := fs.String("ports", "", "comma-separated TCP ports to scan on every host found, e.g. 22,80,443,3389")
+	topPortsFlag := fs.Int("top-ports", 0, "scan the N most common TCP ports on every host found")
+	inventoryFlag := fs.String("inventory", "", "file recording every device found across runs (default $XDG_DATA_HOME/nls/inventory.jsonl)")
+	noInventoryFlag := fs.Bool("no-inventory", false, "do not record the devices found in the inventory")
+	knownFlag := fs.String("known", "", "CSV file of known devices: MAC, label, owner, expected IP, tags (default $XDG_CONFIG_HOME/nls/known-devices.csv, if present)")
+	failUnknownFlag := fs.Bool("fail-unknown", false, "exit with status 3 when --output finds devices missing from the known-devices file")
+	notesFlag := fs.String("notes", "", "file keeping the aliases, notes and tags set in the TUI (default $XDG_DATA_HOME/nls/notes.jsonl)")
+	intervalFlag := fs.Duration("interval", 0, "time between scans with watch (default 5m), or between automatic rescans in the TUI, e.g. 30s or 5m")
+	if err := fs.Parse(arguments); err != nil {
+		return cliOptions{}, err
+	}
+
+	// Targets and flags may be interleaved: keep parsing after each target.
+	var targets []string
+	for fs.NArg() > 0 {
+		targets = append(targets, fs.Arg(0))
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return cliOptions{}, err
+		}
+	}
+
+	opts := cliOptions{
+		showVersion: *versionFlag || *vFlag,
+		targets:     targets,
+		excludeFile: *excludeFileFlag,
+		pick:        *pickFlag,
+		method:      *methodFlag,
+		probePorts:  *probePortsFlag,
+		ouiFile:     *ouiFileFlag,
+		output:      *outputFlag,
+		fromXML:     *fromXMLFlag,
+		ports:       *portsFlag,
+		topPorts:    *topPortsFlag,
+		inventory:   *inventoryFlag,
+		noInventory: *noInventoryFlag,
+		watch:       watch,
+		interval:    *intervalFlag,
+		known:       *knownFlag,
+		failUnknown: *failUnknownFlag,
+		notes:       *notesFlag,
+	}
+	for _, spec := range strings.Split(*excludeFlag, ",") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			opts.exclude = append(opts.exclude, spec)
+		}
+	}
+	return opts, nil
+}
+
+// readExcludeFile reads the targets listed in path.
+func readExcludeFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scanner.ReadTargetList(f)
+}
+
+// localTargets chooses what to scan when no target is given: the IPv4
+// networks of the default-route interface, or a network picked from a list
+// when pick is set or there is no default route. Notes and the list are
+// written to out; the choice is read from in when interactive.
+func localTargets(e netif.Enumerator, pick bool, in io.Reader, out io.Writer, interactive bool) ([]string, error) {
+	networks, err := netif.LocalNetworks(e)
+	if err != nil {
+		return nil, fmt.Errorf("detect local networks: %w", err)
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("no target given and no local network found: specify a network range to scan (e.g., nls 192.168.1.0/24)")
+	}
+
+	if !pick {
+		if defaults := netif.DefaultNetworks(networks); len(defaults) > 0 {
+			targets := make([]string, 0, len(defaults))
+			for _, n := range defaults {
+				fmt.Fprintf(out, "No target given; scanning %s\n", n)
+				targets = append(targets, n.Prefix.String())
+			}
+			return targets, nil
+		}
+	}
+
+	if !interactive {
+		candidates := make([]string, 0, len(networks))
+		for _, n := range networks {
+			candidates = append(candidates, n.String())
+		}
+		return nil, fmt.Errorf("no target given: specify a network range to scan, e.g. one of %s", strings.Join(candidates, ", "))
+	}
+
+	n, err := netif.Prompt(in, out, networks)
+	if err != nil {
+		return nil, err
+	}
+	return []string{n.Prefix.String()}, nil
+}
+
+// openInventory opens the inventory selected by opts, or returns nil when
+// it is disabled. Hosts read from a report are not recorded: they were not
+// seen now.
+func openInventory(opts cliOptions) (inventory.Store, error) {
+	if opts.noInventory || opts.fromXML != "" {
+		return nil, nil
+	}
+	path := opts.inventory
+	if path == "" {
+		var err error
+		if path, err = inventory.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("%w (use --inventory or --no-inventory)", err)
+		}
+	}
+	store, err := inventory.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w (use --no-inventory to scan without it)", err)
+	}
+	return store, nil
+}
+
+// openNotes opens the file keeping the aliases, notes and tags set in the
+// TUI: the --notes file, or the default one.
+func openNotes(opts cliOptions) (notes.Store, error) {
+	path := opts.notes
+	if path == "" {
+		var err error
+		if path, err = notes.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("%w (use --notes)", err)
+		}
+	}
+	return notes.Open(path)
+}
+
+// loadKnownDevices loads the known-devices list selected by opts: the
+// --known file, or the default one when it exists. It returns nil when
+// there is none.
+func loadKnownDevices(opts cliOptions) (*known.List, error) {
+	if opts.known != "" {
+		return known.Load(opts.known)
+	}
+	path, err := known.DefaultPath()
+	if err != nil {
+		return nil, nil
+	}
+	list, err := known.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return list, err
+}
+
+// exitStatus is an error that ends nls with a specific exit status. A nil
+// err exits without a message.
+type exitStatus struct {
+	code int
+	err  error
+}
+
+func (e exitStatus) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e exitStatus) Unwrap() error {
+	return e.err
+}
+
+// usageExit is the exit status of an invalid command line, as for the
+// flag package's own ExitOnError.
+const usageExit = 2
+
+// unknownExit is the exit status of a scan with --fail-unknown that found
+// unknown devices, distinct from the status 1 of errors.
+const unknownExit = 3
+
+// Exit statuses of nls diff, as for diff(1).
+const (
+	diffExitDifferent = 1
+	diffExitTrouble   = 2
+)
+
+// runDiff compares two saved results: nls diff [-o json] old new. It
+// exits with diffExitDifferent when they differ and diffExitTrouble on
+// errors.
+func runDiff(arguments []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("nls diff", flag.ContinueOnError)
+	outputUsage := "print the differences as " + output.FormatTable + " or " + output.FormatJSON
+	outputFlag := fs.String("output", output.FormatTable, outputUsage)
+	fs.StringVar(outputFlag, "o", output.FormatTable, outputUsage)
+	if err := fs.Parse(arguments); err != nil {
+		return exitStatus{code: diffExitTrouble, err: err}
+	}
+	// Files and flags may be interleaved, as for a scan.
+	var files []string
+	for fs.NArg() > 0 {
+		files = append(files, fs.Arg(0))
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return exitStatus{code: diffExitTrouble, err: err}
+		}
+	}
+	if len(files) != 2 {
+		return exitStatus{code: diffExitTrouble, err: fmt.Errorf("usage: nls diff [-o json] <old result> <new result>")}
+	}
+
+	different, err := app.DiffResults(context.Background(), stdout, *outputFlag, files[0], files[1])
+	if err != nil {
+		return exitStatus{code: diffExitTrouble, err: err}
 	}
-	showVersion = *versionFlag || *vFlag
-	return
+	if different {
+		return exitStatus{code: diffExitDifferent}
+	}
+	return nil
 }
 
 func run() error {
-	showVersion, cidr := parseArgs(os.Args[1:])
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		return runDiff(os.Args[2:], os.Stdout)
+	}
+	opts, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return exitStatus{code: usageExit, err: err}
+	}
 
-	if showVersion {
+	if opts.showVersion {
 		fmt.Printf("nls %s\n", version)
 		return nil
 	}
 
 	config := app.DefaultConfig()
-	config.CIDR = cidr
+	config.Targets = opts.targets
+	config.FromXML = opts.fromXML
+	if len(config.Targets) == 0 && config.FromXML == "" {
+		targets, err := localTargets(netif.System{}, opts.pick, os.Stdin, os.Stderr, term.IsTerminal(int(os.Stdin.Fd())))
+		if err != nil {
+			return err
+		}
+		config.Targets = targets
+	}
+	config.Exclude = opts.exclude
+	if opts.excludeFile != "" {
+		exclude, err := readExcludeFile(opts.excludeFile)
+		if err != nil {
+			return fmt.Errorf("read --exclude-file: %w", err)
+		}
+		config.Exclude = append(config.Exclude, exclude...)
+	}
+	config.Method = opts.method
+	if opts.probePorts != "" {
+		ports, err := scanner.ParsePorts(opts.probePorts)
+		if err != nil {
+			return fmt.Errorf("invalid --probe-ports: %w", err)
+		}
+		config.ProbePorts = ports
+	}
+	config.OUIFile = opts.ouiFile
+	if opts.ports != "" {
+		ports, err := scanner.ParsePorts(opts.ports)
+		if err != nil {
+			return fmt.Errorf("invalid --ports: %w", err)
+		}
+		config.Ports = ports
+	}
+	config.TopPorts = opts.topPorts
+	config.Output = opts.output
+	if config.Output == "" && !term.IsTerminal(int(os.Stdout.Fd())) {
+		config.Output = output.FormatTable
+	}
+	config.Watch = opts.watch
+	config.Interval = opts.interval
+	config.FailUnknown = opts.failUnknown
 
-	var progressReporter progress.Reporter
-	if config.ShowProgress {
-		progressReporter = progress.NewSpinner()
-	} else {
-		progressReporter = progress.NoOp{}
+	// In the TUI the scan runs inside the alternate screen, so the UI polls
+	// a tracker to draw its own indicator. Otherwise results go to stdout
+	// and the spinner draws on stderr, but only when someone is watching it.
+	var reporter progress.Reporter = progress.NoOp{}
+	tracker := progress.NewTracker()
+	switch {
+	case config.Output == "" && !config.Watch:
+		reporter = tracker
+	case config.ShowProgress && !config.Watch && term.IsTerminal(int(os.Stderr.Fd())):
+		reporter = progress.NewSpinner()
 	}
-	nmapScanner := scanner.NewNmapScanner(progressReporter)
 
-	application := app.New(config, nmapScanner)
+	s, err := app.NewScanner(config, reporter)
+	if err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	store, err := openInventory(opts)
+	if err != nil {
+		return err
+	}
+
+	list, err := loadKnownDevices(opts)
+	if err != nil {
+		return err
+	}
+	if config.FailUnknown && list == nil {
+		return fmt.Errorf("--fail-unknown needs a known-devices file: use --known")
+	}
+
+	application := app.New(config, s).WithProgress(tracker)
+	if store != nil {
+		application = application.WithInventory(store)
+	}
+	if list != nil {
+		application = application.WithKnownDevices(list)
+	}
+	if !config.Watch {
+		store, err := openNotes(opts)
+		if err != nil {
+			return err
+		}
+		application = application.WithNotes(store)
+	}
+
+	// Watch runs until interrupted, timing out each scan by itself.
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if config.Watch {
+		ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), config.Timeout)
+	}
 	defer cancel()
 
-	return application.Run(ctx)
+	err = application.Run(ctx)
+	if errors.Is(err, app.ErrUnknownDevices) {
+		return exitStatus{code: unknownExit, err: err}
+	}
+	return err
 }
 
 func main() {
 	if err := run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		code := 1
+		var status exitStatus
+		if errors.As(err, &status) {
+			code, err = status.code, status.err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
 	}
 }
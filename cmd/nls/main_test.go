@@ -1,30 +1,270 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nls/internal/netif"
+)
 
 func TestParseArgs(t *testing.T) {
 	tests := []struct {
 		name            string
 		args            []string
 		wantShowVersion bool
-		wantCIDR        string
+		wantTargets     []string
+		wantExclude     []string
+		wantMethod      string
+		wantProbePorts  string
+		wantOUIFile     string
+		wantOutput      string
+		wantFromXML     string
+		wantPorts       string
+		wantTopPorts    int
+		wantInventory   string
+		wantNoInventory bool
+		wantWatch       bool
+		wantInterval    time.Duration
 	}{
-		{name: "--version flag", args: []string{"--version"}, wantShowVersion: true, wantCIDR: ""},
-		{name: "-v flag", args: []string{"-v"}, wantShowVersion: true, wantCIDR: ""},
-		{name: "CIDR arg", args: []string{"10.0.0.0/24"}, wantShowVersion: false, wantCIDR: "10.0.0.0/24"},
-		{name: "no args", args: []string{}, wantShowVersion: false, wantCIDR: ""},
-		{name: "CIDR with version flag", args: []string{"--version", "10.0.0.0/24"}, wantShowVersion: true, wantCIDR: "10.0.0.0/24"},
+		{name: "--version flag", args: []string{"--version"}, wantShowVersion: true, wantTargets: nil, wantMethod: "nmap"},
+		{name: "-v flag", args: []string{"-v"}, wantShowVersion: true, wantTargets: nil, wantMethod: "nmap"},
+		{name: "CIDR arg", args: []string{"10.0.0.0/24"}, wantShowVersion: false, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap"},
+		{name: "no args", args: []string{}, wantShowVersion: false, wantTargets: nil, wantMethod: "nmap"},
+		{name: "CIDR with version flag", args: []string{"--version", "10.0.0.0/24"}, wantShowVersion: true, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap"},
+		{name: "icmp method", args: []string{"--method=icmp", "10.0.0.0/24"}, wantShowVersion: false, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "icmp"},
+		{name: "tcp method with ports", args: []string{"--method", "tcp", "--probe-ports", "22,443", "10.0.0.0/24"}, wantShowVersion: false, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "tcp", wantProbePorts: "22,443"},
+		{name: "arp method", args: []string{"--method", "arp", "10.0.0.0/24"}, wantShowVersion: false, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "arp"},
+		{name: "arp method with OUI file", args: []string{"--method", "arp", "--oui-file", "oui.txt", "10.0.0.0/24"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "arp", wantOUIFile: "oui.txt"},
+		{name: "long output flag", args: []string{"--output", "json", "10.0.0.0/24"}, wantShowVersion: false, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantOutput: "json"},
+		{name: "short output flag", args: []string{"-o", "csv", "10.0.0.0/24"}, wantShowVersion: false, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantOutput: "csv"},
+		{name: "multiple targets", args: []string{"10.0.0.0/24", "10.0.1.1-50", "nas.local"}, wantTargets: []string{"10.0.0.0/24", "10.0.1.1-50", "nas.local"}, wantMethod: "nmap"},
+		{name: "exclusions", args: []string{"--exclude", "10.0.0.1, 10.0.0.5-9", "10.0.0.0/24"}, wantTargets: []string{"10.0.0.0/24"}, wantExclude: []string{"10.0.0.1", "10.0.0.5-9"}, wantMethod: "nmap"},
+		{name: "flags after targets", args: []string{"10.0.0.0/24", "--method", "icmp", "10.0.1.0/24", "-o", "json"}, wantTargets: []string{"10.0.0.0/24", "10.0.1.0/24"}, wantMethod: "icmp", wantOutput: "json"},
+		{name: "from XML", args: []string{"--from-xml", "scan.xml", "-o", "csv"}, wantMethod: "nmap", wantOutput: "csv", wantFromXML: "scan.xml"},
+		{name: "port list", args: []string{"--ports", "22,3389", "10.0.0.0/24"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantPorts: "22,3389"},
+		{name: "top ports", args: []string{"10.0.0.0/24", "--method", "arp", "--top-ports", "100"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "arp", wantTopPorts: 100},
+		{name: "inventory file", args: []string{"--inventory", "devices.jsonl", "10.0.0.0/24"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantInventory: "devices.jsonl"},
+		{name: "watch", args: []string{"watch", "10.0.0.0/24", "--interval", "30s", "-o", "json"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantOutput: "json", wantWatch: true, wantInterval: 30 * time.Second},
+		{name: "TUI auto-refresh", args: []string{"--interval=10m", "10.0.0.0/24"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantInterval: 10 * time.Minute},
+		{name: "watch as a target after flags", args: []string{"--method", "icmp", "watch"}, wantTargets: []string{"watch"}, wantMethod: "icmp"},
+		{name: "no inventory", args: []string{"10.0.0.0/24", "--no-inventory"}, wantTargets: []string{"10.0.0.0/24"}, wantMethod: "nmap", wantNoInventory: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotVersion, gotCIDR := parseArgs(tt.args)
-			if gotVersion != tt.wantShowVersion {
-				t.Errorf("showVersion = %v, want %v", gotVersion, tt.wantShowVersion)
+			got, err := parseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseArgs() error = %v", err)
+			}
+			if got.showVersion != tt.wantShowVersion {
+				t.Errorf("showVersion = %v, want %v", got.showVersion, tt.wantShowVersion)
+			}
+			if !reflect.DeepEqual(got.targets, tt.wantTargets) {
+				t.Errorf("targets = %q, want %q", got.targets, tt.wantTargets)
+			}
+			if !reflect.DeepEqual(got.exclude, tt.wantExclude) {
+				t.Errorf("exclude = %q, want %q", got.exclude, tt.wantExclude)
+			}
+			if got.method != tt.wantMethod {
+				t.Errorf("method = %q, want %q", got.method, tt.wantMethod)
+			}
+			if got.probePorts != tt.wantProbePorts {
+				t.Errorf("probePorts = %q, want %q", got.probePorts, tt.wantProbePorts)
+			}
+			if got.ouiFile != tt.wantOUIFile {
+				t.Errorf("ouiFile = %q, want %q", got.ouiFile, tt.wantOUIFile)
+			}
+			if got.output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", got.output, tt.wantOutput)
+			}
+			if got.fromXML != tt.wantFromXML {
+				t.Errorf("fromXML = %q, want %q", got.fromXML, tt.wantFromXML)
+			}
+			if got.ports != tt.wantPorts {
+				t.Errorf("ports = %q, want %q", got.ports, tt.wantPorts)
 			}
-			if gotCIDR != tt.wantCIDR {
-				t.Errorf("cidr = %q, want %q", gotCIDR, tt.wantCIDR)
+			if got.topPorts != tt.wantTopPorts {
+				t.Errorf("topPorts = %d, want %d", got.topPorts, tt.wantTopPorts)
+			}
+			if got.watch != tt.wantWatch || got.interval != tt.wantInterval {
+				t.Errorf("watch = %v every %v, want %v every %v", got.watch, got.interval, tt.wantWatch, tt.wantInterval)
+			}
+			if got.inventory != tt.wantInventory || got.noInventory != tt.wantNoInventory {
+				t.Errorf("inventory = %q, %v, want %q, %v", got.inventory, got.noInventory, tt.wantInventory, tt.wantNoInventory)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "invalid value after a target", args: []string{"10.0.0.0/24", "-o", "json", "--top-ports", "abc"}, wantErr: "-top-ports"},
+		{name: "unknown flag before others", args: []string{"--bogus", "--from-xml", "r.xml"}, wantErr: "bogus"},
+		{name: "invalid interval", args: []string{"watch", "--interval", "soon", "10.0.0.0/24"}, wantErr: "-interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseArgs(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseArgs() error = %v; want an error about %q", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestReadExcludeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclude.txt")
+	if err := os.WriteFile(path, []byte("# printers\n10.0.0.5\n10.0.0.6 # old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readExcludeFile(path)
+	if err != nil {
+		t.Fatalf("readExcludeFile() error = %v", err)
+	}
+	if want := []string{"10.0.0.5", "10.0.0.6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readExcludeFile() = %v, want %v", got, want)
+	}
+
+	if _, err := readExcludeFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("readExcludeFile() should fail for a missing file")
+	}
+}
+
+// fakeInterfaces is a netif.Enumerator with fixed interfaces.
+type fakeInterfaces struct {
+	ifaces      []netif.Interface
+	defaultName string
+}
+
+func (f fakeInterfaces) Interfaces() ([]netif.Interface, error) { return f.ifaces, nil }
+
+func (f fakeInterfaces) DefaultInterface() (string, error) { return f.defaultName, nil }
+
+func TestLocalTargets(t *testing.T) {
+	ifaces := []netif.Interface{
+		{Name: "eth0", Up: true, Addrs: []netip.Prefix{netip.MustParsePrefix("192.168.1.5/24")}},
+		{Name: "wlan0", Up: true, Addrs: []netip.Prefix{netip.MustParsePrefix("10.20.0.7/24")}},
+	}
+
+	tests := []struct {
+		name        string
+		defaultName string
+		pick        bool
+		interactive bool
+		input       string
+		want        []string
+		wantErr     string
+	}{
+		{name: "default route", defaultName: "wlan0", want: []string{"10.20.0.0/24"}},
+		{name: "pick overrides default", defaultName: "wlan0", pick: true, interactive: true, input: "2\n", want: []string{"192.168.1.0/24"}},
+		{name: "no default route prompts", interactive: true, input: "1\n", want: []string{"192.168.1.0/24"}},
+		{name: "no default route without terminal", wantErr: "192.168.1.0/24 (eth0), 10.20.0.0/24 (wlan0)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			e := fakeInterfaces{ifaces: ifaces, defaultName: tt.defaultName}
+			got, err := localTargets(e, tt.pick, strings.NewReader(tt.input), &out, tt.interactive)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("localTargets() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("localTargets() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("localTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := localTargets(fakeInterfaces{}, false, strings.NewReader(""), io.Discard, true); err == nil {
+		t.Error("localTargets() should fail without any local network")
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	old := write("old.json", `[{"ip": "192.168.1.1", "mac": "00:11:22:33:44:55"}]`)
+	same := write("same.json", `[{"ip": "192.168.1.1", "mac": "00:11:22:33:44:55"}]`)
+	grown := write("new.json", `[{"ip": "192.168.1.1", "mac": "00:11:22:33:44:55"}, {"ip": "192.168.1.9"}]`)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{name: "same", args: []string{old, same}, wantOut: "no changes"},
+		{name: "different", args: []string{old, grown}, wantCode: diffExitDifferent, wantOut: "192.168.1.9"},
+		{name: "flag after files", args: []string{old, grown, "-o", "json"}, wantCode: diffExitDifferent, wantOut: `"added": [`},
+		{name: "one file", args: []string{old}, wantCode: diffExitTrouble},
+		{name: "missing file", args: []string{old, filepath.Join(dir, "missing.json")}, wantCode: diffExitTrouble},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runDiff(tt.args, &out)
+			code := 0
+			var status exitStatus
+			if errors.As(err, &status) {
+				code = status.code
+			} else if err != nil {
+				t.Fatalf("runDiff() error = %v, want an exitStatus", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("runDiff() exit status = %d, want %d (err = %v)", code, tt.wantCode, err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("runDiff() output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestLoadKnownDevices(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+
+	// No list anywhere is not an error
+	if list, err := loadKnownDevices(cliOptions{}); list != nil || err != nil {
+		t.Errorf("loadKnownDevices() without a file = %v, %v; want nil, nil", list, err)
+	}
+	if _, err := loadKnownDevices(cliOptions{known: filepath.Join(config, "missing.csv")}); err == nil {
+		t.Error("loadKnownDevices() should fail for a missing --known file")
+	}
+
+	if err := os.MkdirAll(filepath.Join(config, "nls"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config, "nls", "known-devices.csv"), []byte("00:11:22:33:44:55, Router\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := loadKnownDevices(cliOptions{}); err != nil || list.Len() != 1 {
+		t.Errorf("loadKnownDevices() = %v, %v; want the default file", list, err)
+	}
+}
@@ -2,52 +2,268 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"nls/internal/diff"
+	"nls/internal/inventory"
+	"nls/internal/known"
+	"nls/internal/notes"
+	"nls/internal/output"
 	"nls/internal/progress"
 	"nls/internal/scanner"
+	"nls/internal/tags"
 	"nls/internal/ui"
 )
 
 // App represents the main application orchestrator.
 // It coordinates the scanning and UI components.
 type App struct {
-	config  *Config
-	scanner scanner.Scanner
+	config   *Config
+	scanner  scanner.Scanner
+	progress *progress.Tracker
+
+	// inventory records the hosts of every scan (nil records nothing)
+	inventory inventory.Store
+
+	// known is the known-devices list (nil without one)
+	known *known.List
+
+	// notes keeps the aliases, notes and tags written in the UI (nil keeps
+	// them for the session only)
+	notes notes.Store
+
+	// stdout receives non-interactive output, and stderr notes about it.
+	stdout io.Writer
+	stderr io.Writer
+
+	// programOptions configure the Bubbletea program; tests replace them
+	// to run the UI without a terminal.
+	programOptions []tea.ProgramOption
 }
 
 // New creates a new App instance with the provided configuration and scanner.
 // The scanner parameter allows for dependency injection and testing with mock implementations.
 func New(config *Config, s scanner.Scanner) *App {
 	return &App{
-		config:  config,
-		scanner: s,
+		config:         config,
+		scanner:        s,
+		stdout:         os.Stdout,
+		stderr:         os.Stderr,
+		programOptions: []tea.ProgramOption{tea.WithAltScreen()},
 	}
 }
 
+// WithProgress lets the UI show the scan progress recorded by t, which
+// should be the reporter the scanner was built with.
+func (a *App) WithProgress(t *progress.Tracker) *App {
+	a.progress = t
+	return a
+}
+
+// WithInventory records every host found, by the scan and by rescans from
+// the UI, in store.
+func (a *App) WithInventory(store inventory.Store) *App {
+	a.inventory = store
+	return a
+}
+
+// WithKnownDevices labels the hosts listed in list in the UI and flags the
+// others, and tags them; with Config.FailUnknown a scan finding unlisted
+// hosts fails.
+func (a *App) WithKnownDevices(list *known.List) *App {
+	a.known = list
+	return a
+}
+
+// WithNotes keeps the aliases, notes and tags written in the UI in store.
+// Its tags are written with non-interactive output too.
+func (a *App) WithNotes(store notes.Store) *App {
+	a.notes = store
+	return a
+}
+
+// ErrUnknownDevices is returned, wrapped with the hosts, when a scan with
+// Config.FailUnknown finds hosts missing from the known-devices list.
+var ErrUnknownDevices = errors.New("unknown devices found")
+
 // Run executes the main application workflow:
 // 1. Validates configuration
-// 2. Performs network scan
-// 3. Launches interactive UI with results
+// 2. Launches the interactive UI, which streams hosts in as the scan finds them
+// 3. Reports a scan that failed before finding any host
 //
+// The scanner is reused for rescans from the UI. When Config.Output is set
+// the UI is skipped: the scan runs to completion and the hosts are written
+// to stdout in that format. With Config.Watch the scan repeats until ctx is
+// done, and the changes between scans are written instead.
 // Returns an error if validation, scanning, or UI execution fails.
 func (a *App) Run(ctx context.Context) error {
 	if err := a.config.Validate(); err != nil {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	hosts, err := a.scanner.Scan(ctx, a.config.CIDR)
+	if a.config.Watch {
+		return a.runWatch(ctx)
+	}
+	if a.config.Output != "" {
+		return a.runNonInteractive(ctx)
+	}
+
+	// Deep scans of single hosts always use nmap, whatever the discovery
+	// method, and report no progress so the scan indicator is unaffected.
+	// Hosts read from a report are not deep scanned: they may not be on
+	// this network.
+	model := ui.NewUIModel(nil, a.scanner, a.config.ScanTargets()).
+		WithProgress(a.progress).
+		StartScan(ctx)
+	if _, fromFile := a.scanner.(scanner.FileScanner); !fromFile {
+		model = model.WithDeepScanner(scanner.NewNmapScanner(nil))
+	}
+	if a.inventory != nil {
+		model = model.WithInventory(a.inventory)
+	}
+	if a.known != nil {
+		model = model.WithKnownDevices(a.known)
+	}
+	if a.notes != nil {
+		model = model.WithNotes(a.notes)
+	}
+	if a.config.Interval > 0 {
+		model = model.WithAutoRefresh(a.config.Interval)
+	}
+	final, err := tea.NewProgram(model, a.programOptions...).Run()
+	if err != nil {
+		return fmt.Errorf("run ui: %w", err)
+	}
+
+	if m, ok := final.(ui.UIModel); ok && m.Err() != nil {
+		return fmt.Errorf("scan network: %w", m.Err())
+	}
+
+	return nil
+}
+
+// runNonInteractive scans the configured targets, writes the hosts to
+// stdout in the configured output format and records them in the
+// inventory. With Config.FailUnknown it then returns ErrUnknownDevices
+// when any host is missing from the known-devices list.
+func (a *App) runNonInteractive(ctx context.Context) error {
+	hosts, err := a.scanner.Scan(ctx, a.config.ScanTargets())
 	if err != nil {
 		return fmt.Errorf("scan network: %w", err)
 	}
 
-	rescanScanner := scanner.NewNmapScanner(progress.NoOp{})
-	model := ui.NewUIModel(hosts, rescanScanner, a.config.CIDR)
-	if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
-		return fmt.Errorf("run ui: %w", err)
+	if err := output.Write(a.stdout, a.config.Output, hosts, a.tagsOf); err != nil {
+		return fmt.Errorf("write output: %w", err)
 	}
 
+	if a.inventory != nil {
+		if err := a.inventory.Record(time.Now(), hosts...); err != nil {
+			return fmt.Errorf("update inventory: %w", err)
+		}
+	}
+
+	if a.config.FailUnknown {
+		if unknown := a.known.Unknown(hosts); len(unknown) > 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownDevices, describeHosts(unknown))
+		}
+	}
 	return nil
 }
+
+// tagsOf returns the tags of h, from the known-devices list and the notes.
+func (a *App) tagsOf(h scanner.HostInfo) []string {
+	d, _ := a.known.Lookup(h)
+	var noted []string
+	if a.notes != nil {
+		e, _ := a.notes.Lookup(h.Key())
+		noted = e.Tags
+	}
+	return tags.Merge(d.Tags, noted)
+}
+
+// describeHosts lists hosts briefly, as "192.168.1.9 (AA:BB:CC:DD:EE:FF)".
+func describeHosts(hosts []scanner.HostInfo) string {
+	described := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		d := h.IPString()
+		if h.MAC != nil {
+			d += " (" + h.MACString() + ")"
+		}
+		described = append(described, d)
+	}
+	return strings.Join(described, ", ")
+}
+
+// runWatch scans the configured targets every Config.WatchInterval() until
+// ctx is done, writing the hosts that joined, left or changed since the
+// previous scan to stdout as they are seen. Each scan is limited to
+// Config.Timeout. A failed first scan ends the watch; later failures are
+// reported on stderr and the next scan compares with the last good one.
+func (a *App) runWatch(ctx context.Context) error {
+	format := a.config.Output
+	if format == "" {
+		format = output.FormatTable
+	}
+
+	previous, err := a.watchScan(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("scan network: %w", err)
+	}
+	fmt.Fprintf(a.stderr, "Watching %s every %s: %d host(s) found\n",
+		strings.Join(a.config.Targets, " "), a.config.WatchInterval(), len(previous))
+
+	ticker := time.NewTicker(a.config.WatchInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		// A tick may be picked over a context done at the same time
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		hosts, err := a.watchScan(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintf(a.stderr, "%s scan failed: %v\n", time.Now().Format(time.DateTime), err)
+			continue
+		}
+		if err := output.WriteEvents(a.stdout, format, time.Now(), diff.Hosts(previous, hosts)); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+		previous = hosts
+	}
+}
+
+// watchScan runs one watch scan, limited to Config.Timeout, and records the
+// hosts in the inventory.
+func (a *App) watchScan(ctx context.Context) ([]scanner.HostInfo, error) {
+	scanCtx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	hosts, err := a.scanner.Scan(scanCtx, a.config.ScanTargets())
+	if err != nil {
+		return nil, err
+	}
+	if a.inventory != nil {
+		if err := a.inventory.Record(time.Now(), hosts...); err != nil {
+			fmt.Fprintf(a.stderr, "Inventory not updated: %v\n", err)
+		}
+	}
+	return hosts, nil
+}
@@ -1,12 +1,25 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+
+	"nls/internal/inventory"
+	"nls/internal/known"
+	"nls/internal/notes"
 	"nls/internal/scanner"
 )
 
@@ -15,12 +28,12 @@ type mockScanner struct {
 	err   error
 }
 
-func (m *mockScanner) Scan(_ context.Context, _ string) ([]scanner.HostInfo, error) {
+func (m *mockScanner) Scan(_ context.Context, _ scanner.Targets) ([]scanner.HostInfo, error) {
 	return m.hosts, m.err
 }
 
 func TestApp_Run_InvalidConfig_EmptyCIDR(t *testing.T) {
-	cfg := &Config{CIDR: "", Timeout: 5 * time.Minute}
+	cfg := &Config{Targets: nil, Timeout: 5 * time.Minute}
 	a := New(cfg, &mockScanner{})
 	err := a.Run(context.Background())
 	if err == nil {
@@ -32,7 +45,7 @@ func TestApp_Run_InvalidConfig_EmptyCIDR(t *testing.T) {
 }
 
 func TestApp_Run_InvalidConfig_BadCIDR(t *testing.T) {
-	cfg := &Config{CIDR: "not-a-cidr", Timeout: 5 * time.Minute}
+	cfg := &Config{Targets: []string{"not a cidr"}, Timeout: 5 * time.Minute}
 	a := New(cfg, &mockScanner{})
 	err := a.Run(context.Background())
 	if err == nil {
@@ -44,7 +57,7 @@ func TestApp_Run_InvalidConfig_BadCIDR(t *testing.T) {
 }
 
 func TestApp_Run_InvalidConfig_ZeroTimeout(t *testing.T) {
-	cfg := &Config{CIDR: "192.168.1.0/24", Timeout: 0}
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 0}
 	a := New(cfg, &mockScanner{})
 	err := a.Run(context.Background())
 	if err == nil {
@@ -55,10 +68,20 @@ func TestApp_Run_InvalidConfig_ZeroTimeout(t *testing.T) {
 	}
 }
 
+// headless configures a to run its UI without a terminal.
+func headless(a *App) *App {
+	a.programOptions = []tea.ProgramOption{
+		tea.WithInput(nil),
+		tea.WithOutput(io.Discard),
+		tea.WithoutRenderer(),
+	}
+	return a
+}
+
 func TestApp_Run_ScanError(t *testing.T) {
-	cfg := &Config{CIDR: "192.168.1.0/24", Timeout: 5 * time.Minute}
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute}
 	scanErr := errors.New("permission denied")
-	a := New(cfg, &mockScanner{err: scanErr})
+	a := headless(New(cfg, &mockScanner{err: scanErr}))
 	err := a.Run(context.Background())
 	if err == nil {
 		t.Fatal("expected scan error, got nil")
@@ -72,9 +95,9 @@ func TestApp_Run_ScanError(t *testing.T) {
 }
 
 func TestApp_Run_ContextCancelled(t *testing.T) {
-	cfg := &Config{CIDR: "192.168.1.0/24", Timeout: 5 * time.Minute}
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute}
 	// Scanner that returns context error
-	a := New(cfg, &mockScanner{err: context.Canceled})
+	a := headless(New(cfg, &mockScanner{err: context.Canceled}))
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // immediately cancelled
 	err := a.Run(ctx)
@@ -82,3 +105,239 @@ func TestApp_Run_ContextCancelled(t *testing.T) {
 		t.Fatal("expected error from cancelled context, got nil")
 	}
 }
+
+func TestApp_Run_NonInteractive(t *testing.T) {
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute, Output: "csv"}
+	a := New(cfg, &mockScanner{hosts: []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, Vendor: "Router Co"},
+	}})
+	var out bytes.Buffer
+	a.stdout = &out
+
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := "ip,mac,vendor,hostname,tags\n192.168.1.1,00:11:22:33:44:55,Router Co,,\n"
+	if out.String() != want {
+		t.Errorf("output = %q; want %q", out.String(), want)
+	}
+}
+
+func TestApp_Run_NonInteractive_Inventory(t *testing.T) {
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute, Output: "json"}
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.1"), MAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}}
+	store := inventory.NewMemory()
+	a := New(cfg, &mockScanner{hosts: []scanner.HostInfo{host}}).WithInventory(store)
+	a.stdout = io.Discard
+
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	d, ok := store.Lookup("00:11:22:33:44:55")
+	if !ok || !reflect.DeepEqual(d.IPs, []string{"192.168.1.1"}) {
+		t.Errorf("inventory device = %+v, %v; want the scanned host recorded", d, ok)
+	}
+}
+
+func TestApp_Run_NonInteractive_FailUnknown(t *testing.T) {
+	list, err := known.Read(strings.NewReader("00:11:22:33:44:55, Router\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.1"), MAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}}
+	stranger := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.66"), MAC: net.HardwareAddr{0x66, 0x11, 0x22, 0x33, 0x44, 0x66}}
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute, Output: "json", FailUnknown: true}
+
+	a := New(cfg, &mockScanner{hosts: []scanner.HostInfo{router}}).WithKnownDevices(list)
+	a.stdout = io.Discard
+	if err := a.Run(context.Background()); err != nil {
+		t.Errorf("Run() with only known devices error = %v", err)
+	}
+
+	var out bytes.Buffer
+	a = New(cfg, &mockScanner{hosts: []scanner.HostInfo{router, stranger}}).WithKnownDevices(list)
+	a.stdout = &out
+	err = a.Run(context.Background())
+	if !errors.Is(err, ErrUnknownDevices) || !strings.Contains(err.Error(), "192.168.1.66 (66:11:22:33:44:66)") {
+		t.Errorf("Run() error = %v; want ErrUnknownDevices naming the stranger", err)
+	}
+	if !strings.Contains(out.String(), "192.168.1.66") {
+		t.Errorf("the hosts should be written before failing, got %q", out.String())
+	}
+}
+
+func TestApp_Run_NonInteractive_Tags(t *testing.T) {
+	list, err := known.Read(strings.NewReader("00:11:22:33:44:55, Router, IT,, network\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := notes.NewMemory()
+	if err := store.Set(notes.Entry{Key: "00:11:22:33:44:55", Tags: []string{"prod", "network"}}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute, Output: "csv"}
+	a := New(cfg, &mockScanner{hosts: []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}},
+		{IP: netip.MustParseAddr("192.168.1.9")},
+	}}).WithKnownDevices(list).WithNotes(store)
+	var out bytes.Buffer
+	a.stdout = &out
+
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := "ip,mac,vendor,hostname,tags\n192.168.1.1,00:11:22:33:44:55,,,network prod\n192.168.1.9,,,,\n"
+	if out.String() != want {
+		t.Errorf("output = %q; want %q", out.String(), want)
+	}
+}
+
+// sequenceScanner returns the next of results on each scan, and calls done
+// once they have all been returned.
+type sequenceScanner struct {
+	results []scanResult
+	done    func()
+}
+
+type scanResult struct {
+	hosts []scanner.HostInfo
+	err   error
+}
+
+func (s *sequenceScanner) Scan(_ context.Context, _ scanner.Targets) ([]scanner.HostInfo, error) {
+	r := s.results[0]
+	s.results = s.results[1:]
+	if len(s.results) == 0 {
+		s.done()
+	}
+	return r.hosts, r.err
+}
+
+func TestApp_RunWatch(t *testing.T) {
+	router := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.1"), MAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01}}
+	phone := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.50"), MAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x50}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &sequenceScanner{
+		results: []scanResult{
+			{hosts: []scanner.HostInfo{router}},
+			{hosts: []scanner.HostInfo{router, phone}},
+			{err: errors.New("network unreachable")},
+			{hosts: []scanner.HostInfo{phone}},
+		},
+		done: cancel,
+	}
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: time.Minute, Watch: true, Interval: time.Millisecond, Output: "json"}
+	store := inventory.NewMemory()
+	a := New(cfg, s).WithInventory(store)
+	var out, errOut bytes.Buffer
+	a.stdout = &out
+	a.stderr = &errOut
+
+	if err := a.runWatch(ctx); err != nil {
+		t.Fatalf("runWatch() error = %v", err)
+	}
+
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e struct {
+			Event string `json:"event"`
+			Host  struct {
+				IP string `json:"ip"`
+			} `json:"host"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("event %q: %v", line, err)
+		}
+		events = append(events, e.Event+" "+e.Host.IP)
+	}
+	if want := []string{"join 192.168.1.50", "leave 192.168.1.1"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q; want %q", events, want)
+	}
+	if !strings.Contains(errOut.String(), "1 host(s) found") || !strings.Contains(errOut.String(), "network unreachable") {
+		t.Errorf("stderr = %q; want the watch start and the failed scan", errOut.String())
+	}
+	if len(store.Devices()) != 2 {
+		t.Errorf("inventory has %d device(s); want 2", len(store.Devices()))
+	}
+}
+
+func TestApp_RunWatch_FirstScanFails(t *testing.T) {
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: time.Minute, Watch: true}
+	scanErr := errors.New("permission denied")
+	a := New(cfg, &mockScanner{err: scanErr})
+	a.stderr = io.Discard
+
+	if err := a.runWatch(context.Background()); !errors.Is(err, scanErr) {
+		t.Errorf("runWatch() error = %v; want the scan error", err)
+	}
+}
+
+func TestApp_Run_NonInteractive_ScanError(t *testing.T) {
+	cfg := &Config{Targets: []string{"192.168.1.0/24"}, Timeout: 5 * time.Minute, Output: "json"}
+	scanErr := errors.New("permission denied")
+	a := New(cfg, &mockScanner{err: scanErr})
+	var out bytes.Buffer
+	a.stdout = &out
+
+	err := a.Run(context.Background())
+	if !errors.Is(err, scanErr) {
+		t.Errorf("Run() error = %v; want wrapped scan error", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("nothing should be written on error, got %q", out.String())
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	tests := []struct {
+		method  string
+		want    string
+		wantErr bool
+	}{
+		{method: "", want: "*scanner.NmapScanner"},
+		{method: MethodNmap, want: "*scanner.NmapScanner"},
+		{method: MethodARP, want: "*scanner.ARPScanner"},
+		{method: MethodICMP, want: "*scanner.ICMPScanner"},
+		{method: MethodTCP, want: "*scanner.TCPScanner"},
+		{method: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			s, err := NewScanner(&Config{Method: tt.method}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewScanner() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := fmt.Sprintf("%T", s); !tt.wantErr && got != tt.want {
+				t.Errorf("NewScanner() type = %s; want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, method := range []string{MethodARP, MethodICMP, MethodTCP} {
+		s, err := NewScanner(&Config{Method: method, TopPorts: 100}, nil)
+		if err != nil {
+			t.Fatalf("NewScanner() error = %v", err)
+		}
+		if got := fmt.Sprintf("%T", s); got != "*scanner.PortScanner" {
+			t.Errorf("NewScanner(%s) with a port scan type = %s; want *scanner.PortScanner", method, got)
+		}
+	}
+	if s, _ := NewScanner(&Config{Method: MethodNmap, Ports: []uint16{22}}, nil); fmt.Sprintf("%T", s) != "*scanner.NmapScanner" {
+		t.Errorf("NewScanner(nmap) with a port scan should port-scan with nmap itself, got %T", s)
+	}
+
+	if _, err := NewScanner(&Config{Method: MethodARP, OUIFile: filepath.Join(t.TempDir(), "missing.txt")}, nil); err == nil {
+		t.Error("NewScanner() with a missing OUI file should fail")
+	}
+
+	s, err := NewScanner(&Config{Method: MethodARP, FromXML: "scan.xml"}, nil)
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v", err)
+	}
+	if got := fmt.Sprintf("%T", s); got != "*scanner.XMLScanner" {
+		t.Errorf("NewScanner() with FromXML type = %s; want *scanner.XMLScanner", got)
+	}
+}
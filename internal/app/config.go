@@ -5,46 +5,194 @@ package app
 
 import (
 	"fmt"
-	"net"
+	"strings"
 	"time"
+
+	"nls/internal/output"
+	"nls/internal/scanner"
 )
 
 // Config holds the application configuration settings.
 // It centralizes all configurable parameters for the network scanner.
 type Config struct {
-	// CIDR is the network range to scan (e.g., "192.168.1.0/24")
-	CIDR string
+	// Targets are the networks, ranges, addresses and hostnames to scan
+	// (e.g., "192.168.1.0/24", "10.0.0.1-50", "nas.local")
+	Targets []string
+
+	// Exclude lists targets to leave out of the scan, in the same forms
+	Exclude []string
 
 	// Timeout is the maximum duration for the scan operation
 	Timeout time.Duration
 
 	// ShowProgress determines whether to display a progress spinner
 	ShowProgress bool
+
+	// Method selects the host discovery technique (see the Method* constants)
+	Method string
+
+	// ProbePorts are the TCP ports tried by MethodTCP (empty means defaults)
+	ProbePorts []uint16
+
+	// OUIFile is the vendor database used by MethodARP; empty means the
+	// system's nmap or arp-scan file, if any
+	OUIFile string
+
+	// Output selects a non-interactive output format (see output.Formats);
+	// empty runs the interactive TUI
+	Output string
+
+	// Ports are the TCP ports to port-scan on every discovered host
+	Ports []uint16
+
+	// TopPorts port-scans this many of the most common TCP ports on every
+	// discovered host; it cannot be combined with Ports
+	TopPorts int
+
+	// FromXML is a saved nmap XML report to read hosts from instead of
+	// scanning; Targets and Exclude must be empty when it is set
+	FromXML string
+
+	// Watch scans every WatchInterval() and reports the hosts that join,
+	// leave or change, in Output (table or json), instead of starting the
+	// TUI
+	Watch bool
+
+	// Interval is the time between watch scans, or between automatic
+	// rescans in the TUI; zero disables automatic rescans and makes watch
+	// use DefaultWatchInterval
+	Interval time.Duration
+
+	// FailUnknown makes a scan with Output fail with ErrUnknownDevices
+	// when it finds hosts missing from the known-devices list
+	FailUnknown bool
 }
 
+// DefaultWatchInterval is the time between watch scans when no Interval
+// is set.
+const DefaultWatchInterval = 5 * time.Minute
+
+// Host discovery methods accepted in Config.Method.
+const (
+	// MethodNmap runs an nmap ping scan (default)
+	MethodNmap = "nmap"
+
+	// MethodARP sends native layer-2 ARP requests without nmap
+	MethodARP = "arp"
+
+	// MethodICMP sends native ICMP echo requests, unprivileged where allowed
+	MethodICMP = "icmp"
+
+	// MethodTCP probes TCP ports with plain connect() calls
+	MethodTCP = "tcp"
+)
+
 // DefaultConfig returns a Config with sensible default values.
-// CIDR must be set by the caller before calling Validate.
+// Targets must be set by the caller before calling Validate.
 func DefaultConfig() *Config {
 	return &Config{
 		Timeout:      5 * time.Minute,
 		ShowProgress: true,
+		Method:       MethodNmap,
 	}
 }
 
 // Validate checks if the configuration is valid.
-// Returns an error if CIDR is missing or invalid, or timeout is non-positive.
+// Returns an error if no target is given or a target or exclusion is
+// invalid, timeout is non-positive, or the discovery method or output
+// format is unknown, OUIFile is set for a method other than MethodARP, the
+// port scan is invalid, or the interval, watch or FailUnknown settings are. An empty method means MethodNmap. With FromXML
+// set no target is needed, and giving one is an error.
 func (c *Config) Validate() error {
-	if c.CIDR == "" {
-		return fmt.Errorf("CIDR is required: specify a network range to scan (e.g., nls 192.168.1.0/24)")
-	}
+	if c.FromXML != "" {
+		if len(c.Targets) > 0 || len(c.Exclude) > 0 {
+			return fmt.Errorf("targets cannot be combined with --from-xml: the report already defines what was scanned")
+		}
+		if c.PortScan().Enabled() {
+			return fmt.Errorf("a port scan cannot be combined with --from-xml: the report already lists the ports nmap scanned")
+		}
+	} else {
+		if len(c.Targets) == 0 {
+			return fmt.Errorf("a target is required: specify a network range to scan (e.g., nls 192.168.1.0/24)")
+		}
 
-	if _, _, err := net.ParseCIDR(c.CIDR); err != nil {
-		return fmt.Errorf("invalid CIDR format %s: %w", c.CIDR, err)
+		if err := c.ScanTargets().Validate(); err != nil {
+			return fmt.Errorf("invalid target: %w", err)
+		}
 	}
 
 	if c.Timeout <= 0 {
 		return fmt.Errorf("timeout must be positive, got %v", c.Timeout)
 	}
 
+	switch c.Method {
+	case "", MethodNmap, MethodARP, MethodICMP, MethodTCP:
+	default:
+		return fmt.Errorf("unknown discovery method %q: use %s, %s, %s or %s", c.Method, MethodNmap, MethodARP, MethodICMP, MethodTCP)
+	}
+
+	if c.Output != "" && !output.IsSupported(c.Output) {
+		return fmt.Errorf("unknown output format %q: use %s", c.Output, strings.Join(output.Formats, ", "))
+	}
+
+	if c.OUIFile != "" && c.Method != MethodARP {
+		return fmt.Errorf("--oui-file only applies to --method %s: nmap resolves vendors itself", MethodARP)
+	}
+
+	for _, port := range c.ProbePorts {
+		if port == 0 {
+			return fmt.Errorf("probe port must be between 1 and 65535")
+		}
+	}
+
+	if len(c.Ports) > 0 && c.TopPorts != 0 {
+		return fmt.Errorf("--ports and --top-ports cannot be combined")
+	}
+	if c.TopPorts < 0 || c.TopPorts > 65535 {
+		return fmt.Errorf("top ports must be between 0 (no port scan) and 65535, got %d", c.TopPorts)
+	}
+	for _, port := range c.Ports {
+		if port == 0 {
+			return fmt.Errorf("port must be between 1 and 65535")
+		}
+	}
+
+	if c.Interval < 0 || (c.Interval > 0 && c.Interval < time.Second) {
+		return fmt.Errorf("interval must be at least 1s, got %v", c.Interval)
+	}
+	if c.Watch {
+		if c.FromXML != "" {
+			return fmt.Errorf("watch cannot be combined with --from-xml: a saved report never changes")
+		}
+		switch c.Output {
+		case "", output.FormatTable, output.FormatJSON:
+		default:
+			return fmt.Errorf("watch reports events as %s or %s, not %s", output.FormatTable, output.FormatJSON, c.Output)
+		}
+	}
+	if c.FailUnknown && (c.Output == "" || c.Watch) {
+		return fmt.Errorf("--fail-unknown needs a single scan with --output")
+	}
+
 	return nil
 }
+
+// ScanTargets returns the configured targets and exclusions.
+func (c *Config) ScanTargets() scanner.Targets {
+	return scanner.Targets{Include: c.Targets, Exclude: c.Exclude}
+}
+
+// WatchInterval returns the time between watch scans: Interval, or
+// DefaultWatchInterval when it is not set.
+func (c *Config) WatchInterval() time.Duration {
+	if c.Interval == 0 {
+		return DefaultWatchInterval
+	}
+	return c.Interval
+}
+
+// PortScan returns the configured port scan, which is disabled when
+// neither Ports nor TopPorts is set.
+func (c *Config) PortScan() scanner.PortScan {
+	return scanner.PortScan{Top: c.TopPorts, Ports: c.Ports}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -8,8 +9,8 @@ import (
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
-	if cfg.CIDR != "" {
-		t.Errorf("CIDR = %q; want %q", cfg.CIDR, "")
+	if len(cfg.Targets) != 0 {
+		t.Errorf("Targets = %v; want none", cfg.Targets)
 	}
 
 	if cfg.Timeout != 5*time.Minute {
@@ -19,6 +20,10 @@ func TestDefaultConfig(t *testing.T) {
 	if !cfg.ShowProgress {
 		t.Error("ShowProgress should be true by default")
 	}
+
+	if cfg.Method != MethodNmap {
+		t.Errorf("Method = %q; want %q", cfg.Method, MethodNmap)
+	}
 }
 
 func TestConfig_Validate(t *testing.T) {
@@ -30,16 +35,16 @@ func TestConfig_Validate(t *testing.T) {
 		{
 			name: "valid config",
 			config: &Config{
-				CIDR:         "192.168.1.0/24",
+				Targets:      []string{"192.168.1.0/24"},
 				Timeout:      5 * time.Minute,
 				ShowProgress: true,
 			},
 			wantErr: false,
 		},
 		{
-			name: "empty CIDR",
+			name: "no targets",
 			config: &Config{
-				CIDR:         "",
+				Targets:      nil,
 				Timeout:      5 * time.Minute,
 				ShowProgress: true,
 			},
@@ -48,7 +53,7 @@ func TestConfig_Validate(t *testing.T) {
 		{
 			name: "invalid CIDR",
 			config: &Config{
-				CIDR:         "invalid",
+				Targets:      []string{"192.168.1.0/33"},
 				Timeout:      5 * time.Minute,
 				ShowProgress: true,
 			},
@@ -57,7 +62,7 @@ func TestConfig_Validate(t *testing.T) {
 		{
 			name: "zero timeout",
 			config: &Config{
-				CIDR:         "192.168.1.0/24",
+				Targets:      []string{"192.168.1.0/24"},
 				Timeout:      0,
 				ShowProgress: true,
 			},
@@ -66,7 +71,7 @@ func TestConfig_Validate(t *testing.T) {
 		{
 			name: "negative timeout",
 			config: &Config{
-				CIDR:         "192.168.1.0/24",
+				Targets:      []string{"192.168.1.0/24"},
 				Timeout:      -1 * time.Second,
 				ShowProgress: true,
 			},
@@ -75,12 +80,271 @@ func TestConfig_Validate(t *testing.T) {
 		{
 			name: "valid different CIDR",
 			config: &Config{
-				CIDR:         "10.0.0.0/8",
+				Targets:      []string{"10.0.0.0/8"},
 				Timeout:      1 * time.Minute,
 				ShowProgress: false,
 			},
 			wantErr: false,
 		},
+		{
+			name: "arp method",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Method:  MethodARP,
+			},
+			wantErr: false,
+		},
+		{
+			name: "icmp method",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Method:  MethodICMP,
+			},
+			wantErr: false,
+		},
+		{
+			name: "tcp method with ports",
+			config: &Config{
+				Targets:    []string{"192.168.1.0/24"},
+				Timeout:    1 * time.Minute,
+				Method:     MethodTCP,
+				ProbePorts: []uint16{22, 443},
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero probe port",
+			config: &Config{
+				Targets:    []string{"192.168.1.0/24"},
+				Timeout:    1 * time.Minute,
+				Method:     MethodTCP,
+				ProbePorts: []uint16{0},
+			},
+			wantErr: true,
+		},
+		{
+			name: "arp method with oui file",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Method:  MethodARP,
+				OUIFile: "oui.txt",
+			},
+			wantErr: false,
+		},
+		{
+			name: "oui file without arp",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Method:  MethodNmap,
+				OUIFile: "oui.txt",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown method",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Method:  "carrier-pigeon",
+			},
+			wantErr: true,
+		},
+		{
+			name: "multiple targets with exclusions",
+			config: &Config{
+				Targets: []string{"10.0.0.0/24", "10.0.1.1-50", "10.0.2.7", "nas.local"},
+				Exclude: []string{"10.0.0.1", "10.0.1.10-20"},
+				Timeout: 1 * time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "IPv6 link-local network",
+			config: &Config{
+				Targets: []string{"fe80::/64", "fd00::/120"},
+				Timeout: 1 * time.Minute,
+				Method:  MethodICMP,
+			},
+			wantErr: false,
+		},
+		{
+			name: "IPv6 network larger than /64",
+			config: &Config{
+				Targets: []string{"2001:db8::/32"},
+				Timeout: 1 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid exclusion",
+			config: &Config{
+				Targets: []string{"10.0.0.0/24"},
+				Exclude: []string{"10.0.0.300"},
+				Timeout: 1 * time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "json output",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Output:  "json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown output format",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Output:  "yaml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "from XML without targets",
+			config: &Config{
+				Timeout: 1 * time.Minute,
+				FromXML: "scan.xml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "from XML with targets",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				FromXML: "scan.xml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "port list",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Ports:   []uint16{22, 3389},
+			},
+			wantErr: false,
+		},
+		{
+			name: "top ports",
+			config: &Config{
+				Targets:  []string{"192.168.1.0/24"},
+				Timeout:  1 * time.Minute,
+				TopPorts: 100,
+			},
+			wantErr: false,
+		},
+		{
+			name: "port list and top ports",
+			config: &Config{
+				Targets:  []string{"192.168.1.0/24"},
+				Timeout:  1 * time.Minute,
+				Ports:    []uint16{22},
+				TopPorts: 100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative top ports",
+			config: &Config{
+				Targets:  []string{"192.168.1.0/24"},
+				Timeout:  1 * time.Minute,
+				TopPorts: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero port",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Ports:   []uint16{0},
+			},
+			wantErr: true,
+		},
+		{
+			name: "from XML with a port scan",
+			config: &Config{
+				Timeout:  1 * time.Minute,
+				FromXML:  "scan.xml",
+				TopPorts: 100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "from XML with exclusions",
+			config: &Config{
+				Exclude: []string{"192.168.1.1"},
+				Timeout: 1 * time.Minute,
+				FromXML: "scan.xml",
+			},
+			wantErr: true,
+		},
+		{
+			name: "watch with JSON events",
+			config: &Config{
+				Targets:  []string{"192.168.1.0/24"},
+				Timeout:  1 * time.Minute,
+				Watch:    true,
+				Interval: 30 * time.Second,
+				Output:   "json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "watch as CSV",
+			config: &Config{
+				Targets: []string{"192.168.1.0/24"},
+				Timeout: 1 * time.Minute,
+				Watch:   true,
+				Output:  "csv",
+			},
+			wantErr: true,
+		},
+		{
+			name: "watch a report",
+			config: &Config{
+				Timeout: 1 * time.Minute,
+				FromXML: "scan.xml",
+				Watch:   true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail unknown with output",
+			config: &Config{
+				Targets:     []string{"192.168.1.0/24"},
+				Timeout:     1 * time.Minute,
+				Output:      "json",
+				FailUnknown: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail unknown in the TUI",
+			config: &Config{
+				Targets:     []string{"192.168.1.0/24"},
+				Timeout:     1 * time.Minute,
+				FailUnknown: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "interval below a second",
+			config: &Config{
+				Targets:  []string{"192.168.1.0/24"},
+				Timeout:  1 * time.Minute,
+				Interval: 500 * time.Millisecond,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -92,3 +356,20 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_WatchInterval(t *testing.T) {
+	if got := (&Config{}).WatchInterval(); got != DefaultWatchInterval {
+		t.Errorf("WatchInterval() without Interval = %v; want %v", got, DefaultWatchInterval)
+	}
+	if got := (&Config{Interval: time.Minute}).WatchInterval(); got != time.Minute {
+		t.Errorf("WatchInterval() = %v; want %v", got, time.Minute)
+	}
+}
+
+func TestConfig_ScanTargets(t *testing.T) {
+	cfg := &Config{Targets: []string{"10.0.0.0/24", "nas.local"}, Exclude: []string{"10.0.0.1"}}
+	got := cfg.ScanTargets()
+	if !reflect.DeepEqual(got.Include, cfg.Targets) || !reflect.DeepEqual(got.Exclude, cfg.Exclude) {
+		t.Errorf("ScanTargets() = %+v", got)
+	}
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"nls/internal/diff"
+	"nls/internal/output"
+	"nls/internal/scanner"
+)
+
+// DiffResults compares two saved results, each a JSON result written with
+// --output json or an nmap XML report, and writes the hosts added, removed
+// and changed from before to after to w in format (table or json). It
+// reports whether there are any differences.
+func DiffResults(ctx context.Context, w io.Writer, format, before, after string) (bool, error) {
+	switch format {
+	case output.FormatTable, output.FormatJSON:
+	default:
+		return false, fmt.Errorf("diff reports differences as %s or %s, not %s", output.FormatTable, output.FormatJSON, format)
+	}
+
+	oldHosts, err := loadResult(ctx, before)
+	if err != nil {
+		return false, err
+	}
+	newHosts, err := loadResult(ctx, after)
+	if err != nil {
+		return false, err
+	}
+
+	changes := diff.Hosts(oldHosts, newHosts)
+	if err := output.WriteDiff(w, format, changes); err != nil {
+		return false, fmt.Errorf("write output: %w", err)
+	}
+	return len(changes) > 0, nil
+}
+
+// loadResult reads the hosts saved at path, as nmap XML when the file
+// starts with "<" and as an nls JSON result otherwise.
+func loadResult(ctx context.Context, path string) ([]scanner.HostInfo, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read result: %w", err)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		return scanner.NewXMLScanner(nil, path).Scan(ctx, scanner.Targets{})
+	}
+	hosts, err := output.Read(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const diffTestXML = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -sn -oX before.xml 192.168.1.0/24" start="1700000000" version="7.94">
+<host><status state="up" reason="arp-response"/>
+<address addr="192.168.1.1" addrtype="ipv4"/>
+<address addr="00:11:22:33:44:55" addrtype="mac" vendor="Router Co"/>
+</host>
+<host><status state="up" reason="arp-response"/>
+<address addr="192.168.1.20" addrtype="ipv4"/>
+<address addr="AA:BB:CC:DD:EE:FF" addrtype="mac" vendor="Acme"/>
+</host>
+</nmaprun>
+`
+
+func TestDiffResults(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.xml")
+	after := filepath.Join(dir, "after.json")
+	if err := os.WriteFile(before, []byte(diffTestXML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The laptop moved, the router is matched by its MAC and a phone joined
+	afterJSON := `[
+  {"ip": "192.168.1.1", "mac": "00:11:22:33:44:55", "vendor": "Router Co", "hostname": ""},
+  {"ip": "192.168.1.31", "mac": "AA:BB:CC:DD:EE:FF", "vendor": "Acme", "hostname": ""},
+  {"ip": "192.168.1.50", "mac": "", "vendor": "", "hostname": ""}
+]`
+	if err := os.WriteFile(after, []byte(afterJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	different, err := DiffResults(context.Background(), &out, "table", before, after)
+	if err != nil {
+		t.Fatalf("DiffResults() error = %v", err)
+	}
+	if !different {
+		t.Error("DiffResults() should report differences")
+	}
+	for _, want := range []string{"~  192.168.1.31", "(IP 192.168.1.20 → 192.168.1.31)", "+  192.168.1.50", "+1 new, −0 gone, 1 changed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output should contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	different, err = DiffResults(context.Background(), &out, "json", before, before)
+	if err != nil || different {
+		t.Errorf("DiffResults() of a file with itself = %v, %v; want no differences", different, err)
+	}
+}
+
+func TestDiffResults_Errors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, format, before, wantErr string
+	}{
+		{"missing file", "table", filepath.Join(dir, "missing.json"), "read result"},
+		{"invalid JSON", "table", bad, "bad.json"},
+		{"CSV output", "csv", bad, "table or json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DiffResults(context.Background(), &bytes.Buffer{}, tt.format, tt.before, bad)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DiffResults() error = %v; want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"fmt"
+
+	"nls/internal/progress"
+	"nls/internal/scanner"
+)
+
+// NewScanner builds the scanner selected by config.Method, reporting
+// progress through p. An empty method selects the nmap scanner. When
+// config.FromXML is set, the hosts are read from that report instead. A
+// configured port scan is run by nmap itself, and by a PortScanner
+// wrapping the discovery scanner for the native methods.
+func NewScanner(config *Config, p progress.Reporter) (scanner.Scanner, error) {
+	if config.FromXML != "" {
+		return scanner.NewXMLScanner(p, config.FromXML), nil
+	}
+
+	var s scanner.Scanner
+	switch config.Method {
+	case "", MethodNmap:
+		// nmap runs the port scan itself.
+		return scanner.NewNmapScanner(p).WithPortScan(config.PortScan()), nil
+	case MethodARP:
+		arp := scanner.NewARPScanner(p)
+		if config.OUIFile != "" {
+			var err error
+			if arp, err = arp.WithOUIFile(config.OUIFile); err != nil {
+				return nil, err
+			}
+		}
+		s = arp
+	case MethodICMP:
+		s = scanner.NewICMPScanner(p)
+	case MethodTCP:
+		s = scanner.NewTCPScanner(p, config.ProbePorts)
+	default:
+		return nil, fmt.Errorf("unknown discovery method %q", config.Method)
+	}
+
+	if ps := config.PortScan(); ps.Enabled() {
+		return scanner.NewPortScanner(s, ps), nil
+	}
+	return s, nil
+}
@@ -0,0 +1,219 @@
+// Package diff compares two sets of hosts, such as successive scans or
+// saved results, and reports the hosts that appeared, disappeared or moved
+// to another address.
+package diff
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"nls/internal/scanner"
+)
+
+// Kind is the way a host differs between the two sets.
+type Kind int
+
+const (
+	// Added hosts are only in the later set
+	Added Kind = iota + 1
+
+	// Removed hosts are only in the earlier set
+	Removed
+
+	// Changed hosts are in both sets, with a different IP address, or
+	// are at the same IP address with a different MAC address
+	Changed
+)
+
+// String returns "new", "gone" or "changed".
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "new"
+	case Removed:
+		return "gone"
+	case Changed:
+		return "changed"
+	default:
+		return ""
+	}
+}
+
+// Change is a host that differs between the two sets.
+type Change struct {
+	Kind Kind
+
+	// Old is the host before; it is the zero HostInfo for Added
+	Old scanner.HostInfo
+
+	// New is the host after; it is the zero HostInfo for Removed
+	New scanner.HostInfo
+}
+
+// Host returns the host as last seen: New, or Old when it was removed.
+func (c Change) Host() scanner.HostInfo {
+	if c.Kind == Removed {
+		return c.Old
+	}
+	return c.New
+}
+
+// Detail describes what changed, e.g. "IP 192.168.1.5 → 192.168.1.7" or
+// "MAC 00:11:22:33:44:55 → 66:11:22:33:44:55". It is "" for added and
+// removed hosts.
+func (c Change) Detail() string {
+	if c.Kind != Changed {
+		return ""
+	}
+	if c.Old.IP == c.New.IP {
+		return fmt.Sprintf("MAC %s → %s", orUnknown(c.Old.MACString()), orUnknown(c.New.MACString()))
+	}
+	return fmt.Sprintf("IP %s → %s", orUnknown(c.Old.IPString()), orUnknown(c.New.IPString()))
+}
+
+// orUnknown returns s, or "unknown" when s is empty.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// Hosts compares the sets of hosts before and after. Hosts are matched by MAC
+// address, and by IP address when either side has no MAC (hosts beyond the
+// local network, or found by unprivileged scans). A matched host whose IP
+// address differs is Changed, and so is a host left unmatched at the IP
+// address of one with another MAC address, e.g. a replaced device or a
+// spoofed address. The others are Added or Removed. Hosts that are the
+// same in both sets are left out. Changes are ordered by the IP address of
+// their host.
+func Hosts(before, after []scanner.HostInfo) []Change {
+	used := make([]bool, len(before))
+	byMAC := make(map[string][]int)
+	byIP := make(map[netip.Addr][]int)
+	for i, h := range before {
+		if h.MAC != nil {
+			byMAC[h.MACString()] = append(byMAC[h.MACString()], i)
+		}
+		if h.IP.IsValid() {
+			byIP[h.IP] = append(byIP[h.IP], i)
+		}
+	}
+
+	// match returns an unused host of before among candidates, preferring one
+	// at the same IP address, or -1.
+	match := func(h scanner.HostInfo, candidates []int, usable func(int) bool) int {
+		found := -1
+		for _, i := range candidates {
+			if used[i] || !usable(i) {
+				continue
+			}
+			if before[i].IP == h.IP {
+				return i
+			}
+			if found < 0 {
+				found = i
+			}
+		}
+		return found
+	}
+
+	var changes []Change
+	matched := make([]int, len(after))
+	for j, h := range after {
+		matched[j] = -1
+		if h.MAC == nil {
+			continue
+		}
+		if i := match(h, byMAC[h.MACString()], func(int) bool { return true }); i >= 0 {
+			used[i] = true
+			matched[j] = i
+		}
+	}
+	for j, h := range after {
+		if matched[j] >= 0 || !h.IP.IsValid() {
+			continue
+		}
+		// Hosts with different MACs are different devices, even at the
+		// same address: they are matched last.
+		unknownMAC := func(i int) bool { return h.MAC == nil || before[i].MAC == nil }
+		if i := match(h, byIP[h.IP], unknownMAC); i >= 0 {
+			used[i] = true
+			matched[j] = i
+		}
+	}
+	for j, h := range after {
+		if matched[j] >= 0 || !h.IP.IsValid() {
+			continue
+		}
+		if i := match(h, byIP[h.IP], func(int) bool { return true }); i >= 0 {
+			used[i] = true
+			matched[j] = i
+		}
+	}
+
+	for j, h := range after {
+		switch i := matched[j]; {
+		case i < 0:
+			changes = append(changes, Change{Kind: Added, New: h})
+		case before[i].IP != h.IP || macChanged(before[i], h):
+			changes = append(changes, Change{Kind: Changed, Old: before[i], New: h})
+		}
+	}
+	for i, h := range before {
+		if !used[i] {
+			changes = append(changes, Change{Kind: Removed, Old: h})
+		}
+	}
+
+	sort.SliceStable(changes, func(a, b int) bool {
+		ipA, ipB := changes[a].Host().IP, changes[b].Host().IP
+		if ipA.IsValid() != ipB.IsValid() {
+			return ipA.IsValid()
+		}
+		return ipA.Less(ipB)
+	})
+	return changes
+}
+
+// macChanged reports whether was and now both have a MAC address and they
+// differ.
+func macChanged(was, now scanner.HostInfo) bool {
+	return was.MAC != nil && now.MAC != nil && was.MACString() != now.MACString()
+}
+
+// Summary counts changes by kind.
+type Summary struct {
+	Added, Removed, Changed int
+}
+
+// Summarize counts changes by kind.
+func Summarize(changes []Change) Summary {
+	var s Summary
+	for _, c := range changes {
+		switch c.Kind {
+		case Added:
+			s.Added++
+		case Removed:
+			s.Removed++
+		case Changed:
+			s.Changed++
+		}
+	}
+	return s
+}
+
+// Total returns the number of changes.
+func (s Summary) Total() int {
+	return s.Added + s.Removed + s.Changed
+}
+
+// String returns the counts in the form "+3 new, −1 gone, 2 changed",
+// or "no changes".
+func (s Summary) String() string {
+	if s.Total() == 0 {
+		return "no changes"
+	}
+	return fmt.Sprintf("+%d new, −%d gone, %d changed", s.Added, s.Removed, s.Changed)
+}
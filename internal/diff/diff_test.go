@@ -0,0 +1,128 @@
+package diff
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"nls/internal/scanner"
+)
+
+func host(ip, mac string) scanner.HostInfo {
+	h := scanner.HostInfo{}
+	if ip != "" {
+		h.IP = netip.MustParseAddr(ip)
+	}
+	if mac != "" {
+		var err error
+		if h.MAC, err = net.ParseMAC(mac); err != nil {
+			panic(err)
+		}
+	}
+	return h
+}
+
+func TestHosts(t *testing.T) {
+	router := host("192.168.1.1", "00:11:22:33:44:01")
+	laptop := host("192.168.1.20", "00:11:22:33:44:20")
+	moved := host("192.168.1.31", "00:11:22:33:44:20")
+	remote := host("10.0.0.7", "")
+	remoteWithMAC := host("10.0.0.7", "00:11:22:33:44:07")
+	printer := host("192.168.1.40", "00:11:22:33:44:40")
+	impostor := host("192.168.1.40", "66:11:22:33:44:40")
+
+	tests := []struct {
+		name          string
+		before, after []scanner.HostInfo
+		want          []Change
+	}{
+		{
+			name:   "no changes",
+			before: []scanner.HostInfo{router, laptop},
+			after:  []scanner.HostInfo{laptop, router},
+		},
+		{
+			name:   "added and removed",
+			before: []scanner.HostInfo{router, laptop},
+			after:  []scanner.HostInfo{router, printer},
+			want: []Change{
+				{Kind: Removed, Old: laptop},
+				{Kind: Added, New: printer},
+			},
+		},
+		{
+			name:   "same MAC at a new address",
+			before: []scanner.HostInfo{router, laptop},
+			after:  []scanner.HostInfo{router, moved},
+			want:   []Change{{Kind: Changed, Old: laptop, New: moved}},
+		},
+		{
+			name:   "matched by IP without a MAC",
+			before: []scanner.HostInfo{remote},
+			after:  []scanner.HostInfo{remoteWithMAC},
+		},
+		{
+			name:   "different MAC at the same address",
+			before: []scanner.HostInfo{printer},
+			after:  []scanner.HostInfo{impostor},
+			want:   []Change{{Kind: Changed, Old: printer, New: impostor}},
+		},
+		{
+			name:   "different MAC at the address of a moved host",
+			before: []scanner.HostInfo{printer},
+			after:  []scanner.HostInfo{impostor, host("192.168.1.41", "00:11:22:33:44:40")},
+			want: []Change{
+				{Kind: Added, New: impostor},
+				{Kind: Changed, Old: printer, New: host("192.168.1.41", "00:11:22:33:44:40")},
+			},
+		},
+		{
+			name:   "one MAC answering for several addresses",
+			before: []scanner.HostInfo{host("192.168.1.2", "00:11:22:33:44:01"), router},
+			after:  []scanner.HostInfo{router, host("192.168.1.2", "00:11:22:33:44:01")},
+		},
+		{
+			name:  "first scan",
+			after: []scanner.HostInfo{remote, router},
+			want: []Change{
+				{Kind: Added, New: remote},
+				{Kind: Added, New: router},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hosts(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hosts() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	changes := []Change{
+		{Kind: Added}, {Kind: Added}, {Kind: Added},
+		{Kind: Removed},
+		{Kind: Changed}, {Kind: Changed},
+	}
+	if got, want := Summarize(changes).String(), "+3 new, −1 gone, 2 changed"; got != want {
+		t.Errorf("Summarize().String() = %q; want %q", got, want)
+	}
+	if got := Summarize(nil).String(); got != "no changes" {
+		t.Errorf("Summarize(nil).String() = %q; want %q", got, "no changes")
+	}
+}
+
+func TestChangeDetail(t *testing.T) {
+	c := Change{Kind: Changed, Old: host("192.168.1.20", ""), New: host("192.168.1.31", "")}
+	if got, want := c.Detail(), "IP 192.168.1.20 → 192.168.1.31"; got != want {
+		t.Errorf("Detail() = %q; want %q", got, want)
+	}
+	c = Change{Kind: Changed, Old: host("192.168.1.40", "00:11:22:33:44:40"), New: host("192.168.1.40", "66:11:22:33:44:40")}
+	if got, want := c.Detail(), "MAC 00:11:22:33:44:40 → 66:11:22:33:44:40"; got != want {
+		t.Errorf("Detail() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"nls/internal/scanner"
+	"nls/internal/xdg"
+)
+
+// compactRatio is how many lines per device the inventory file may grow to
+// before it is rewritten with one line per device.
+const compactRatio = 4
+
+// File is a Store persisted as a JSON-lines file. Each line is a snapshot
+// of one device; a later line replaces earlier ones with the same key.
+// Every device found by a scan is appended, since its LastSeen moves on,
+// so Open and Record rewrite the file with one line per device once
+// superseded lines pile up.
+type File struct {
+	path string
+	mem  *Memory
+
+	// mu serializes writes to the file and guards lines
+	mu sync.Mutex
+	// lines is the number of snapshots in the file
+	lines int
+}
+
+// DefaultPath returns where the inventory is kept:
+// $XDG_DATA_HOME/nls/inventory.jsonl, or ~/.local/share/nls/inventory.jsonl
+// when XDG_DATA_HOME is not set.
+func DefaultPath() (string, error) {
+	return xdg.DataPath("inventory.jsonl")
+}
+
+// Open loads the inventory file at path, which is created, along with its
+// directory, on the first Record if it does not exist.
+func Open(path string) (*File, error) {
+	f := &File{path: path, mem: NewMemory()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read inventory: %w", err)
+	}
+
+	lines := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var d Device
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil {
+			return nil, fmt.Errorf("read inventory %s: line %d: %w", path, lines+1, err)
+		}
+		lines++
+		if d.Key != "" {
+			f.mem.put(d)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read inventory: %w", err)
+	}
+
+	f.lines = lines
+	if err := f.compactIfNeeded(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the location of the inventory file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Record notes that hosts were found at t and appends the updated devices
+// to the file, compacting it once superseded lines pile up.
+func (f *File) Record(t time.Time, hosts ...scanner.HostInfo) error {
+	updated := f.mem.record(t, hosts)
+	if len(updated) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range updated {
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("encode inventory: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return fmt.Errorf("create inventory directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("open inventory: %w", err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("write inventory: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write inventory: %w", err)
+	}
+	f.lines += len(updated)
+	return f.compactIfNeeded()
+}
+
+// Lookup returns the device recorded under key.
+func (f *File) Lookup(key string) (Device, bool) {
+	return f.mem.Lookup(key)
+}
+
+// Devices returns every recorded device, ordered by key.
+func (f *File) Devices() []Device {
+	return f.mem.Devices()
+}
+
+// compactIfNeeded compacts the file when it holds more than compactRatio
+// lines per device. f.mu must be held, except from Open.
+func (f *File) compactIfNeeded() error {
+	if f.lines <= compactRatio*f.mem.count() {
+		return nil
+	}
+	return f.compact()
+}
+
+// compact rewrites the file with the latest snapshot of each device. The
+// new file replaces the old one atomically.
+func (f *File) compact() error {
+	devices := f.mem.Devices()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range devices {
+		if err := enc.Encode(d); err != nil {
+			return fmt.Errorf("encode inventory: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".inventory-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("compact inventory: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("compact inventory: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("compact inventory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("compact inventory: %w", err)
+	}
+	f.lines = len(devices)
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package inventory remembers every device nls has found, across runs, so
+// that it can tell when a device was first and last seen and which
+// addresses and names it has used.
+package inventory
+
+import (
+	"net/netip"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"nls/internal/scanner"
+)
+
+// Device is everything recorded about one device. Devices are identified
+// by their MAC address; those found without one (beyond the local network
+// or by unprivileged scans) are identified by their IP address instead.
+type Device struct {
+	// Key identifies the device, as returned by scanner.HostInfo.Key
+	Key string `json:"key"`
+
+	// MAC is the device's hardware address, or "" when it was never known
+	MAC string `json:"mac,omitempty"`
+
+	// Vendor is the last known NIC vendor
+	Vendor string `json:"vendor,omitempty"`
+
+	// FirstSeen and LastSeen are when the device was first and last found
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	// IPs lists every address the device has used, in the order they were
+	// first seen
+	IPs []string `json:"ips,omitempty"`
+
+	// Hostnames lists every name the device has used, in the order they
+	// were first seen
+	Hostnames []string `json:"hostnames,omitempty"`
+}
+
+// Store records the devices found by scans. Memory implements it for a
+// single session and File persists it across runs.
+type Store interface {
+	// Record notes that hosts were found at t. Hosts without a MAC or IP
+	// address are ignored.
+	Record(t time.Time, hosts ...scanner.HostInfo) error
+
+	// Lookup returns the device recorded under key (see
+	// scanner.HostInfo.Key).
+	Lookup(key string) (Device, bool)
+
+	// Devices returns every recorded device, ordered by key.
+	Devices() []Device
+}
+
+// Memory is a Store that keeps devices in memory only. It is safe for
+// concurrent use.
+type Memory struct {
+	mu      sync.Mutex
+	devices map[string]Device
+}
+
+// NewMemory creates an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{devices: make(map[string]Device)}
+}
+
+// Record notes that hosts were found at t.
+func (m *Memory) Record(t time.Time, hosts ...scanner.HostInfo) error {
+	m.record(t, hosts)
+	return nil
+}
+
+// record merges hosts into the store and returns the updated devices.
+func (m *Memory) record(t time.Time, hosts []scanner.HostInfo) []Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := make([]Device, 0, len(hosts))
+	for _, h := range hosts {
+		key := h.Key()
+		if key == "" {
+			continue
+		}
+		d := merge(m.devices[key], key, h, t)
+		m.devices[key] = d
+		updated = append(updated, d)
+	}
+	return updated
+}
+
+// Lookup returns the device recorded under key.
+func (m *Memory) Lookup(key string) (Device, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.devices[key]
+	return d, ok
+}
+
+// Devices returns every recorded device, ordered by key.
+func (m *Memory) Devices() []Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	devices := make([]Device, 0, len(m.devices))
+	for _, d := range m.devices {
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Key < devices[j].Key
+	})
+	return devices
+}
+
+// count returns the number of recorded devices.
+func (m *Memory) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.devices)
+}
+
+// put stores d as it is, replacing any device with the same key.
+func (m *Memory) put(d Device) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.devices[d.Key] = d
+}
+
+// merge returns d updated with host h found at t. A zero d starts a new
+// device under key.
+func merge(d Device, key string, h scanner.HostInfo, t time.Time) Device {
+	if d.Key == "" {
+		d = Device{Key: key, FirstSeen: t}
+	}
+	if t.Before(d.FirstSeen) {
+		d.FirstSeen = t
+	}
+	if t.After(d.LastSeen) {
+		d.LastSeen = t
+	}
+	if h.MAC != nil {
+		d.MAC = h.MACString()
+	}
+	if h.Vendor != "" {
+		d.Vendor = h.Vendor
+	}
+
+	// Copy the lists so devices handed out earlier are not modified.
+	d.IPs = slices.Clone(d.IPs)
+	addrs := h.Addresses
+	if len(addrs) == 0 && h.IP.IsValid() {
+		addrs = []netip.Addr{h.IP}
+	}
+	for _, addr := range addrs {
+		d.IPs = appendNew(d.IPs, addr.String())
+	}
+
+	d.Hostnames = slices.Clone(d.Hostnames)
+	names := h.Hostnames
+	if len(names) == 0 && h.Hostname != "" {
+		names = []string{h.Hostname}
+	}
+	for _, name := range names {
+		d.Hostnames = appendNew(d.Hostnames, name)
+	}
+	return d
+}
+
+// appendNew appends s to list unless it is already there.
+func appendNew(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,175 @@
+package inventory
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nls/internal/scanner"
+)
+
+var (
+	monday  = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tuesday = monday.Add(24 * time.Hour)
+)
+
+func mustParseMAC(s string) net.HardwareAddr {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		panic(err)
+	}
+	return mac
+}
+
+func TestMemory_Record(t *testing.T) {
+	laptop := scanner.HostInfo{
+		IP:       netip.MustParseAddr("192.168.1.20"),
+		MAC:      mustParseMAC("aa:bb:cc:dd:ee:ff"),
+		Vendor:   "Apple",
+		Hostname: "laptop.lan",
+	}
+	moved := laptop
+	moved.IP = netip.MustParseAddr("192.168.1.31")
+	moved.Addresses = []netip.Addr{moved.IP, netip.MustParseAddr("fd00::31")}
+	moved.Hostname = ""
+	remote := scanner.HostInfo{IP: netip.MustParseAddr("10.0.0.1")}
+
+	store := NewMemory()
+	if err := store.Record(monday, laptop, remote, scanner.HostInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Record(tuesday, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Device{
+		{Key: "10.0.0.1", FirstSeen: monday, LastSeen: monday, IPs: []string{"10.0.0.1"}},
+		{
+			Key:       "AA:BB:CC:DD:EE:FF",
+			MAC:       "AA:BB:CC:DD:EE:FF",
+			Vendor:    "Apple",
+			FirstSeen: monday,
+			LastSeen:  tuesday,
+			IPs:       []string{"192.168.1.20", "192.168.1.31", "fd00::31"},
+			Hostnames: []string{"laptop.lan"},
+		},
+	}
+	if got := store.Devices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Devices() = %+v\nwant %+v", got, want)
+	}
+
+	if _, ok := store.Lookup("192.168.1.20"); ok {
+		t.Error("a device with a MAC should not be found by IP")
+	}
+}
+
+func TestMemory_RecordOutOfOrder(t *testing.T) {
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5")}
+	store := NewMemory()
+	_ = store.Record(tuesday, host)
+	_ = store.Record(monday, host)
+
+	d, _ := store.Lookup("192.168.1.5")
+	if !d.FirstSeen.Equal(monday) || !d.LastSeen.Equal(tuesday) {
+		t.Errorf("seen %v - %v; want %v - %v", d.FirstSeen, d.LastSeen, monday, tuesday)
+	}
+}
+
+func TestFile_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nls", "inventory.jsonl")
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("00:11:22:33:44:55")}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() on a missing file error = %v", err)
+	}
+	if err := f.Record(monday, host); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	host.IP = netip.MustParseAddr("192.168.1.6")
+	if err := f.Record(tuesday, host); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got, want := reopened.Devices(), f.Devices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened Devices() = %+v\nwant %+v", got, want)
+	}
+	d, _ := reopened.Lookup("00:11:22:33:44:55")
+	if !d.FirstSeen.Equal(monday) || !reflect.DeepEqual(d.IPs, []string{"192.168.1.5", "192.168.1.6"}) {
+		t.Errorf("reopened device = %+v", d)
+	}
+}
+
+func TestFile_Compacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.jsonl")
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5")}
+
+	f, _ := Open(path)
+	for i := range 10 {
+		if err := f.Record(monday.Add(time.Duration(i)*time.Minute), host); err != nil {
+			t.Fatal(err)
+		}
+		// A long-running watch never reopens the file: Record compacts it.
+		data, _ := os.ReadFile(path)
+		if lines := strings.Count(string(data), "\n"); lines > compactRatio {
+			t.Fatalf("file has %d lines after %d records; want at most %d", lines, i+1, compactRatio)
+		}
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	d, _ := reopened.Lookup("192.168.1.5")
+	if want := monday.Add(9 * time.Minute); !d.LastSeen.Equal(want) {
+		t.Errorf("LastSeen = %v; want %v", d.LastSeen, want)
+	}
+}
+
+func TestOpen_Compacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.jsonl")
+	line := "{\"key\":\"10.0.0.1\"}\n"
+	if err := os.WriteFile(path, []byte(strings.Repeat(line, compactRatio+1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("file has %d lines after opening; want 1", lines)
+	}
+}
+
+func TestOpen_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.jsonl")
+	if err := os.WriteFile(path, []byte("{\"key\":\"10.0.0.1\"}\nnot json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Open() error = %v; want the bad line reported", err)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	if got, _ := DefaultPath(); got != filepath.Join("/data", "nls", "inventory.jsonl") {
+		t.Errorf("DefaultPath() with XDG_DATA_HOME = %q", got)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if got, _ := DefaultPath(); got != filepath.Join("/home/me", ".local", "share", "nls", "inventory.jsonl") {
+		t.Errorf("DefaultPath() without XDG_DATA_HOME = %q", got)
+	}
+}
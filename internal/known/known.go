@@ -0,0 +1,181 @@
+// Package known reads the list of devices expected on the network, so that
+// hosts can be labelled and the ones nobody listed flagged as unknown.
+package known
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+
+	"nls/internal/scanner"
+	"nls/internal/tags"
+	"nls/internal/xdg"
+)
+
+// Device is an entry of the known-devices list.
+type Device struct {
+	// Key identifies the device: its MAC address in upper case, as
+	// scanner.HostInfo.Key gives it, or its IP address (see List.Lookup)
+	Key string
+
+	// Label names the device, e.g. "Reception printer"
+	Label string
+
+	// Owner is who is responsible for the device; it may be empty
+	Owner string
+
+	// IP is the address the device is expected at; it is invalid when the
+	// device may use any address
+	IP netip.Addr
+
+	// Tags group the device by role, e.g. "printer" or "iot" (see
+	// tags.Parse)
+	Tags []string
+}
+
+// Unexpected reports whether h, a host matching d, is not at the address d
+// is expected at.
+func (d Device) Unexpected(h scanner.HostInfo) bool {
+	return d.IP.IsValid() && h.IP.IsValid() && h.IP != d.IP
+}
+
+// List is a known-devices list. A nil List knows no device.
+type List struct {
+	devices map[string]Device
+}
+
+// DefaultPath returns where the known-devices list is looked for:
+// $XDG_CONFIG_HOME/nls/known-devices.csv, or ~/.config/nls/known-devices.csv
+// when XDG_CONFIG_HOME is not set.
+func DefaultPath() (string, error) {
+	return xdg.ConfigPath("known-devices.csv")
+}
+
+// Load reads the known-devices list at path (see Read).
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read known devices: %w", err)
+	}
+	defer f.Close()
+
+	l, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read known devices %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Read parses a known-devices list: CSV records of a MAC address (or an IP
+// address, for devices found without one or whose MAC changes), a label,
+// an owner, an expected IP address and tags separated by spaces or
+// semicolons. Only the first two fields are required. Lines starting with
+// "#" are comments, and a first record starting with "mac" is taken as a
+// header, as spreadsheets export it.
+func Read(r io.Reader) (*List, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	l := &List{devices: make(map[string]Device)}
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return l, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "mac") {
+			continue
+		}
+
+		d, err := parseDevice(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, dup := l.devices[d.Key]; dup {
+			return nil, fmt.Errorf("line %d: %s is listed twice", line, d.Key)
+		}
+		l.devices[d.Key] = d
+	}
+}
+
+// parseDevice parses the fields of one record.
+func parseDevice(record []string) (Device, error) {
+	for i := range record {
+		record[i] = strings.TrimSpace(record[i])
+	}
+	if len(record) < 2 || record[1] == "" {
+		return Device{}, fmt.Errorf("expected a MAC address and a label")
+	}
+	if len(record) > 5 {
+		return Device{}, fmt.Errorf("expected at most 5 fields (MAC, label, owner, IP, tags), got %d", len(record))
+	}
+
+	d := Device{Label: record[1]}
+	if mac, err := net.ParseMAC(record[0]); err == nil {
+		d.Key = scanner.HostInfo{MAC: mac}.Key()
+	} else if ip, err := netip.ParseAddr(record[0]); err == nil {
+		d.Key = ip.String()
+	} else {
+		return Device{}, fmt.Errorf("invalid MAC address %q", record[0])
+	}
+	if len(record) > 2 {
+		d.Owner = record[2]
+	}
+	if len(record) > 3 && record[3] != "" {
+		ip, err := netip.ParseAddr(record[3])
+		if err != nil {
+			return Device{}, fmt.Errorf("invalid expected IP address %q", record[3])
+		}
+		d.IP = ip
+	}
+	if len(record) > 4 {
+		d.Tags = tags.Parse(record[4])
+	}
+	return d, nil
+}
+
+// Len returns the number of devices listed.
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.devices)
+}
+
+// Lookup returns the listed device that h is, matched by HostInfo.Key, or
+// by h's IP address when its MAC address is not listed, so that devices
+// listed by IP are found whether or not the scan reported a MAC.
+func (l *List) Lookup(h scanner.HostInfo) (Device, bool) {
+	if l == nil {
+		return Device{}, false
+	}
+	if d, ok := l.devices[h.Key()]; ok {
+		return d, true
+	}
+	if h.MAC == nil || !h.IP.IsValid() {
+		return Device{}, false
+	}
+	d, ok := l.devices[h.IP.String()]
+	return d, ok
+}
+
+// Unknown returns the hosts that are not listed.
+func (l *List) Unknown(hosts []scanner.HostInfo) []scanner.HostInfo {
+	unknown := make([]scanner.HostInfo, 0)
+	for _, h := range hosts {
+		if _, ok := l.Lookup(h); !ok {
+			unknown = append(unknown, h)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,146 @@
+package known
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nls/internal/scanner"
+)
+
+func host(ip, mac string) scanner.HostInfo {
+	h := scanner.HostInfo{IP: netip.MustParseAddr(ip)}
+	if mac != "" {
+		var err error
+		if h.MAC, err = net.ParseMAC(mac); err != nil {
+			panic(err)
+		}
+	}
+	return h
+}
+
+const sample = `mac,label,owner,ip,tags
+# Office network
+00:11:22:33:44:55, Router, IT, 192.168.1.1
+aa-bb-cc-dd-ee-ff, "Reception printer, 1st floor", Facilities,, printer IoT
+10.0.0.7, VPN gateway
+`
+
+func TestRead(t *testing.T) {
+	l, err := Read(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if l.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", l.Len())
+	}
+
+	tests := []struct {
+		name string
+		host scanner.HostInfo
+		want Device
+		ok   bool
+	}{
+		{
+			name: "by MAC",
+			host: host("192.168.1.1", "00:11:22:33:44:55"),
+			want: Device{Key: "00:11:22:33:44:55", Label: "Router", Owner: "IT", IP: netip.MustParseAddr("192.168.1.1")},
+			ok:   true,
+		},
+		{
+			name: "MAC in another notation",
+			host: host("192.168.1.40", "AA:BB:CC:DD:EE:FF"),
+			want: Device{Key: "AA:BB:CC:DD:EE:FF", Label: "Reception printer, 1st floor", Owner: "Facilities", Tags: []string{"iot", "printer"}},
+			ok:   true,
+		},
+		{
+			name: "by IP without a MAC",
+			host: host("10.0.0.7", ""),
+			want: Device{Key: "10.0.0.7", Label: "VPN gateway"},
+			ok:   true,
+		},
+		{name: "unknown MAC", host: host("192.168.1.1", "66:11:22:33:44:55")},
+		{
+			name: "listed IP found with a MAC",
+			host: host("10.0.0.7", "66:11:22:33:44:07"),
+			want: Device{Key: "10.0.0.7", Label: "VPN gateway"},
+			ok:   true,
+		},
+		{
+			name: "listed MAC at a listed IP",
+			host: host("10.0.0.7", "00:11:22:33:44:55"),
+			want: Device{Key: "00:11:22:33:44:55", Label: "Router", Owner: "IT", IP: netip.MustParseAddr("192.168.1.1")},
+			ok:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := l.Lookup(tt.host)
+			if !reflect.DeepEqual(got, tt.want) || ok != tt.ok {
+				t.Errorf("Lookup() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	unknown := l.Unknown([]scanner.HostInfo{host("192.168.1.1", "00:11:22:33:44:55"), host("192.168.1.9", "")})
+	if len(unknown) != 1 || unknown[0].IPString() != "192.168.1.9" {
+		t.Errorf("Unknown() = %v, want only 192.168.1.9", unknown)
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name, input, wantErr string
+	}{
+		{"no label", "00:11:22:33:44:55\n", "line 1: expected a MAC address and a label"},
+		{"invalid MAC", "router, Router\n", `line 1: invalid MAC address "router"`},
+		{"invalid IP", "00:11:22:33:44:55, Router, IT, 192.168.1\n", "invalid expected IP address"},
+		{"too many fields", "00:11:22:33:44:55, Router, IT, 192.168.1.1, iot, extra\n", "at most 5 fields"},
+		{"duplicate", "# routers\n00:11:22:33:44:55, Router\n00-11-22-33-44-55, Old router\n", "line 3: 00:11:22:33:44:55 is listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known-devices.csv")
+	if err := os.WriteFile(path, []byte(sample), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := Load(path); err != nil || l.Len() != 3 {
+		t.Errorf("Load() = %v, %v; want 3 devices", l.Len(), err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Load() should fail for a missing file")
+	}
+}
+
+func TestDevice_Unexpected(t *testing.T) {
+	d := Device{Key: "00:11:22:33:44:55", IP: netip.MustParseAddr("192.168.1.1")}
+	if d.Unexpected(host("192.168.1.1", "00:11:22:33:44:55")) {
+		t.Error("Unexpected() at the expected address")
+	}
+	if !d.Unexpected(host("192.168.1.2", "00:11:22:33:44:55")) {
+		t.Error("Unexpected() should report another address")
+	}
+	if (Device{}).Unexpected(host("192.168.1.2", "")) {
+		t.Error("Unexpected() without an expected address")
+	}
+}
+
+func TestNilList(t *testing.T) {
+	var l *List
+	if _, ok := l.Lookup(host("192.168.1.1", "")); ok || l.Len() != 0 {
+		t.Error("a nil List should know no device")
+	}
+}
@@ -0,0 +1,232 @@
+// Package netif discovers the networks the local machine is attached to,
+// so nls can offer a sensible target when none is given.
+package netif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Interface is a local network interface and the addresses assigned to it.
+type Interface struct {
+	Name     string
+	Up       bool
+	Loopback bool
+
+	// Addrs are the interface addresses with their network prefix length
+	// (e.g., 192.168.1.5/24)
+	Addrs []netip.Prefix
+}
+
+// Enumerator lists local interfaces. System implements it for the running
+// machine; tests provide fake interfaces.
+type Enumerator interface {
+	// Interfaces returns every local interface.
+	Interfaces() ([]Interface, error)
+
+	// DefaultInterface returns the name of the interface that carries the
+	// default route, or "" when there is none.
+	DefaultInterface() (string, error)
+}
+
+// Network is a local subnet that can be offered as a scan target.
+type Network struct {
+	// Interface is the name of the interface attached to the network
+	Interface string
+
+	// Prefix is the masked network (e.g., 192.168.1.0/24)
+	Prefix netip.Prefix
+
+	// Default is true when Interface carries the default route
+	Default bool
+}
+
+// String describes the network for display, e.g. "192.168.1.0/24 (eth0, default route)".
+func (n Network) String() string {
+	if n.Default {
+		return fmt.Sprintf("%s (%s, default route)", n.Prefix, n.Interface)
+	}
+	return fmt.Sprintf("%s (%s)", n.Prefix, n.Interface)
+}
+
+// LocalNetworks returns the networks of every up, non-loopback interface,
+// skipping IPv6 link-local addresses. Networks on the default-route
+// interface come first, then IPv4 before IPv6, then by interface name.
+func LocalNetworks(e Enumerator) ([]Network, error) {
+	ifaces, err := e.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("list interfaces: %w", err)
+	}
+	defaultName, err := e.DefaultInterface()
+	if err != nil {
+		return nil, fmt.Errorf("find default route: %w", err)
+	}
+
+	var (
+		networks []Network
+		seen     = make(map[netip.Prefix]bool)
+	)
+	for _, ifi := range ifaces {
+		if !ifi.Up || ifi.Loopback {
+			continue
+		}
+		for _, addr := range ifi.Addrs {
+			if addr.Addr().IsLinkLocalUnicast() || addr.Addr().IsLoopback() {
+				continue
+			}
+			prefix := addr.Masked()
+			if seen[prefix] {
+				continue
+			}
+			seen[prefix] = true
+			networks = append(networks, Network{
+				Interface: ifi.Name,
+				Prefix:    prefix,
+				Default:   defaultName != "" && ifi.Name == defaultName,
+			})
+		}
+	}
+
+	sort.SliceStable(networks, func(i, j int) bool {
+		a, b := networks[i], networks[j]
+		if a.Default != b.Default {
+			return a.Default
+		}
+		if a.Prefix.Addr().Is4() != b.Prefix.Addr().Is4() {
+			return a.Prefix.Addr().Is4()
+		}
+		return a.Interface < b.Interface
+	})
+	return networks, nil
+}
+
+// DefaultNetworks returns the IPv4 networks of the default-route interface,
+// which is what "scan the LAN I'm on" means. It returns nil when there is
+// no default route or it has no IPv4 network.
+func DefaultNetworks(networks []Network) []Network {
+	var result []Network
+	for _, n := range networks {
+		if n.Default && n.Prefix.Addr().Is4() {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// Prompt lists networks on w and reads the user's choice from r. The
+// first network is chosen when the user just presses enter.
+func Prompt(r io.Reader, w io.Writer, networks []Network) (Network, error) {
+	if len(networks) == 0 {
+		return Network{}, fmt.Errorf("no local networks to choose from")
+	}
+
+	fmt.Fprintln(w, "Select a network to scan:")
+	for i, n := range networks {
+		fmt.Fprintf(w, "  %d) %s\n", i+1, n)
+	}
+	fmt.Fprintf(w, "Network [1-%d] (default 1): ", len(networks))
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return Network{}, fmt.Errorf("read selection: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return networks[0], nil
+	}
+	choice, err := strconv.Atoi(line)
+	if err != nil || choice < 1 || choice > len(networks) {
+		return Network{}, fmt.Errorf("invalid selection %q: enter a number from 1 to %d", line, len(networks))
+	}
+	return networks[choice-1], nil
+}
+
+// System enumerates the interfaces of the running machine.
+type System struct{}
+
+// Interfaces returns the machine's interfaces and their addresses.
+func (System) Interfaces() ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Interface, 0, len(ifaces))
+	for _, ifi := range ifaces {
+		iface := Interface{
+			Name:     ifi.Name,
+			Up:       ifi.Flags&net.FlagUp != 0,
+			Loopback: ifi.Flags&net.FlagLoopback != 0,
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if prefix, err := netip.ParsePrefix(ipNet.String()); err == nil {
+				iface.Addrs = append(iface.Addrs, prefix)
+			}
+		}
+		result = append(result, iface)
+	}
+	return result, nil
+}
+
+// routeProbes are documentation addresses used to ask the kernel which
+// local address it would use for off-link traffic. Connecting a UDP socket
+// performs the route lookup without sending any packet.
+var routeProbes = []struct{ network, address string }{
+	{"udp4", "192.0.2.1:9"},
+	{"udp6", "[2001:db8::1]:9"},
+}
+
+// DefaultInterface returns the interface the kernel routes off-link
+// traffic through, trying IPv4 before IPv6.
+func (s System) DefaultInterface() (string, error) {
+	ifaces, err := s.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	for _, probe := range routeProbes {
+		conn, err := net.Dial(probe.network, probe.address)
+		if err != nil {
+			continue
+		}
+		local, ok := conn.LocalAddr().(*net.UDPAddr)
+		_ = conn.Close()
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(local.IP)
+		if !ok {
+			continue
+		}
+		if name := interfaceWithAddr(ifaces, addr.Unmap()); name != "" {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// interfaceWithAddr returns the name of the interface that owns addr.
+func interfaceWithAddr(ifaces []Interface, addr netip.Addr) string {
+	for _, ifi := range ifaces {
+		for _, a := range ifi.Addrs {
+			if a.Addr() == addr {
+				return ifi.Name
+			}
+		}
+	}
+	return ""
+}
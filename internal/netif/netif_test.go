@@ -0,0 +1,134 @@
+package netif
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeEnumerator serves a fixed set of interfaces.
+type fakeEnumerator struct {
+	ifaces      []Interface
+	defaultName string
+	err         error
+}
+
+func (f fakeEnumerator) Interfaces() ([]Interface, error) { return f.ifaces, f.err }
+
+func (f fakeEnumerator) DefaultInterface() (string, error) { return f.defaultName, nil }
+
+func prefixes(s ...string) []netip.Prefix {
+	var result []netip.Prefix
+	for _, p := range s {
+		result = append(result, netip.MustParsePrefix(p))
+	}
+	return result
+}
+
+var testInterfaces = []Interface{
+	{Name: "lo", Up: true, Loopback: true, Addrs: prefixes("127.0.0.1/8", "::1/128")},
+	{Name: "docker0", Up: false, Addrs: prefixes("172.17.0.1/16")},
+	{Name: "wlan0", Up: true, Addrs: prefixes("10.20.0.7/24")},
+	{Name: "eth0", Up: true, Addrs: prefixes("fe80::1/64", "fd00::5/64", "192.168.1.5/24")},
+}
+
+func TestLocalNetworks(t *testing.T) {
+	got, err := LocalNetworks(fakeEnumerator{ifaces: testInterfaces, defaultName: "eth0"})
+	if err != nil {
+		t.Fatalf("LocalNetworks() error = %v", err)
+	}
+
+	want := []Network{
+		{Interface: "eth0", Prefix: netip.MustParsePrefix("192.168.1.0/24"), Default: true},
+		{Interface: "eth0", Prefix: netip.MustParsePrefix("fd00::/64"), Default: true},
+		{Interface: "wlan0", Prefix: netip.MustParsePrefix("10.20.0.0/24")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalNetworks() mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	defaults := DefaultNetworks(got)
+	if len(defaults) != 1 || defaults[0].Prefix.String() != "192.168.1.0/24" {
+		t.Errorf("DefaultNetworks() = %+v; want the IPv4 network of eth0", defaults)
+	}
+}
+
+func TestLocalNetworks_NoDefaultRoute(t *testing.T) {
+	got, err := LocalNetworks(fakeEnumerator{ifaces: testInterfaces})
+	if err != nil {
+		t.Fatalf("LocalNetworks() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Interface != "eth0" || got[0].Default {
+		t.Errorf("LocalNetworks() = %+v", got)
+	}
+	if d := DefaultNetworks(got); d != nil {
+		t.Errorf("DefaultNetworks() = %+v; want nil", d)
+	}
+}
+
+func TestLocalNetworks_Error(t *testing.T) {
+	_, err := LocalNetworks(fakeEnumerator{err: errors.New("boom")})
+	if err == nil || !strings.Contains(err.Error(), "list interfaces") {
+		t.Errorf("LocalNetworks() error = %v; want wrapped error", err)
+	}
+}
+
+func TestNetwork_String(t *testing.T) {
+	n := Network{Interface: "eth0", Prefix: netip.MustParsePrefix("192.168.1.0/24"), Default: true}
+	if got := n.String(); got != "192.168.1.0/24 (eth0, default route)" {
+		t.Errorf("String() = %q", got)
+	}
+	n.Default = false
+	if got := n.String(); got != "192.168.1.0/24 (eth0)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	networks := []Network{
+		{Interface: "eth0", Prefix: netip.MustParsePrefix("192.168.1.0/24")},
+		{Interface: "wlan0", Prefix: netip.MustParsePrefix("10.20.0.0/24")},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "choose second", input: "2\n", want: "10.20.0.0/24"},
+		{name: "enter picks first", input: "\n", want: "192.168.1.0/24"},
+		{name: "no trailing newline", input: "2", want: "10.20.0.0/24"},
+		{name: "out of range", input: "3\n", wantErr: true},
+		{name: "not a number", input: "eth0\n", wantErr: true},
+		{name: "end of input", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := Prompt(strings.NewReader(tt.input), &out, networks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prompt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Prefix.String() != tt.want {
+				t.Errorf("Prompt() = %s; want %s", got.Prefix, tt.want)
+			}
+			if !strings.Contains(out.String(), "2) 10.20.0.0/24 (wlan0)") {
+				t.Errorf("Prompt() did not list the networks:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestInterfaceWithAddr(t *testing.T) {
+	if got := interfaceWithAddr(testInterfaces, netip.MustParseAddr("10.20.0.7")); got != "wlan0" {
+		t.Errorf("interfaceWithAddr() = %q; want wlan0", got)
+	}
+	if got := interfaceWithAddr(testInterfaces, netip.MustParseAddr("10.20.0.8")); got != "" {
+		t.Errorf("interfaceWithAddr() = %q; want empty", got)
+	}
+}
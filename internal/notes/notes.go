@@ -0,0 +1,209 @@
+// Package notes keeps the aliases, free-text notes and tags users attach
+// to hosts, across runs, for devices whose scanned names say nothing about
+// what they are.
+package notes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"nls/internal/xdg"
+)
+
+// Entry is what the user wrote about one host.
+type Entry struct {
+	// Key identifies the host, as returned by scanner.HostInfo.Key: its
+	// MAC address, or its IP address when the MAC is unknown
+	Key string `json:"key"`
+
+	// Alias is a short name for the host, shown in the table
+	Alias string `json:"alias,omitempty"`
+
+	// Note is free text, shown in the detail pane
+	Note string `json:"note,omitempty"`
+
+	// Tags group the host by role, e.g. "printer" or "iot" (see
+	// tags.Parse)
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Empty reports whether e holds nothing worth keeping.
+func (e Entry) Empty() bool {
+	return e.Alias == "" && e.Note == "" && len(e.Tags) == 0
+}
+
+// Store holds the entries of hosts. Memory implements it for a single
+// session and File persists it across runs.
+type Store interface {
+	// Lookup returns the entry stored under key (see
+	// scanner.HostInfo.Key).
+	Lookup(key string) (Entry, bool)
+
+	// Set stores e under e.Key, replacing any entry there; an empty e
+	// removes it.
+	Set(e Entry) error
+
+	// Entries returns every entry, ordered by key.
+	Entries() []Entry
+}
+
+// Memory is a Store that keeps entries in memory only. It is safe for
+// concurrent use.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// NewMemory creates an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]Entry)}
+}
+
+// Lookup returns the entry stored under key.
+func (m *Memory) Lookup(key string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	return e, ok
+}
+
+// Set stores e under e.Key, or removes the entry when e is empty.
+func (m *Memory) Set(e Entry) error {
+	if e.Key == "" {
+		return fmt.Errorf("a host without MAC or IP address cannot be annotated")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e.Empty() {
+		delete(m.entries, e.Key)
+	} else {
+		m.entries[e.Key] = e
+	}
+	return nil
+}
+
+// Entries returns every entry, ordered by key.
+func (m *Memory) Entries() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]Entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// File is a Store persisted as a JSON-lines file, one entry per line. The
+// file is small and changes only when the user edits an entry, so every
+// change rewrites it.
+type File struct {
+	path string
+	mem  *Memory
+
+	// mu serializes rewrites of the file
+	mu sync.Mutex
+}
+
+// DefaultPath returns where notes are kept: $XDG_DATA_HOME/nls/notes.jsonl,
+// or ~/.local/share/nls/notes.jsonl when XDG_DATA_HOME is not set.
+func DefaultPath() (string, error) {
+	return xdg.DataPath("notes.jsonl")
+}
+
+// Open loads the notes file at path, which is created, along with its
+// directory, on the first Set if it does not exist.
+func Open(path string) (*File, error) {
+	f := &File{path: path, mem: NewMemory()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read notes: %w", err)
+	}
+
+	line := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		line++
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("read notes %s: line %d: %w", path, line, err)
+		}
+		if e.Key != "" && !e.Empty() {
+			f.mem.entries[e.Key] = e
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read notes: %w", err)
+	}
+	return f, nil
+}
+
+// Path returns the location of the notes file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Lookup returns the entry stored under key.
+func (f *File) Lookup(key string) (Entry, bool) {
+	return f.mem.Lookup(key)
+}
+
+// Entries returns every entry, ordered by key.
+func (f *File) Entries() []Entry {
+	return f.mem.Entries()
+}
+
+// Set stores e under e.Key, or removes the entry when e is empty, and
+// rewrites the file atomically. The entry is kept for the session even
+// when the file cannot be written.
+func (f *File) Set(e Entry) error {
+	if err := f.mem.Set(e); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range f.mem.Entries() {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encode notes: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return fmt.Errorf("create notes directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".notes-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("write notes: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write notes: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write notes: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("write notes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMemory_Set(t *testing.T) {
+	m := NewMemory()
+	nas := Entry{Key: "AA:BB:CC:DD:EE:FF", Alias: "NAS", Note: "Backups, in the rack", Tags: []string{"backup", "prod"}}
+	if err := m.Set(nas); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(Entry{Key: "10.0.0.7", Alias: "VPN"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m.Lookup("AA:BB:CC:DD:EE:FF"); !ok || !reflect.DeepEqual(got, nas) {
+		t.Errorf("Lookup() = %+v, %v; want %+v", got, ok, nas)
+	}
+	if got := m.Entries(); len(got) != 2 || got[0].Key != "10.0.0.7" {
+		t.Errorf("Entries() = %+v; want both, ordered by key", got)
+	}
+
+	// Clearing every field removes the entry
+	if err := m.Set(Entry{Key: "10.0.0.7"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Lookup("10.0.0.7"); ok {
+		t.Error("an empty entry should be removed")
+	}
+	if err := m.Set(Entry{Alias: "nothing"}); err == nil {
+		t.Error("Set() without a key should fail")
+	}
+}
+
+func TestFile_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nls", "notes.jsonl")
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() on a missing file error = %v", err)
+	}
+	for _, e := range []Entry{
+		{Key: "AA:BB:CC:DD:EE:FF", Alias: "NAS"},
+		{Key: "10.0.0.7", Alias: "VPN", Note: "Managed by the ISP"},
+		{Key: "AA:BB:CC:DD:EE:FF", Alias: "NAS", Note: "Backups", Tags: []string{"prod"}},
+	} {
+		if err := f.Set(e); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	want := []Entry{
+		{Key: "10.0.0.7", Alias: "VPN", Note: "Managed by the ISP"},
+		{Key: "AA:BB:CC:DD:EE:FF", Alias: "NAS", Note: "Backups", Tags: []string{"prod"}},
+	}
+	if got := reopened.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened Entries() = %+v\nwant %+v", got, want)
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("file has %d lines; want one per entry", lines)
+	}
+}
+
+func TestOpen_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.jsonl")
+	if err := os.WriteFile(path, []byte("{\"key\":\"10.0.0.1\",\"alias\":\"gw\"}\n\nnot json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Open() error = %v; want the bad line reported", err)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	if got, _ := DefaultPath(); got != filepath.Join("/data", "nls", "notes.jsonl") {
+		t.Errorf("DefaultPath() with XDG_DATA_HOME = %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"nls/internal/diff"
+)
+
+// Watch events, as reported in Event.Event.
+const (
+	// EventJoin is a host found that the previous scan did not find
+	EventJoin = "join"
+
+	// EventLeave is a host the previous scan found that is gone
+	EventLeave = "leave"
+
+	// EventChange is a host found at another IP address
+	EventChange = "change"
+)
+
+// Event is the stable JSON representation of a change seen by watch mode.
+type Event struct {
+	// Time is when the scan that saw the change finished
+	Time time.Time `json:"time"`
+
+	// Event is EventJoin, EventLeave or EventChange
+	Event string `json:"event"`
+
+	// Host is the host as last seen
+	Host Host `json:"host"`
+
+	// Previous is the host before an EventChange; omitted otherwise
+	Previous *Host `json:"previous,omitempty"`
+}
+
+// eventNames maps change kinds to event names.
+var eventNames = map[diff.Kind]string{
+	diff.Added:   EventJoin,
+	diff.Removed: EventLeave,
+	diff.Changed: EventChange,
+}
+
+// toEvent converts a change seen at t.
+func toEvent(t time.Time, c diff.Change) Event {
+	e := Event{Time: t, Event: eventNames[c.Kind], Host: toHost(c.Host())}
+	if c.Kind == diff.Changed {
+		previous := toHost(c.Old)
+		e.Previous = &previous
+	}
+	return e
+}
+
+// WriteEvents renders the changes seen at t to w, one per line: JSON
+// objects for FormatJSON (JSON lines), aligned plain text for FormatTable.
+// Returns an error for other formats or when writing fails.
+func WriteEvents(w io.Writer, format string, t time.Time, changes []diff.Change) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		for _, c := range changes {
+			if err := enc.Encode(toEvent(t, c)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, c := range changes {
+			fmt.Fprintln(tw, changeLine(c, t.Format(time.DateTime), eventNames[c.Kind]))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown event format %q: use %s or %s", format, FormatTable, FormatJSON)
+	}
+}
+
+// Diff is the stable JSON representation of the differences between two
+// results. The lists are empty, not omitted, when there is nothing in them.
+type Diff struct {
+	Added   []Host        `json:"added"`
+	Removed []Host        `json:"removed"`
+	Changed []HostChanged `json:"changed"`
+}
+
+// HostChanged is a host found in both results with a different IP address,
+// or a different MAC address at the same IP address.
+type HostChanged struct {
+	Old Host `json:"old"`
+	New Host `json:"new"`
+}
+
+// diffMarks prefix each change in the plain-text diff.
+var diffMarks = map[diff.Kind]string{
+	diff.Added:   "+",
+	diff.Removed: "-",
+	diff.Changed: "~",
+}
+
+// WriteDiff renders the differences between two results to w: a JSON
+// object for FormatJSON, or for FormatTable one aligned line per change,
+// marked "+" (added), "-" (removed) or "~" (changed), and a summary line.
+// Returns an error for other formats or when writing fails.
+func WriteDiff(w io.Writer, format string, changes []diff.Change) error {
+	switch format {
+	case FormatJSON:
+		d := Diff{Added: []Host{}, Removed: []Host{}, Changed: []HostChanged{}}
+		for _, c := range changes {
+			switch c.Kind {
+			case diff.Added:
+				d.Added = append(d.Added, toHost(c.New))
+			case diff.Removed:
+				d.Removed = append(d.Removed, toHost(c.Old))
+			case diff.Changed:
+				d.Changed = append(d.Changed, HostChanged{Old: toHost(c.Old), New: toHost(c.New)})
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, c := range changes {
+			fmt.Fprintln(tw, changeLine(c, diffMarks[c.Kind]))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w, diff.Summarize(changes))
+		return err
+	default:
+		return fmt.Errorf("unknown diff format %q: use %s or %s", format, FormatTable, FormatJSON)
+	}
+}
+
+// changeLine renders c as tab-separated plain text: the lead cells, the
+// host's fields with unknown values as placeholders, and what changed.
+func changeLine(c diff.Change, lead ...string) string {
+	values := append(lead, toHost(c.Host()).fields()...)
+	for i, v := range values {
+		if v == "" {
+			values[i] = tablePlaceholder
+		}
+	}
+	line := strings.Join(values, "\t")
+	if detail := c.Detail(); detail != "" {
+		line += "\t(" + detail + ")"
+	}
+	return line
+}
@@ -0,0 +1,164 @@
+// Package output renders scan results in non-interactive formats for
+// scripts, spreadsheets and other tools. The formats are documented in
+// docs/OUTPUT.md and are kept stable: fields are only ever appended.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"nls/internal/scanner"
+	"nls/internal/tags"
+)
+
+// Supported output formats.
+const (
+	// FormatJSON writes a JSON array of host objects
+	FormatJSON = "json"
+
+	// FormatCSV writes comma-separated values with a header row
+	FormatCSV = "csv"
+
+	// FormatTSV writes tab-separated values with a header row
+	FormatTSV = "tsv"
+
+	// FormatTable writes an aligned plain-text table for humans
+	FormatTable = "table"
+)
+
+// Formats lists every supported format in the order shown in help text.
+var Formats = []string{FormatTable, FormatJSON, FormatCSV, FormatTSV}
+
+// tablePlaceholder is shown in the plain table for unknown values.
+const tablePlaceholder = "-"
+
+// header is the column order used by the delimited and table formats.
+var header = []string{"ip", "mac", "vendor", "hostname"}
+
+// tagsColumn is the column the delimited formats append to header.
+const tagsColumn = "tags"
+
+// Host is the stable JSON representation of a scanner.HostInfo.
+// Unknown values are empty strings.
+type Host struct {
+	IP           string   `json:"ip"`
+	MAC          string   `json:"mac"`
+	Vendor       string   `json:"vendor"`
+	Hostname     string   `json:"hostname"`
+	AnsweredPort uint16   `json:"answered_port,omitempty"`
+	Addresses    []string `json:"addresses,omitempty"`
+	Hostnames    []string `json:"hostnames,omitempty"`
+	Ports        []Port   `json:"ports,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// Port is the stable JSON representation of a scanner.Port.
+type Port struct {
+	Port     uint16 `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`
+}
+
+// IsSupported reports whether format is one of Formats.
+func IsSupported(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// TagFunc returns the tags of a host, sorted (see tags.Merge).
+type TagFunc func(scanner.HostInfo) []string
+
+// Write renders hosts to w in the given format, with the tags given by
+// tagsOf in JSON, CSV and TSV; a nil tagsOf tags no host.
+// Returns an error for an unknown format or when writing fails.
+func Write(w io.Writer, format string, hosts []scanner.HostInfo, tagsOf TagFunc) error {
+	if tagsOf == nil {
+		tagsOf = func(scanner.HostInfo) []string { return nil }
+	}
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, hosts, tagsOf)
+	case FormatCSV:
+		return writeDelimited(w, ',', hosts, tagsOf)
+	case FormatTSV:
+		return writeDelimited(w, '\t', hosts, tagsOf)
+	case FormatTable:
+		return writeTable(w, hosts)
+	default:
+		return fmt.Errorf("unknown output format %q: use %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// toHost converts a HostInfo, rendering unknown values as "".
+func toHost(h scanner.HostInfo) Host {
+	host := Host{
+		IP:           h.IPString(),
+		MAC:          h.MACString(),
+		Vendor:       h.Vendor,
+		Hostname:     h.Hostname,
+		AnsweredPort: h.AnsweredPort,
+		Hostnames:    h.Hostnames,
+	}
+	for _, addr := range h.Addresses {
+		host.Addresses = append(host.Addresses, addr.String())
+	}
+	for _, p := range h.Ports {
+		host.Ports = append(host.Ports, Port{Port: p.Number, Protocol: p.Protocol, Service: p.Service})
+	}
+	return host
+}
+
+// fields returns the values of h in header order.
+func (h Host) fields() []string {
+	return []string{h.IP, h.MAC, h.Vendor, h.Hostname}
+}
+
+func writeJSON(w io.Writer, hosts []scanner.HostInfo, tagsOf TagFunc) error {
+	records := make([]Host, 0, len(hosts))
+	for _, h := range hosts {
+		record := toHost(h)
+		record.Tags = tagsOf(h)
+		records = append(records, record)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+func writeDelimited(w io.Writer, comma rune, hosts []scanner.HostInfo, tagsOf TagFunc) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(append(header[:len(header):len(header)], tagsColumn)); err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		if err := cw.Write(append(toHost(h).fields(), tags.String(tagsOf(h)))); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, hosts []scanner.HostInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, h := range hosts {
+		values := toHost(h).fields()
+		for i, v := range values {
+			if v == "" {
+				values[i] = tablePlaceholder
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,259 @@
+package output
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nls/internal/diff"
+	"nls/internal/scanner"
+)
+
+var testHosts = []scanner.HostInfo{
+	{
+		IP:        netip.MustParseAddr("192.168.1.1"),
+		MAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		Vendor:    "Router, Inc",
+		Hostname:  "router.local",
+		Addresses: []netip.Addr{netip.MustParseAddr("192.168.1.1"), netip.MustParseAddr("fd00::1")},
+		Hostnames: []string{"router.local", "gw.local"},
+	},
+	{
+		IP:           netip.MustParseAddr("192.168.1.20"),
+		AnsweredPort: 22,
+		Ports:        []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh"}, {Number: 8443, Protocol: "tcp"}},
+	},
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatJSON,
+			want: `[
+  {
+    "ip": "192.168.1.1",
+    "mac": "00:11:22:33:44:55",
+    "vendor": "Router, Inc",
+    "hostname": "router.local",
+    "addresses": [
+      "192.168.1.1",
+      "fd00::1"
+    ],
+    "hostnames": [
+      "router.local",
+      "gw.local"
+    ]
+  },
+  {
+    "ip": "192.168.1.20",
+    "mac": "",
+    "vendor": "",
+    "hostname": "",
+    "answered_port": 22,
+    "ports": [
+      {
+        "port": 22,
+        "protocol": "tcp",
+        "service": "ssh"
+      },
+      {
+        "port": 8443,
+        "protocol": "tcp",
+        "service": ""
+      }
+    ]
+  }
+]
+`,
+		},
+		{
+			format: FormatCSV,
+			want: "ip,mac,vendor,hostname,tags\n" +
+				"192.168.1.1,00:11:22:33:44:55,\"Router, Inc\",router.local,\n" +
+				"192.168.1.20,,,,\n",
+		},
+		{
+			format: FormatTSV,
+			want: "ip\tmac\tvendor\thostname\ttags\n" +
+				"192.168.1.1\t00:11:22:33:44:55\tRouter, Inc\trouter.local\t\n" +
+				"192.168.1.20\t\t\t\t\n",
+		},
+		{
+			format: FormatTable,
+			want: "IP            MAC                VENDOR       HOSTNAME\n" +
+				"192.168.1.1   00:11:22:33:44:55  Router, Inc  router.local\n" +
+				"192.168.1.20  -                  -            -\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, testHosts, nil); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() output mismatch:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite_NoHosts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, nil, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("Write() = %q; want an empty JSON array", got)
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	err := Write(&bytes.Buffer{}, "xml", testHosts, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("Write() error = %v; want unknown format error", err)
+	}
+}
+
+func TestWrite_Tags(t *testing.T) {
+	tagsOf := func(h scanner.HostInfo) []string {
+		if h.MAC == nil {
+			return nil
+		}
+		return []string{"network", "prod"}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, testHosts, tagsOf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "ip,mac,vendor,hostname,tags\n" +
+		"192.168.1.1,00:11:22:33:44:55,\"Router, Inc\",router.local,network prod\n" +
+		"192.168.1.20,,,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Write() CSV =\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatJSON, testHosts, tagsOf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := buf.String(); strings.Count(got, `"tags"`) != 1 || !strings.Contains(got, `"tags": [
+      "network",
+      "prod"
+    ]`) {
+		t.Errorf("Write() JSON should list the tags of the tagged host only, got:\n%s", got)
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	for _, f := range Formats {
+		if !IsSupported(f) {
+			t.Errorf("IsSupported(%q) = false; want true", f)
+		}
+	}
+	if IsSupported("yaml") {
+		t.Error("IsSupported(\"yaml\") = true; want false")
+	}
+}
+
+func TestWriteEvents(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 5, 0, 0, time.UTC)
+	moved := testHosts[0]
+	moved.IP = netip.MustParseAddr("192.168.1.2")
+	moved.Addresses = nil
+	moved.Hostnames = nil
+	changes := []diff.Change{
+		{Kind: diff.Added, New: testHosts[1]},
+		{Kind: diff.Changed, Old: testHosts[0], New: moved},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatJSON,
+			want: `{"time":"2026-03-02T09:05:00Z","event":"join","host":{"ip":"192.168.1.20","mac":"","vendor":"","hostname":"","answered_port":22,"ports":[{"port":22,"protocol":"tcp","service":"ssh"},{"port":8443,"protocol":"tcp","service":""}]}}
+{"time":"2026-03-02T09:05:00Z","event":"change","host":{"ip":"192.168.1.2","mac":"00:11:22:33:44:55","vendor":"Router, Inc","hostname":"router.local"},"previous":{"ip":"192.168.1.1","mac":"00:11:22:33:44:55","vendor":"Router, Inc","hostname":"router.local","addresses":["192.168.1.1","fd00::1"],"hostnames":["router.local","gw.local"]}}
+`,
+		},
+		{
+			format: FormatTable,
+			want: `2026-03-02 09:05:00  join    192.168.1.20  -                  -            -
+2026-03-02 09:05:00  change  192.168.1.2   00:11:22:33:44:55  Router, Inc  router.local  (IP 192.168.1.1 → 192.168.1.2)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteEvents(&buf, tt.format, at, changes); err != nil {
+				t.Fatalf("WriteEvents() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteEvents() output mismatch:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if err := WriteEvents(&bytes.Buffer{}, FormatCSV, at, changes); err == nil {
+		t.Error("WriteEvents() as CSV should fail")
+	}
+}
+
+func TestRead_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, testHosts, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, testHosts) {
+		t.Errorf("Read() = %+v\nwant %+v", got, testHosts)
+	}
+
+	if _, err := Read(strings.NewReader(`[{"ip":"192.168.1.300"}]`)); err == nil || !strings.Contains(err.Error(), "host 1") {
+		t.Errorf("Read() error = %v; want the bad host reported", err)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	moved := testHosts[1]
+	moved.IP = netip.MustParseAddr("192.168.1.21")
+	changes := []diff.Change{
+		{Kind: diff.Removed, Old: testHosts[0]},
+		{Kind: diff.Changed, Old: testHosts[1], New: moved},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, FormatTable, changes); err != nil {
+		t.Fatalf("WriteDiff() error = %v", err)
+	}
+	want := `-  192.168.1.1   00:11:22:33:44:55  Router, Inc  router.local
+~  192.168.1.21  -                  -            -  (IP 192.168.1.20 → 192.168.1.21)
++0 new, −1 gone, 1 changed
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteDiff() output mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteDiff(&buf, FormatJSON, nil); err != nil {
+		t.Fatalf("WriteDiff() error = %v", err)
+	}
+	if got, want := buf.String(), "{\n  \"added\": [],\n  \"removed\": [],\n  \"changed\": []\n}\n"; got != want {
+		t.Errorf("WriteDiff() without changes = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+
+	"nls/internal/scanner"
+)
+
+// Read parses the hosts of a result written in FormatJSON, so that saved
+// results can be compared. Unknown fields are ignored and empty values are
+// left unknown.
+func Read(r io.Reader) ([]scanner.HostInfo, error) {
+	var records []Host
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("parse JSON result: %w", err)
+	}
+
+	hosts := make([]scanner.HostInfo, 0, len(records))
+	for i, rec := range records {
+		h, err := rec.hostInfo()
+		if err != nil {
+			return nil, fmt.Errorf("parse JSON result: host %d: %w", i+1, err)
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+// hostInfo converts h back to a HostInfo.
+func (h Host) hostInfo() (scanner.HostInfo, error) {
+	host := scanner.HostInfo{
+		Vendor:       h.Vendor,
+		Hostname:     h.Hostname,
+		Hostnames:    h.Hostnames,
+		AnsweredPort: h.AnsweredPort,
+	}
+	if h.IP != "" {
+		ip, err := netip.ParseAddr(h.IP)
+		if err != nil {
+			return scanner.HostInfo{}, fmt.Errorf("invalid ip: %w", err)
+		}
+		host.IP = ip
+	}
+	if h.MAC != "" {
+		mac, err := net.ParseMAC(h.MAC)
+		if err != nil {
+			return scanner.HostInfo{}, fmt.Errorf("invalid mac: %w", err)
+		}
+		host.MAC = mac
+	}
+	for _, a := range h.Addresses {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return scanner.HostInfo{}, fmt.Errorf("invalid address: %w", err)
+		}
+		host.Addresses = append(host.Addresses, addr)
+	}
+	for _, p := range h.Ports {
+		host.Ports = append(host.Ports, scanner.Port{Number: p.Port, Protocol: p.Protocol, Service: p.Service})
+	}
+	return host, nil
+}
@@ -2,6 +2,8 @@
 // during long-running operations like network scanning.
 package progress
 
+import "time"
+
 // Reporter defines the interface for progress reporting during scans.
 // Implementations can provide visual feedback (spinners, progress bars)
 // or silent operation for non-interactive contexts.
@@ -12,6 +14,10 @@ type Reporter interface {
 	// Update signals progress is being made (e.g., animate spinner)
 	Update()
 
+	// Progress reports determinate progress: done out of total units of
+	// work, with eta as the estimated time remaining (zero if unknown)
+	Progress(done, total int, eta time.Duration)
+
 	// Finish stops progress reporting and cleans up
 	Finish()
 }
@@ -26,5 +32,8 @@ func (NoOp) Start(string) {}
 // Update is a no-op implementation.
 func (NoOp) Update() {}
 
+// Progress is a no-op implementation.
+func (NoOp) Progress(int, int, time.Duration) {}
+
 // Finish is a no-op implementation.
 func (NoOp) Finish() {}
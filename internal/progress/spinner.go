@@ -1,13 +1,21 @@
 package progress
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/schollz/progressbar/v3"
 )
 
 // Spinner provides visual feedback during scanning using a progress spinner.
-// It implements the Reporter interface using the progressbar library.
+// It implements the Reporter interface using the progressbar library and
+// draws on stderr so it never mixes with results written to stdout.
+// Once determinate progress is reported the spinner turns into a bar
+// showing the percentage and estimated time remaining.
 type Spinner struct {
-	bar *progressbar.ProgressBar
+	bar     *progressbar.ProgressBar
+	message string
 }
 
 // NewSpinner creates a new Spinner progress reporter.
@@ -18,10 +26,13 @@ func NewSpinner() *Spinner {
 
 // Start begins displaying the progress spinner with the given message.
 func (s *Spinner) Start(message string) {
+	s.message = message
 	s.bar = progressbar.NewOptions(
 		-1,
 		progressbar.OptionSetDescription(message),
 		progressbar.OptionSpinnerType(14),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionClearOnFinish(),
 	)
 }
 
@@ -33,6 +44,20 @@ func (s *Spinner) Update() {
 	}
 }
 
+// Progress switches the spinner to a determinate bar and sets its position.
+func (s *Spinner) Progress(done, total int, eta time.Duration) {
+	if s.bar == nil || total <= 0 {
+		return
+	}
+	if s.bar.GetMax() != total {
+		s.bar.ChangeMax(total)
+	}
+	if eta > 0 {
+		s.bar.Describe(fmt.Sprintf("%s (ETA %s)", s.message, eta.Round(time.Second)))
+	}
+	_ = s.bar.Set(done)
+}
+
 // Finish stops the spinner and clears the display.
 func (s *Spinner) Finish() {
 	if s.bar != nil {
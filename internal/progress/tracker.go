@@ -0,0 +1,82 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// State is a snapshot of the progress recorded by a Tracker.
+type State struct {
+	// Active is true between Start and Finish
+	Active bool
+
+	// Message is the text passed to Start
+	Message string
+
+	// Done and Total are the last determinate progress reported;
+	// Total is zero while progress is indeterminate
+	Done  int
+	Total int
+
+	// ETA is the last estimated time remaining (zero if unknown)
+	ETA time.Duration
+}
+
+// Percent returns the completion percentage, and false while progress
+// is indeterminate.
+func (s State) Percent() (float64, bool) {
+	if s.Total <= 0 {
+		return 0, false
+	}
+	pct := float64(s.Done) / float64(s.Total) * 100
+	if pct > 100 {
+		pct = 100
+	}
+	return pct, true
+}
+
+// Tracker is a Reporter that records the latest progress instead of
+// drawing it, so another component (such as the TUI) can poll it.
+// It is safe for concurrent use.
+type Tracker struct {
+	mu    sync.Mutex
+	state State
+}
+
+// NewTracker creates a new Tracker progress reporter.
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Start resets the tracker and marks it active.
+func (t *Tracker) Start(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state = State{Active: true, Message: message}
+}
+
+// Update is a no-op; indeterminate activity is implied by Active.
+func (t *Tracker) Update() {}
+
+// Progress records determinate progress.
+func (t *Tracker) Progress(done, total int, eta time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Done = done
+	t.state.Total = total
+	t.state.ETA = eta
+}
+
+// Finish marks the tracker inactive.
+func (t *Tracker) Finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Active = false
+}
+
+// Snapshot returns the current progress state.
+func (t *Tracker) Snapshot() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
@@ -0,0 +1,48 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	if tr.Snapshot().Active {
+		t.Error("new tracker should be inactive")
+	}
+
+	tr.Start("Scanning")
+	tr.Update()
+	if _, ok := tr.Snapshot().Percent(); ok {
+		t.Error("Percent() should be indeterminate before Progress is reported")
+	}
+
+	tr.Progress(25, 200, 3*time.Second)
+	state := tr.Snapshot()
+	if !state.Active || state.Message != "Scanning" {
+		t.Errorf("Snapshot() = %+v; want active with message", state)
+	}
+	if pct, ok := state.Percent(); !ok || pct != 12.5 {
+		t.Errorf("Percent() = %v, %v; want 12.5, true", pct, ok)
+	}
+	if state.ETA != 3*time.Second {
+		t.Errorf("ETA = %v; want 3s", state.ETA)
+	}
+
+	tr.Finish()
+	if tr.Snapshot().Active {
+		t.Error("tracker should be inactive after Finish")
+	}
+
+	// A new scan starts from scratch.
+	tr.Start("Rescanning")
+	if _, ok := tr.Snapshot().Percent(); ok {
+		t.Error("Start should reset progress")
+	}
+}
+
+func TestState_PercentClamped(t *testing.T) {
+	if pct, _ := (State{Done: 5, Total: 4}).Percent(); pct != 100 {
+		t.Errorf("Percent() = %v; want 100", pct)
+	}
+}
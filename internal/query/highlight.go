@@ -0,0 +1,102 @@
+package query
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Span is the byte range [Start, End) of a match in a text.
+type Span struct {
+	Start, End int
+}
+
+// Highlights returns the parts of text, the value of field f of a host as
+// shown, that the terms of q look for, ordered and without overlaps. Terms
+// under a negation found nothing and are left out; a term on another field
+// highlights nothing in f.
+func (q Query) Highlights(f Field, text string) []Span {
+	var spans []Span
+	for _, t := range positiveTerms(q.root, nil) {
+		if t.field == FieldAny || t.field == f {
+			spans = append(spans, t.spans(f, text)...)
+		}
+	}
+	return mergeSpans(spans)
+}
+
+// positiveTerms appends to terms the terms of n that are not negated.
+func positiveTerms(n node, terms []term) []term {
+	switch n := n.(type) {
+	case andNode:
+		return positiveTerms(n.right, positiveTerms(n.left, terms))
+	case orNode:
+		return positiveTerms(n.right, positiveTerms(n.left, terms))
+	case term:
+		return append(terms, n)
+	}
+	return terms
+}
+
+// spans returns the parts of text, the value of field f, that t matches,
+// following the rules of matchField.
+func (t term) spans(f Field, text string) []Span {
+	var spans []Span
+	switch {
+	case f == FieldPort && t.re == nil:
+		// The table shows port numbers only, e.g. "22,80,443"
+		number, _, _ := strings.Cut(t.text, "/")
+		start := 0
+		for _, item := range strings.Split(text, ",") {
+			if strings.TrimSpace(item) == number {
+				spans = append(spans, Span{start, start + len(item)})
+			}
+			start += len(item) + 1
+		}
+	case f == FieldIP && t.network.IsValid():
+		value := strings.TrimSpace(text)
+		if addr, err := netip.ParseAddr(value); err == nil && t.network.Contains(addr) {
+			spans = append(spans, Span{0, len(value)})
+		}
+	case f == FieldTag && t.field == FieldTag && t.re == nil:
+		start := 0
+		for _, tag := range strings.Split(text, " ") {
+			if strings.EqualFold(tag, t.text) {
+				spans = append(spans, Span{start, start + len(tag)})
+			}
+			start += len(tag) + 1
+		}
+	case t.re != nil:
+		for _, loc := range t.re.FindAllStringIndex(text, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, Span{loc[0], loc[1]})
+			}
+		}
+	default:
+		for i := 0; i+len(t.text) <= len(text); {
+			if strings.EqualFold(text[i:i+len(t.text)], t.text) {
+				spans = append(spans, Span{i, i + len(t.text)})
+				i += len(t.text)
+				continue
+			}
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+		}
+	}
+	return spans
+}
+
+// mergeSpans sorts spans and joins the overlapping ones.
+func mergeSpans(spans []Span) []Span {
+	slices.SortFunc(spans, func(a, b Span) int { return a.Start - b.Start })
+	merged := spans[:0]
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, s.End)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
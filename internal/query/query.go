@@ -0,0 +1,455 @@
+// Package query parses the search queries of the TUI and matches hosts
+// against them. A query is a list of terms that must all match; a term is a
+// word matched against every field of a host, or a field-qualified one such
+// as vendor:apple, ip:10.0.1.0/26 or host:/^db\d+/. Terms are negated with
+// "-", combined with OR (AND is implied but may be written) and grouped with
+// parentheses.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nls/internal/scanner"
+	"nls/internal/tags"
+)
+
+// Field is the part of a host a term is matched against.
+type Field string
+
+// Fields a term can be qualified with. FieldAny matches a word against all
+// of them.
+const (
+	FieldAny    Field = ""
+	FieldIP     Field = "ip"
+	FieldMAC    Field = "mac"
+	FieldVendor Field = "vendor"
+	FieldHost   Field = "host"
+	FieldPort   Field = "port"
+	FieldLabel  Field = "label"
+	FieldAlias  Field = "alias"
+	FieldNote   Field = "note"
+	FieldTag    Field = "tag"
+)
+
+// fieldNames maps the qualifiers accepted in queries to their field.
+var fieldNames = map[string]Field{
+	"ip":       FieldIP,
+	"mac":      FieldMAC,
+	"vendor":   FieldVendor,
+	"host":     FieldHost,
+	"hostname": FieldHost,
+	"port":     FieldPort,
+	"label":    FieldLabel,
+	"alias":    FieldAlias,
+	"note":     FieldNote,
+	"tag":      FieldTag,
+}
+
+// anyFields are the fields an unqualified term is matched against.
+var anyFields = []Field{FieldIP, FieldMAC, FieldVendor, FieldHost, FieldPort, FieldLabel, FieldAlias, FieldNote, FieldTag}
+
+// Subject is what a query is matched against: a host and what the user
+// knows about it.
+type Subject struct {
+	scanner.HostInfo
+
+	// Label is the host's label in the known-devices list
+	Label string
+
+	// Alias and Note are what the user wrote about the host
+	Alias, Note string
+
+	// Tags are the host's tags, from both the list and the notes
+	Tags []string
+}
+
+// Query is a parsed search query. The zero Query matches every host.
+type Query struct {
+	root node
+}
+
+// node is an element of a parsed query.
+type node interface {
+	match(s Subject) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(s Subject) bool { return n.left.match(s) && n.right.match(s) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(s Subject) bool { return n.left.match(s) || n.right.match(s) }
+
+type notNode struct{ x node }
+
+func (n notNode) match(s Subject) bool { return !n.x.match(s) }
+
+// Match reports whether s matches q.
+func (q Query) Match(s Subject) bool {
+	return q.root == nil || q.root.match(s)
+}
+
+// Parse parses a query. Words are matched case-insensitively as substrings,
+// except that:
+//   - port: and unqualified numbers match a port number exactly ("22",
+//     "22/tcp"), so that 22 does not find port 8022
+//   - tag: matches a whole tag
+//   - ip: with an address matches it exactly, and with a network (CIDR)
+//     every address in it
+//   - a value between slashes is a regular expression, and one between
+//     double quotes may contain spaces
+//
+// Unknown values never match.
+func Parse(s string) (Query, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return Query{}, err
+	}
+	if len(toks) == 0 {
+		return Query{}, nil
+	}
+
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return Query{}, err
+	}
+	if t, ok := p.peek(); ok {
+		return Query{}, fmt.Errorf("unexpected %q", t.text)
+	}
+	return Query{root: root}, nil
+}
+
+// tokenKind is the kind of a token of a query.
+type tokenKind int
+
+const (
+	tokTerm tokenKind = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+)
+
+// token is a lexed part of a query; text is how it was written.
+type token struct {
+	kind tokenKind
+	text string
+	term term
+}
+
+// lex splits s into tokens.
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			toks = append(toks, token{kind: tokOpen, text: "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokClose, text: ")"})
+			i++
+		case c == '-' && i+1 < len(s) && !strings.ContainsRune(" \t)", rune(s[i+1])):
+			toks = append(toks, token{kind: tokNot, text: "-"})
+			i++
+		default:
+			t, n, err := lexTerm(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, t)
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+// lexTerm lexes the term, or the AND or OR operator, at the start of s and
+// returns it with its length.
+func lexTerm(s string) (token, int, error) {
+	field, i := FieldAny, 0
+	if j := strings.IndexByte(s, ':'); j > 0 {
+		if f, ok := fieldNames[strings.ToLower(s[:j])]; ok {
+			field, i = f, j+1
+		}
+	}
+
+	var value string
+	var regex, quoted bool
+	n := i
+	switch {
+	case i < len(s) && s[i] == '"':
+		end := strings.IndexByte(s[i+1:], '"')
+		if end < 0 {
+			return token{}, 0, errors.New("missing closing \"")
+		}
+		value, quoted, n = s[i+1:i+1+end], true, i+end+2
+	case i < len(s) && s[i] == '/':
+		end := -1
+		for k := i + 1; k < len(s); k++ {
+			if s[k] == '\\' {
+				k++
+			} else if s[k] == '/' {
+				end = k
+				break
+			}
+		}
+		if end < 0 {
+			return token{}, 0, errors.New("missing closing / of the regular expression")
+		}
+		value, regex, n = strings.ReplaceAll(s[i+1:end], `\/`, "/"), true, end+1
+	default:
+		end := strings.IndexAny(s[i:], " \t()")
+		if end < 0 {
+			end = len(s) - i
+		}
+		value, n = s[i:i+end], i+end
+	}
+	text := s[:n]
+
+	if field == FieldAny && !regex && !quoted && (value == "AND" || value == "OR") {
+		kind := tokAnd
+		if value == "OR" {
+			kind = tokOr
+		}
+		return token{kind: kind, text: text}, n, nil
+	}
+	if value == "" && !regex {
+		return token{}, 0, fmt.Errorf("%s needs a value to search for", text)
+	}
+
+	t, err := newTerm(field, value, regex)
+	if err != nil {
+		return token{}, 0, err
+	}
+	return token{kind: tokTerm, text: text, term: t}, n, nil
+}
+
+// parser builds the tree of a query from its tokens: OR binds looser than
+// AND, which binds looser than negation.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// peek returns the next token, if any.
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if t, ok := p.peek(); !ok || t.kind != tokOr {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokOr || t.kind == tokClose {
+			return left, nil
+		}
+		if t.kind == tokAnd {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("expected a search term after %q", p.toks[p.pos-1].text)
+	}
+	p.pos++
+	switch t.kind {
+	case tokTerm:
+		return t.term, nil
+	case tokNot:
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	case tokOpen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokClose {
+			return nil, errors.New("missing closing )")
+		}
+		p.pos++
+		return x, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+// term matches one field of a host, or all of them for FieldAny.
+type term struct {
+	field Field
+
+	// text is the lower-case word to look for, when re is nil
+	text string
+	re   *regexp.Regexp
+
+	// network is set for ip: with an address or a network
+	network netip.Prefix
+}
+
+// newTerm builds the term for value in field.
+func newTerm(field Field, value string, regex bool) (term, error) {
+	t := term{field: field}
+	switch {
+	case regex:
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return term{}, fmt.Errorf("invalid regular expression /%s/: %w", value, err)
+		}
+		t.re = re
+	case field == FieldIP && strings.Contains(value, "/"):
+		network, err := netip.ParsePrefix(value)
+		if err != nil {
+			return term{}, fmt.Errorf("invalid network %q", value)
+		}
+		t.network = network.Masked()
+	case field == FieldIP:
+		if addr, err := netip.ParseAddr(value); err == nil {
+			t.network = netip.PrefixFrom(addr, addr.BitLen())
+		}
+	}
+	t.text = strings.ToLower(value)
+	if field == FieldMAC {
+		t.text = strings.ReplaceAll(t.text, "-", ":")
+	}
+	return t, nil
+}
+
+func (t term) match(s Subject) bool {
+	if t.field != FieldAny {
+		return t.matchField(s, t.field)
+	}
+	for _, f := range anyFields {
+		if t.matchField(s, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchField reports whether field f of s matches t.
+func (t term) matchField(s Subject, f Field) bool {
+	switch {
+	case f == FieldPort && t.re == nil:
+		return portMatches(s.Ports, t.text)
+	case f == FieldIP && t.network.IsValid():
+		for _, addr := range addrs(s.HostInfo) {
+			if t.network.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	case f == FieldTag && t.field == FieldTag && t.re == nil:
+		return tags.Has(s.Tags, t.text)
+	}
+
+	for _, v := range values(s, f) {
+		if v == "" {
+			continue
+		}
+		if t.re != nil && t.re.MatchString(v) || t.re == nil && strings.Contains(strings.ToLower(v), t.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the text of field f of s that terms are matched against;
+// unknown values are empty strings.
+func values(s Subject, f Field) []string {
+	switch f {
+	case FieldIP:
+		values := []string{s.IPString()}
+		for _, addr := range s.Addresses {
+			values = append(values, addr.String())
+		}
+		return values
+	case FieldMAC:
+		return []string{s.MACString()}
+	case FieldVendor:
+		return []string{s.Vendor}
+	case FieldHost:
+		return append([]string{s.Hostname}, s.Hostnames...)
+	case FieldPort:
+		values := make([]string, 0, 2*len(s.Ports))
+		for _, p := range s.Ports {
+			values = append(values, p.String(), p.Service)
+		}
+		return values
+	case FieldLabel:
+		return []string{s.Label}
+	case FieldAlias:
+		return []string{s.Alias}
+	case FieldNote:
+		return []string{s.Note}
+	case FieldTag:
+		return s.Tags
+	}
+	return nil
+}
+
+// addrs returns every valid address of h.
+func addrs(h scanner.HostInfo) []netip.Addr {
+	all := make([]netip.Addr, 0, 1+len(h.Addresses))
+	if h.IP.IsValid() {
+		all = append(all, h.IP)
+	}
+	return append(all, h.Addresses...)
+}
+
+// portMatches reports whether the lower-case text names one of ports:
+// exactly its number ("22") or number and protocol ("22/tcp"), or part of
+// its service name ("ssh"). Numbers must match exactly so that "22" does
+// not find port 8022.
+func portMatches(ports []scanner.Port, text string) bool {
+	for _, p := range ports {
+		if text == strconv.Itoa(int(p.Number)) || text == p.String() ||
+			(p.Service != "" && strings.Contains(strings.ToLower(p.Service), text)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"nls/internal/scanner"
+)
+
+func subject(ip, mac, vendor, hostname string) Subject {
+	s := Subject{HostInfo: scanner.HostInfo{IP: netip.MustParseAddr(ip), Vendor: vendor, Hostname: hostname}}
+	if mac != "" {
+		var err error
+		if s.MAC, err = net.ParseMAC(mac); err != nil {
+			panic(err)
+		}
+	}
+	return s
+}
+
+func TestParse_Match(t *testing.T) {
+	mac := subject("10.0.1.10", "AA:BB:CC:00:00:10", "Apple, Inc.", "macbook.lan")
+	db := subject("10.0.1.70", "00:11:22:33:44:70", "Dell", "db12.lan")
+	db.Ports = []scanner.Port{{Number: 5432, Protocol: "tcp", Service: "postgresql"}, {Number: 22, Protocol: "tcp", Service: "ssh"}}
+	db.Tags = []string{"prod"}
+	printer := subject("192.168.1.10", "", "", "")
+	printer.Label, printer.Alias, printer.Note = "Reception printer", "prn", "Toner ordered"
+	printer.Tags = []string{"iot", "printer"}
+	all := []Subject{mac, db, printer}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"10.0.1.10", "10.0.1.70", "192.168.1.10"}},
+		{"10", []string{"10.0.1.10", "10.0.1.70", "192.168.1.10"}},
+		{"ip:10.0.1.10", []string{"10.0.1.10"}},
+		{"ip:10.0.1.0/26", []string{"10.0.1.10"}},
+		{"ip:10.0.1", []string{"10.0.1.10", "10.0.1.70"}},
+		{"mac:aa-bb", []string{"10.0.1.10"}},
+		{"vendor:apple", []string{"10.0.1.10"}},
+		{"-vendor:apple", []string{"10.0.1.70", "192.168.1.10"}},
+		{`host:/^db\d+/`, []string{"10.0.1.70"}},
+		{"hostname:LAN", []string{"10.0.1.10", "10.0.1.70"}},
+		{"port:22", []string{"10.0.1.70"}},
+		{"port:/^54/", []string{"10.0.1.70"}},
+		{"port:2", nil},
+		{"port:543", nil},
+		{"postgres", []string{"10.0.1.70"}},
+		{"tag:iot", []string{"192.168.1.10"}},
+		{"tag:io", nil},
+		{"label:reception alias:prn note:toner", []string{"192.168.1.10"}},
+		{"printer", []string{"192.168.1.10"}},
+		{`"apple, inc"`, []string{"10.0.1.10"}},
+		{"vendor:apple OR vendor:dell", []string{"10.0.1.10", "10.0.1.70"}},
+		{"lan AND -tag:prod", []string{"10.0.1.10"}},
+		{"(vendor:apple OR tag:prod) port:ssh", []string{"10.0.1.70"}},
+		{"vendor:apple OR tag:prod port:ssh", []string{"10.0.1.10", "10.0.1.70"}},
+		{"-(vendor:apple OR vendor:dell)", []string{"192.168.1.10"}},
+		{"vendor:/^$/", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var got []string
+			for _, s := range all {
+				if q.Match(s) {
+					got = append(got, s.IPString())
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("matched %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_MACAndIPv6WithoutField(t *testing.T) {
+	s := subject("fd00::5", "AA:BB:CC:DD:EE:FF", "", "")
+	for _, query := range []string{"aa:bb:cc", "fd00::5", "ip:fd00::/64"} {
+		if q, err := Parse(query); err != nil || !q.Match(s) {
+			t.Errorf("Parse(%q) = %v; want a query matching the host", query, err)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		query, wantErr string
+	}{
+		{"vendor:", "vendor: needs a value"},
+		{"ip:10.0.1.0/33", `invalid network "10.0.1.0/33"`},
+		{"host:/db(/", "invalid regular expression"},
+		{"host:/db", "missing closing /"},
+		{`"apple`, `missing closing "`},
+		{"(apple OR dell", "missing closing )"},
+		{"apple)", `unexpected ")"`},
+		{"apple OR", `expected a search term after "OR"`},
+		{"OR apple", `unexpected "OR"`},
+		{"()", `unexpected ")"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuery_Highlights(t *testing.T) {
+	tests := []struct {
+		query string
+		field Field
+		text  string
+		want  []string
+	}{
+		{"apple", FieldVendor, "Apple, Inc. (apple)", []string{"Apple", "apple"}},
+		{"vendor:apple", FieldHost, "apple.lan", nil},
+		{"-vendor:apple", FieldVendor, "Apple", nil},
+		{"ip:10.0.1.0/24", FieldIP, "10.0.1.7", []string{"10.0.1.7"}},
+		{"ip:10.0.1.0/24", FieldIP, "10.0.2.7", nil},
+		{"port:22", FieldPort, "22,8022", []string{"22"}},
+		{"22", FieldPort, "80,22", []string{"22"}},
+		{`host:/db\d+/`, FieldHost, "db12.lan", []string{"db12"}},
+		{"tag:iot", FieldTag, "iot2 iot", []string{"iot"}},
+		{"db OR db1 lan", FieldHost, "db12.lan", []string{"db1", "lan"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range q.Highlights(tt.field, tt.text) {
+				got = append(got, tt.text[s.Start:s.End])
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Highlights(%s, %q) = %q; want %q", tt.field, tt.text, got, tt.want)
+			}
+		})
+	}
+}
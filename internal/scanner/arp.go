@@ -0,0 +1,330 @@
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"nls/internal/progress"
+)
+
+// ARP frame layout constants.
+const (
+	etherTypeARP   = 0x0806
+	etherHeaderLen = 14
+	arpPacketLen   = 28
+	arpFrameLen    = etherHeaderLen + arpPacketLen
+	minFrameLen    = 60
+	arpOpRequest   = 1
+	arpOpReply     = 2
+)
+
+// Default ARP sweep timing.
+const (
+	DefaultARPSendInterval = time.Millisecond
+	DefaultARPReplyWait    = 2 * time.Second
+)
+
+// packetConn abstracts raw layer-2 frame I/O on a single interface so the
+// ARP sweep can be exercised without a real NIC.
+type packetConn interface {
+	// ReadFrame reads one Ethernet frame into b. It must return an error
+	// once the connection is closed.
+	ReadFrame(b []byte) (int, error)
+
+	// WriteFrame transmits one Ethernet frame.
+	WriteFrame(b []byte) error
+
+	// Close releases the connection and unblocks pending reads.
+	Close() error
+}
+
+// arpInterface is a local interface the sweep can send ARP requests from.
+type arpInterface struct {
+	Index int
+	Name  string
+	MAC   net.HardwareAddr
+	Addr  netip.Prefix
+}
+
+// ARPScanner implements the Scanner interface with a native layer-2 ARP
+// sweep. It needs no external tools but requires raw socket privileges
+// (root or CAP_NET_RAW) and only works for directly attached IPv4 networks.
+type ARPScanner struct {
+	progress     progress.Reporter
+	sendInterval time.Duration
+	replyWait    time.Duration
+
+	interfaces func() ([]arpInterface, error)
+	open       func(arpInterface) (packetConn, error)
+	lookupHost func(context.Context, string) ([]netip.Addr, error)
+	lookupAddr func(context.Context, string) ([]string, error)
+	vendors    func() ouiTable
+}
+
+// NewARPScanner creates a new ARPScanner with the provided progress reporter.
+// If progress reporter is nil, a no-op reporter is used.
+func NewARPScanner(p progress.Reporter) *ARPScanner {
+	if p == nil {
+		p = progress.NoOp{}
+	}
+	return &ARPScanner{
+		progress:     p,
+		sendInterval: DefaultARPSendInterval,
+		replyWait:    DefaultARPReplyWait,
+		interfaces:   systemARPInterfaces,
+		open:         openPacketConn,
+		lookupHost:   defaultLookupHost,
+		lookupAddr:   defaultLookupAddr,
+		vendors:      systemOUI,
+	}
+}
+
+// WithOUIFile resolves vendors from the database at path instead of the
+// system's nmap or arp-scan files. Both of their formats are accepted, as
+// is the IEEE's oui.txt. Returns an error if the file cannot be read or
+// lists no vendor.
+func (s *ARPScanner) WithOUIFile(path string) (*ARPScanner, error) {
+	table, err := loadOUI(path)
+	if err != nil {
+		return nil, err
+	}
+	s.vendors = func() ouiTable { return table }
+	return s, nil
+}
+
+// Scan sends an ARP request to every address in targets and returns the
+// hosts that replied, sorted by IP. The scan respects the provided context
+// for cancellation.
+//
+// Every target must lie on a directly attached IPv4 network; targets on
+// different interfaces are swept together. Vendors are resolved from the
+// file given to WithOUIFile, or else from the system's nmap or arp-scan OUI
+// database when present.
+//
+// Returns an error if no suitable interface exists or packet I/O fails.
+func (s *ARPScanner) Scan(ctx context.Context, targets Targets) ([]HostInfo, error) {
+	return collectHosts(ctx, targets, s.ScanStream)
+}
+
+// arpLink is an open packet connection on one local interface.
+type arpLink struct {
+	ifi  arpInterface
+	conn packetConn
+}
+
+// ScanStream performs the same sweep as Scan, delivering each host through
+// found as soon as its reply arrives and its hostname has been resolved.
+func (s *ARPScanner) ScanStream(ctx context.Context, targets Targets, found func(HostInfo)) error {
+	s.progress.Start("Scanning network (ARP)...")
+	defer s.progress.Finish()
+
+	addrs, err := expandTargets(ctx, targets, s.lookupHost)
+	if err != nil {
+		return err
+	}
+
+	ifaces, err := s.interfaces()
+	if err != nil {
+		return fmt.Errorf("list interfaces: %w", err)
+	}
+	own := make(map[netip.Addr]bool, len(ifaces))
+	for _, ifi := range ifaces {
+		own[ifi.Addr.Addr()] = true
+	}
+
+	// Route each address to the interface it is attached to, never
+	// ARPing for one of our own addresses.
+	var (
+		links  = make(map[string]*arpLink)
+		route  = make(map[netip.Addr]*arpLink, len(addrs))
+		probes = make([]netip.Addr, 0, len(addrs))
+	)
+	for _, addr := range addrs {
+		if !addr.Is4() {
+			return fmt.Errorf("ARP scan requires IPv4 targets, got %s", addr)
+		}
+		if own[addr] {
+			continue
+		}
+		ifi, err := findInterface(ifaces, addr)
+		if err != nil {
+			return err
+		}
+		link, ok := links[ifi.Name]
+		if !ok {
+			link = &arpLink{ifi: ifi}
+			links[ifi.Name] = link
+		}
+		route[addr] = link
+		probes = append(probes, addr)
+	}
+
+	for _, link := range links {
+		if link.conn, err = s.open(link.ifi); err != nil {
+			for _, opened := range links {
+				if opened.conn != nil {
+					_ = opened.conn.Close()
+				}
+			}
+			return fmt.Errorf("open %s: %w", link.ifi.Name, err)
+		}
+	}
+
+	var (
+		vendors = s.vendors()
+		emitter = newHostEmitter(ctx, s.lookupAddr, found)
+		sent    = newSendTimes()
+		mu      sync.Mutex
+		seen    = make(map[netip.Addr]bool)
+		readErr = make(chan error, len(links))
+		closing = make(chan struct{})
+	)
+	for _, link := range links {
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				n, err := link.conn.ReadFrame(buf)
+				if err != nil {
+					select {
+					case <-closing:
+						readErr <- nil
+					default:
+						readErr <- err
+					}
+					return
+				}
+				ip, mac, ok := parseARPReply(buf[:n])
+				if !ok || route[ip] == nil {
+					continue
+				}
+				mu.Lock()
+				dup := seen[ip]
+				seen[ip] = true
+				mu.Unlock()
+				if dup {
+					continue
+				}
+				emitter.emit(HostInfo{
+					IP:     ip,
+					MAC:    mac,
+					Vendor: vendors.Vendor(mac),
+					RTT:    sent.since(ip),
+					Reason: ReasonARPResponse,
+				})
+			}
+		}()
+	}
+
+	frame := make([]byte, minFrameLen)
+	sweepErr := runSweep(ctx, s.progress, probes, s.sendInterval, s.replyWait, func(_ int, addr netip.Addr) error {
+		link := route[addr]
+		encodeARPRequest(frame, link.ifi.MAC, link.ifi.Addr.Addr(), addr)
+		sent.mark(addr)
+		if err := link.conn.WriteFrame(frame); err != nil {
+			return fmt.Errorf("send ARP request to %s: %w", addr, err)
+		}
+		return nil
+	})
+	close(closing)
+	for _, link := range links {
+		_ = link.conn.Close()
+	}
+	for range links {
+		if err := <-readErr; err != nil && sweepErr == nil {
+			sweepErr = fmt.Errorf("read ARP reply: %w", err)
+		}
+	}
+	emitter.wait()
+	return sweepErr
+}
+
+// findInterface returns the interface whose IPv4 network contains addr.
+func findInterface(ifaces []arpInterface, addr netip.Addr) (arpInterface, error) {
+	for _, ifi := range ifaces {
+		if ifi.Addr.Contains(addr) {
+			return ifi, nil
+		}
+	}
+	return arpInterface{}, fmt.Errorf("no local interface is attached to %s", addr)
+}
+
+// systemARPInterfaces lists the up, non-loopback interfaces with an
+// Ethernet address and their IPv4 networks.
+func systemARPInterfaces() ([]arpInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []arpInterface
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 || len(ifi.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(ipNet.String())
+			if err != nil || !prefix.Addr().Is4() {
+				continue
+			}
+			result = append(result, arpInterface{
+				Index: ifi.Index,
+				Name:  ifi.Name,
+				MAC:   ifi.HardwareAddr,
+				Addr:  prefix,
+			})
+		}
+	}
+	return result, nil
+}
+
+// encodeARPRequest writes a broadcast ARP "who-has target" frame into
+// frame, which must be at least arpFrameLen bytes long.
+func encodeARPRequest(frame []byte, srcMAC net.HardwareAddr, srcIP, target netip.Addr) {
+	clear(frame)
+
+	// Ethernet header
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+
+	// ARP payload
+	arp := frame[etherHeaderLen:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)      // hardware type: Ethernet
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800) // protocol type: IPv4
+	arp[4] = 6
+	arp[5] = 4
+	binary.BigEndian.PutUint16(arp[6:8], arpOpRequest)
+	copy(arp[8:14], srcMAC)
+	src4 := srcIP.As4()
+	copy(arp[14:18], src4[:])
+	dst4 := target.As4()
+	copy(arp[24:28], dst4[:])
+}
+
+// parseARPReply extracts the sender IP and MAC from an Ethernet ARP reply.
+func parseARPReply(frame []byte) (netip.Addr, net.HardwareAddr, bool) {
+	if len(frame) < arpFrameLen || binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		return netip.Addr{}, nil, false
+	}
+	arp := frame[etherHeaderLen:]
+	if arp[4] != 6 || arp[5] != 4 || binary.BigEndian.Uint16(arp[6:8]) != arpOpReply {
+		return netip.Addr{}, nil, false
+	}
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, arp[8:14])
+	ip := netip.AddrFrom4([4]byte(arp[14:18]))
+	return ip, mac, true
+}
@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// packetFile is a packetConn backed by an AF_PACKET socket bound to one
+// interface. The socket is non-blocking so reads go through the runtime
+// poller and are interrupted by Close.
+type packetFile struct {
+	f *os.File
+}
+
+// openPacketConn opens a raw AF_PACKET socket that receives ARP frames
+// on the given interface. Requires root or CAP_NET_RAW.
+func openPacketConn(ifi arpInterface) (packetConn, error) {
+	proto := htons(unix.ETH_P_ARP)
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, int(proto))
+	if err != nil {
+		return nil, fmt.Errorf("open packet socket: %w", err)
+	}
+
+	addr := &unix.SockaddrLinklayer{Protocol: proto, Ifindex: ifi.Index}
+	if err := unix.Bind(fd, addr); err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("bind packet socket: %w", err)
+	}
+
+	return &packetFile{f: os.NewFile(uintptr(fd), "arp:"+ifi.Name)}, nil
+}
+
+// ReadFrame reads one Ethernet frame.
+func (p *packetFile) ReadFrame(b []byte) (int, error) {
+	return p.f.Read(b)
+}
+
+// WriteFrame transmits one Ethernet frame.
+func (p *packetFile) WriteFrame(b []byte) error {
+	_, err := p.f.Write(b)
+	return err
+}
+
+// Close closes the socket.
+func (p *packetFile) Close() error {
+	return p.f.Close()
+}
+
+// htons converts a short from host to network byte order.
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"errors"
+	"runtime"
+)
+
+// openPacketConn is only implemented on Linux, where AF_PACKET sockets
+// are available.
+func openPacketConn(arpInterface) (packetConn, error) {
+	return nil, errors.New("ARP scanning is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,295 @@
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePacketConn answers ARP requests for a fixed set of hosts.
+type fakePacketConn struct {
+	hosts map[netip.Addr]net.HardwareAddr
+
+	mu       sync.Mutex
+	requests []netip.Addr
+	frames   chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newFakePacketConn(hosts map[netip.Addr]net.HardwareAddr) *fakePacketConn {
+	return &fakePacketConn{
+		hosts:  hosts,
+		frames: make(chan []byte, 1024),
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *fakePacketConn) ReadFrame(b []byte) (int, error) {
+	select {
+	case frame := <-c.frames:
+		return copy(b, frame), nil
+	case <-c.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *fakePacketConn) WriteFrame(b []byte) error {
+	target := netip.AddrFrom4([4]byte(b[etherHeaderLen+24 : etherHeaderLen+28]))
+	c.mu.Lock()
+	c.requests = append(c.requests, target)
+	c.mu.Unlock()
+
+	if mac, ok := c.hosts[target]; ok {
+		c.frames <- arpReplyFrame(target, mac)
+	}
+	return nil
+}
+
+func (c *fakePacketConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// arpReplyFrame builds an Ethernet ARP reply from ip/mac.
+func arpReplyFrame(ip netip.Addr, mac net.HardwareAddr) []byte {
+	frame := make([]byte, minFrameLen)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeARP)
+	arp := frame[etherHeaderLen:]
+	binary.BigEndian.PutUint16(arp[0:2], 1)
+	binary.BigEndian.PutUint16(arp[2:4], 0x0800)
+	arp[4] = 6
+	arp[5] = 4
+	binary.BigEndian.PutUint16(arp[6:8], arpOpReply)
+	copy(arp[8:14], mac)
+	ip4 := ip.As4()
+	copy(arp[14:18], ip4[:])
+	return frame
+}
+
+func newTestARPScanner(conn packetConn) *ARPScanner {
+	s := NewARPScanner(nil)
+	s.sendInterval = 0
+	s.replyWait = 10 * time.Millisecond
+	s.interfaces = func() ([]arpInterface, error) {
+		return []arpInterface{{
+			Index: 2,
+			Name:  "eth0",
+			MAC:   net.HardwareAddr{0x02, 0, 0, 0, 0, 1},
+			Addr:  netip.MustParsePrefix("192.168.1.5/24"),
+		}}, nil
+	}
+	s.open = func(arpInterface) (packetConn, error) { return conn, nil }
+	s.lookupHost = fakeLookupHost(map[string][]string{"router.local": {"192.168.1.1"}})
+	s.lookupAddr = func(_ context.Context, addr string) ([]string, error) {
+		if addr == "192.168.1.1" {
+			return []string{"router.local."}, nil
+		}
+		return nil, errors.New("not found")
+	}
+	s.vendors = func() ouiTable { return ouiTable{"001122": "Router Co"} }
+	return s
+}
+
+func TestARPScanner_Scan(t *testing.T) {
+	conn := newFakePacketConn(map[netip.Addr]net.HardwareAddr{
+		netip.MustParseAddr("192.168.1.20"): {0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		netip.MustParseAddr("192.168.1.1"):  {0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+	})
+	s := newTestARPScanner(conn)
+
+	got, err := s.Scan(context.Background(), NewTargets("192.168.1.0/24"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("00:11:22:33:44:55"), Vendor: "Router Co", Hostname: "router.local", Addresses: parseAddrs("192.168.1.1"), Hostnames: []string{"router.local"}, Reason: ReasonARPResponse},
+		{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Addresses: parseAddrs("192.168.1.20"), Reason: ReasonARPResponse},
+	}
+	clearRTTs(t, got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	// 254 host addresses minus the scanner's own address.
+	if len(conn.requests) != 253 {
+		t.Errorf("sent %d requests; want 253", len(conn.requests))
+	}
+	for _, r := range conn.requests {
+		if r == netip.MustParseAddr("192.168.1.5") {
+			t.Error("scanner should not ARP for its own address")
+		}
+	}
+}
+
+func TestARPScanner_Scan_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr string
+	}{
+		{name: "invalid target", target: "not a cidr", wantErr: "parse target"},
+		{name: "unresolvable hostname", target: "ghost.local", wantErr: "resolve target"},
+		{name: "IPv6 target", target: "fd00::/120", wantErr: "IPv4"},
+		{name: "no attached interface", target: "10.0.0.0/24", wantErr: "no local interface"},
+		{name: "too large", target: "10.0.0.0/8", wantErr: "too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestARPScanner(newFakePacketConn(nil))
+			_, err := s.Scan(context.Background(), NewTargets(tt.target))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Scan() error = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestARPScanner_Scan_MultipleInterfaces(t *testing.T) {
+	conns := map[string]*fakePacketConn{
+		"eth0": newFakePacketConn(map[netip.Addr]net.HardwareAddr{
+			netip.MustParseAddr("192.168.1.1"): {0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		}),
+		"vlan20": newFakePacketConn(map[netip.Addr]net.HardwareAddr{
+			netip.MustParseAddr("10.20.0.9"): {0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		}),
+	}
+	s := newTestARPScanner(nil)
+	s.interfaces = func() ([]arpInterface, error) {
+		return []arpInterface{
+			{Index: 2, Name: "eth0", MAC: net.HardwareAddr{0x02, 0, 0, 0, 0, 1}, Addr: netip.MustParsePrefix("192.168.1.5/24")},
+			{Index: 3, Name: "vlan20", MAC: net.HardwareAddr{0x02, 0, 0, 0, 0, 2}, Addr: netip.MustParsePrefix("10.20.0.1/24")},
+		}, nil
+	}
+	s.open = func(ifi arpInterface) (packetConn, error) { return conns[ifi.Name], nil }
+
+	targets := Targets{
+		Include: []string{"router.local", "10.20.0.1-20"},
+		Exclude: []string{"10.20.0.10-20"},
+	}
+	got, err := s.Scan(context.Background(), targets)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	var ips []string
+	for _, h := range got {
+		ips = append(ips, h.IP.String())
+	}
+	if want := []string{"10.20.0.9", "192.168.1.1"}; !reflect.DeepEqual(ips, want) {
+		t.Errorf("Scan() found %v; want %v", ips, want)
+	}
+
+	// vlan20 probes 10.20.0.2-9: its own address and the exclusions are skipped.
+	if n := len(conns["vlan20"].requests); n != 8 {
+		t.Errorf("vlan20 sent %d requests; want 8", n)
+	}
+	if n := len(conns["eth0"].requests); n != 1 {
+		t.Errorf("eth0 sent %d requests; want 1", n)
+	}
+}
+
+func TestARPScanner_Scan_ContextCancelled(t *testing.T) {
+	conn := newFakePacketConn(nil)
+	s := newTestARPScanner(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.Scan(ctx, NewTargets("192.168.1.0/24"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Scan() error = %v; want context.Canceled", err)
+	}
+	if len(conn.requests) != 0 {
+		t.Errorf("sent %d requests after cancellation; want 0", len(conn.requests))
+	}
+}
+
+func TestEncodeARPRequest_RoundTrip(t *testing.T) {
+	frame := make([]byte, minFrameLen)
+	src := net.HardwareAddr{0x02, 0, 0, 0, 0, 1}
+	encodeARPRequest(frame, src, netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.9"))
+
+	if binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		t.Fatal("frame is not an ARP frame")
+	}
+	if op := binary.BigEndian.Uint16(frame[etherHeaderLen+6 : etherHeaderLen+8]); op != arpOpRequest {
+		t.Errorf("op = %d; want %d", op, arpOpRequest)
+	}
+
+	// A request is not a reply.
+	if _, _, ok := parseARPReply(frame); ok {
+		t.Error("parseARPReply accepted a request frame")
+	}
+
+	ip, mac, ok := parseARPReply(arpReplyFrame(netip.MustParseAddr("10.0.0.9"), src))
+	if !ok || ip != netip.MustParseAddr("10.0.0.9") || mac.String() != src.String() {
+		t.Errorf("parseARPReply() = %v, %v, %v", ip, mac, ok)
+	}
+}
+
+func TestParseOUI(t *testing.T) {
+	input := "# comment\n001122 Router Co\naabbcc\tTab Vendor Inc\nbad line\n" +
+		"DC-A6-32   (hex)\t\tRaspberry Pi Trading Ltd\nDCA632     (base 16)\t\tRaspberry Pi Trading Ltd\n"
+	table := parseOUI(strings.NewReader(input))
+
+	tests := []struct {
+		mac  net.HardwareAddr
+		want string
+	}{
+		{mac: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, want: "Router Co"},
+		{mac: net.HardwareAddr{0xaa, 0xbb, 0xcc, 0x00, 0x00, 0x01}, want: "Tab Vendor Inc"},
+		{mac: net.HardwareAddr{0xdc, 0xa6, 0x32, 0x00, 0x00, 0x01}, want: "Raspberry Pi Trading Ltd"},
+		{mac: net.HardwareAddr{0x12, 0x34, 0x56, 0x00, 0x00, 0x01}, want: ""},
+		{mac: nil, want: ""},
+	}
+	for _, tt := range tests {
+		if got := table.Vendor(tt.mac); got != tt.want {
+			t.Errorf("Vendor(%v) = %q; want %q", tt.mac, got, tt.want)
+		}
+	}
+}
+
+func TestARPScanner_WithOUIFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "oui.txt")
+	if err := os.WriteFile(path, []byte("AA-BB-CC   (hex)\t\tGadget Corp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing here\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := newFakePacketConn(map[netip.Addr]net.HardwareAddr{
+		netip.MustParseAddr("192.168.1.20"): {0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+	})
+	s, err := newTestARPScanner(conn).WithOUIFile(path)
+	if err != nil {
+		t.Fatalf("WithOUIFile() error = %v", err)
+	}
+	got, err := s.Scan(context.Background(), NewTargets("192.168.1.20"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Vendor != "Gadget Corp" {
+		t.Errorf("Scan() = %+v; want one host from Gadget Corp", got)
+	}
+
+	for _, bad := range []string{filepath.Join(dir, "missing.txt"), empty} {
+		if _, err := NewARPScanner(nil).WithOUIFile(bad); err == nil {
+			t.Errorf("WithOUIFile(%q) should fail", bad)
+		}
+	}
+}
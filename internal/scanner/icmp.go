@@ -0,0 +1,400 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"nls/internal/netif"
+	"nls/internal/progress"
+)
+
+// Default ICMP sweep timing.
+const (
+	DefaultICMPSendInterval = time.Millisecond
+	DefaultICMPReplyWait    = 2 * time.Second
+)
+
+// IANA protocol numbers used to parse ICMP messages.
+const (
+	icmpProtocolIPv4 = 1
+	icmpProtocolIPv6 = 58
+)
+
+// allNodes is the link-local all-nodes multicast group. An echo request
+// sent to it is answered by every IPv6 host on the link, which is how
+// hosts on networks too large to sweep are discovered.
+var allNodes = netip.MustParseAddr("ff02::1")
+
+// echoConn abstracts ICMP echo I/O so the sweep can be exercised without
+// opening real sockets.
+type echoConn interface {
+	// SendEcho transmits an echo request with the given sequence number to dst.
+	SendEcho(dst netip.Addr, seq int) error
+
+	// ReadReply blocks until an echo reply for this sweep arrives and
+	// returns its source address, zoned for link-local sources. It must
+	// return an error once the connection is closed.
+	ReadReply() (netip.Addr, error)
+
+	// Close releases the connection and unblocks pending reads.
+	Close() error
+}
+
+// ICMPScanner implements the Scanner interface with a native ICMP echo
+// sweep over IPv4 and IPv6. It prefers unprivileged ICMP datagram sockets,
+// which Linux allows for groups listed in net.ipv4.ping_group_range and
+// macOS allows for all users, and falls back to raw sockets (root or
+// CAP_NET_RAW) otherwise.
+//
+// IPv6 networks too large to sweep (up to a /64, such as fe80::/64) are
+// discovered by pinging the all-nodes multicast group on every attached
+// interface. Echo replies carry no link-layer data, so MAC and Vendor are
+// never set.
+type ICMPScanner struct {
+	progress     progress.Reporter
+	sendInterval time.Duration
+	replyWait    time.Duration
+
+	listen     func(ipv6 bool) (echoConn, error)
+	interfaces func() ([]netif.Interface, error)
+	lookupHost func(context.Context, string) ([]netip.Addr, error)
+	lookupAddr func(context.Context, string) ([]string, error)
+}
+
+// NewICMPScanner creates a new ICMPScanner with the provided progress reporter.
+// If progress reporter is nil, a no-op reporter is used.
+func NewICMPScanner(p progress.Reporter) *ICMPScanner {
+	if p == nil {
+		p = progress.NoOp{}
+	}
+	return &ICMPScanner{
+		progress:     p,
+		sendInterval: DefaultICMPSendInterval,
+		replyWait:    DefaultICMPReplyWait,
+		listen:       listenEcho,
+		interfaces:   netif.System{}.Interfaces,
+		lookupHost:   defaultLookupHost,
+		lookupAddr:   defaultLookupAddr,
+	}
+}
+
+// Scan sends an ICMP echo request to every address in targets and
+// returns the hosts that replied, sorted by IP. The scan respects the
+// provided context for cancellation.
+//
+// Returns an error if no ICMP socket can be opened or packet I/O fails.
+func (s *ICMPScanner) Scan(ctx context.Context, targets Targets) ([]HostInfo, error) {
+	return collectHosts(ctx, targets, s.ScanStream)
+}
+
+// ScanStream performs the same sweep as Scan, delivering each host through
+// found as soon as its reply arrives and its hostname has been resolved.
+func (s *ICMPScanner) ScanStream(ctx context.Context, targets Targets, found func(HostInfo)) error {
+	s.progress.Start("Scanning network (ICMP)...")
+	defer s.progress.Finish()
+
+	if err := targets.Validate(); err != nil {
+		return err
+	}
+	unicast, multicast := splitMulticast(targets)
+
+	var (
+		addrs []netip.Addr
+		err   error
+	)
+	if len(unicast.Include) > 0 {
+		if addrs, err = expandTargets(ctx, unicast, s.lookupHost); err != nil {
+			return err
+		}
+	}
+	excluded, err := newExcluder(ctx, targets.Exclude, s.lookupHost)
+	if err != nil {
+		return err
+	}
+
+	var (
+		groups []netip.Addr
+		own    = make(map[netip.Addr]bool)
+	)
+	if len(multicast) > 0 {
+		ifaces, err := s.interfaces()
+		if err != nil {
+			return fmt.Errorf("list interfaces: %w", err)
+		}
+		if groups, err = multicastGroups(ifaces, multicast); err != nil {
+			return err
+		}
+		for _, ifi := range ifaces {
+			for _, a := range ifi.Addrs {
+				own[a.Addr()] = true
+			}
+		}
+	}
+
+	// Replies are accepted from probed addresses and, for multicast
+	// discovery, from any non-excluded address on the target network.
+	wanted := make(map[netip.Addr]bool, len(addrs))
+	for _, addr := range addrs {
+		wanted[addr] = true
+	}
+	accept := func(src netip.Addr) bool {
+		if wanted[src] {
+			return true
+		}
+		bare := src.WithZone("")
+		if own[bare] || excluded(src) {
+			return false
+		}
+		for _, network := range multicast {
+			if network.Contains(bare) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var conn4, conn6 echoConn
+	probes := append(addrs, groups...)
+	for _, addr := range probes {
+		switch {
+		case addr.Is4() && conn4 == nil:
+			conn4, err = s.listen(false)
+		case addr.Is6() && conn6 == nil:
+			conn6, err = s.listen(true)
+		}
+		if err != nil {
+			for _, c := range []echoConn{conn4, conn6} {
+				if c != nil {
+					_ = c.Close()
+				}
+			}
+			return err
+		}
+	}
+	conns := make([]echoConn, 0, 2)
+	for _, c := range []echoConn{conn4, conn6} {
+		if c != nil {
+			conns = append(conns, c)
+		}
+	}
+
+	var (
+		emitter = newHostEmitter(ctx, s.lookupAddr, found)
+		sent    = newSendTimes()
+		mu      sync.Mutex
+		seen    = make(map[netip.Addr]bool)
+		readErr = make(chan error, len(conns))
+		closing = make(chan struct{})
+	)
+	for _, conn := range conns {
+		go func() {
+			for {
+				src, err := conn.ReadReply()
+				if err != nil {
+					select {
+					case <-closing:
+						readErr <- nil
+					default:
+						readErr <- err
+					}
+					return
+				}
+				if !accept(src) {
+					continue
+				}
+				mu.Lock()
+				dup := seen[src]
+				seen[src] = true
+				mu.Unlock()
+				if dup {
+					continue
+				}
+				rtt := sent.since(src)
+				if rtt == 0 && src.Zone() != "" {
+					// A reply to the all-nodes group of that link
+					rtt = sent.since(allNodes.WithZone(src.Zone()))
+				}
+				emitter.emit(HostInfo{
+					IP:     src,
+					RTT:    rtt,
+					Reason: ReasonEchoReply,
+				})
+			}
+		}()
+	}
+
+	sweepErr := runSweep(ctx, s.progress, probes, s.sendInterval, s.replyWait, func(seq int, addr netip.Addr) error {
+		conn := conn6
+		if addr.Is4() {
+			conn = conn4
+		}
+		sent.mark(addr)
+		if err := conn.SendEcho(addr, seq); err != nil {
+			return fmt.Errorf("send echo request to %s: %w", addr, err)
+		}
+		return nil
+	})
+	close(closing)
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	for range conns {
+		if err := <-readErr; err != nil && sweepErr == nil {
+			sweepErr = fmt.Errorf("read echo reply: %w", err)
+		}
+	}
+	emitter.wait()
+	return sweepErr
+}
+
+// multicastGroups returns the zoned all-nodes address of every interface
+// attached to one of networks. Link-local networks are attached to every
+// up interface with a link-local address; other networks to interfaces
+// with an address inside them.
+func multicastGroups(ifaces []netif.Interface, networks []netip.Prefix) ([]netip.Addr, error) {
+	var (
+		groups []netip.Addr
+		seen   = make(map[string]bool)
+	)
+	for _, network := range networks {
+		attached := false
+		for _, ifi := range ifaces {
+			if !ifi.Up || ifi.Loopback {
+				continue
+			}
+			for _, a := range ifi.Addrs {
+				onLink := network.Contains(a.Addr())
+				if network.Addr().IsLinkLocalUnicast() {
+					onLink = a.Addr().Is6() && a.Addr().IsLinkLocalUnicast()
+				}
+				if !onLink {
+					continue
+				}
+				attached = true
+				if !seen[ifi.Name] {
+					seen[ifi.Name] = true
+					groups = append(groups, allNodes.WithZone(ifi.Name))
+				}
+				break
+			}
+		}
+		if !attached {
+			return nil, fmt.Errorf("no local interface is attached to %s", network)
+		}
+	}
+	return groups, nil
+}
+
+// icmpEchoConn is an echoConn backed by an ICMP or ICMPv6 socket from x/net/icmp.
+type icmpEchoConn struct {
+	conn       *icmp.PacketConn
+	privileged bool
+	ipv6       bool
+	id         int
+	buf        []byte
+}
+
+// listenEcho opens an unprivileged ICMP datagram socket for the address
+// family, falling back to a raw ICMP socket when the kernel does not
+// permit the former.
+func listenEcho(ipv6 bool) (echoConn, error) {
+	id := os.Getpid() & 0xffff
+	network, rawNetwork, addr := "udp4", "ip4:icmp", "0.0.0.0"
+	if ipv6 {
+		network, rawNetwork, addr = "udp6", "ip6:ipv6-icmp", "::"
+	}
+
+	conn, err := icmp.ListenPacket(network, addr)
+	if err == nil {
+		return &icmpEchoConn{conn: conn, ipv6: ipv6, id: id, buf: make([]byte, 1500)}, nil
+	}
+
+	raw, rawErr := icmp.ListenPacket(rawNetwork, addr)
+	if rawErr != nil {
+		return nil, fmt.Errorf("open ICMP socket (unprivileged: %v): %w", err, rawErr)
+	}
+	return &icmpEchoConn{conn: raw, privileged: true, ipv6: ipv6, id: id, buf: make([]byte, 1500)}, nil
+}
+
+// SendEcho transmits an echo request to dst. The zone of a link-local or
+// multicast dst selects the outgoing interface.
+func (c *icmpEchoConn) SendEcho(dst netip.Addr, seq int) error {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if c.ipv6 {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	msg := icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{ID: c.id, Seq: seq & 0xffff, Data: []byte("nls")},
+	}
+	// The kernel computes the ICMPv6 checksum, so no pseudo-header is needed.
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	var addr net.Addr = &net.UDPAddr{IP: dst.AsSlice(), Zone: dst.Zone()}
+	if c.privileged {
+		addr = &net.IPAddr{IP: dst.AsSlice(), Zone: dst.Zone()}
+	}
+	_, err = c.conn.WriteTo(b, addr)
+	return err
+}
+
+// ReadReply returns the source of the next echo reply. Raw sockets see
+// every ICMP message on the host, so replies are matched by identifier;
+// datagram sockets are already filtered by the kernel.
+func (c *icmpEchoConn) ReadReply() (netip.Addr, error) {
+	proto, replyType := icmpProtocolIPv4, icmp.Type(ipv4.ICMPTypeEchoReply)
+	if c.ipv6 {
+		proto, replyType = icmpProtocolIPv6, ipv6.ICMPTypeEchoReply
+	}
+
+	for {
+		n, peer, err := c.conn.ReadFrom(c.buf)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+
+		msg, err := icmp.ParseMessage(proto, c.buf[:n])
+		if err != nil || msg.Type != replyType {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || (c.privileged && echo.ID != c.id) {
+			continue
+		}
+
+		var (
+			ip   net.IP
+			zone string
+		)
+		switch a := peer.(type) {
+		case *net.UDPAddr:
+			ip, zone = a.IP, a.Zone
+		case *net.IPAddr:
+			ip, zone = a.IP, a.Zone
+		}
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			addr = addr.Unmap()
+			if zone != "" && addr.Is6() && addr.IsLinkLocalUnicast() {
+				addr = addr.WithZone(zone)
+			}
+			return addr, nil
+		}
+	}
+}
+
+// Close closes the socket.
+func (c *icmpEchoConn) Close() error {
+	return c.conn.Close()
+}
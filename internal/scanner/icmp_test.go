@@ -0,0 +1,218 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nls/internal/netif"
+)
+
+// fakeEchoConn answers echo requests for a fixed set of hosts. Requests to
+// the all-nodes group are answered by the members of the zone's link.
+type fakeEchoConn struct {
+	alive   map[netip.Addr]bool
+	members map[string][]netip.Addr
+
+	mu      sync.Mutex
+	sent    int
+	replies chan netip.Addr
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newFakeEchoConn(alive ...string) *fakeEchoConn {
+	c := &fakeEchoConn{
+		alive:   make(map[netip.Addr]bool),
+		replies: make(chan netip.Addr, 1024),
+		closed:  make(chan struct{}),
+	}
+	for _, a := range alive {
+		c.alive[netip.MustParseAddr(a)] = true
+	}
+	return c
+}
+
+func (c *fakeEchoConn) SendEcho(dst netip.Addr, _ int) error {
+	c.mu.Lock()
+	c.sent++
+	c.mu.Unlock()
+	if dst.WithZone("") == allNodes {
+		for _, member := range c.members[dst.Zone()] {
+			c.replies <- member
+		}
+	}
+	if c.alive[dst] {
+		c.replies <- dst
+	}
+	return nil
+}
+
+func (c *fakeEchoConn) ReadReply() (netip.Addr, error) {
+	select {
+	case addr := <-c.replies:
+		return addr, nil
+	case <-c.closed:
+		return netip.Addr{}, net.ErrClosed
+	}
+}
+
+func (c *fakeEchoConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func newTestICMPScanner(conn echoConn) *ICMPScanner {
+	s := NewICMPScanner(nil)
+	s.sendInterval = 0
+	s.replyWait = 10 * time.Millisecond
+	s.listen = func(bool) (echoConn, error) { return conn, nil }
+	s.interfaces = func() ([]netif.Interface, error) {
+		return []netif.Interface{
+			{Name: "lo", Up: true, Loopback: true, Addrs: []netip.Prefix{netip.MustParsePrefix("::1/128")}},
+			{Name: "eth0", Up: true, Addrs: []netip.Prefix{
+				netip.MustParsePrefix("fe80::1/64"),
+				netip.MustParsePrefix("fd00::1/64"),
+			}},
+			{Name: "wlan0", Up: true, Addrs: []netip.Prefix{netip.MustParsePrefix("fe80::2/64")}},
+		}, nil
+	}
+	s.lookupAddr = func(_ context.Context, addr string) ([]string, error) {
+		if addr == "10.0.0.1" {
+			return []string{"gw.lan."}, nil
+		}
+		return nil, errors.New("not found")
+	}
+	return s
+}
+
+func TestICMPScanner_Scan(t *testing.T) {
+	conn := newFakeEchoConn("10.0.0.9", "10.0.0.1")
+	s := newTestICMPScanner(conn)
+
+	got, err := s.Scan(context.Background(), NewTargets("10.0.0.0/28"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []HostInfo{
+		{IP: netip.MustParseAddr("10.0.0.1"), Hostname: "gw.lan", Addresses: parseAddrs("10.0.0.1"), Hostnames: []string{"gw.lan"}, Reason: ReasonEchoReply},
+		{IP: netip.MustParseAddr("10.0.0.9"), Addresses: parseAddrs("10.0.0.9"), Reason: ReasonEchoReply},
+	}
+	clearRTTs(t, got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+	if conn.sent != 14 {
+		t.Errorf("sent %d echo requests; want 14", conn.sent)
+	}
+}
+
+func TestICMPScanner_Scan_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		listen  func(bool) (echoConn, error)
+		wantErr string
+	}{
+		{name: "invalid target", target: "10.0.0.0/99", wantErr: "parse target"},
+		{name: "IPv6 network too large", target: "fd00::/48", wantErr: "too large"},
+		{name: "IPv6 network not attached", target: "fd01::/64", wantErr: "no local interface"},
+		{
+			name:    "socket unavailable",
+			target:  "10.0.0.0/24",
+			listen:  func(bool) (echoConn, error) { return nil, errors.New("operation not permitted") },
+			wantErr: "operation not permitted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestICMPScanner(newFakeEchoConn())
+			if tt.listen != nil {
+				s.listen = tt.listen
+			}
+			_, err := s.Scan(context.Background(), NewTargets(tt.target))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Scan() error = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestICMPScanner_Scan_IPv6(t *testing.T) {
+	conn := newFakeEchoConn("fd00::10", "10.0.0.1")
+	conn.members = map[string][]netip.Addr{
+		// eth0 hears itself, a link-local-only device and a global address
+		"eth0": {
+			netip.MustParseAddr("fe80::1%eth0"),
+			netip.MustParseAddr("fe80::aa%eth0"),
+			netip.MustParseAddr("fd00::1:77"),
+		},
+		"wlan0": {netip.MustParseAddr("fe80::bb%wlan0")},
+	}
+	s := newTestICMPScanner(conn)
+
+	targets := Targets{
+		Include: []string{"fe80::/64", "fd00::/120", "10.0.0.1"},
+		Exclude: []string{"fe80::bb"},
+	}
+	got, err := s.Scan(context.Background(), targets)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	// Multicast replies are timed from the send to their link's group.
+	clearRTTs(t, got)
+
+	var ips []string
+	for _, h := range got {
+		ips = append(ips, h.IP.String())
+	}
+	// fd00::1:77 answered the multicast but lies outside fd00::/120.
+	want := []string{"10.0.0.1", "fd00::10", "fe80::aa%eth0"}
+	if !reflect.DeepEqual(ips, want) {
+		t.Errorf("Scan() found %v; want %v", ips, want)
+	}
+}
+
+func TestMulticastGroups(t *testing.T) {
+	ifaces := []netif.Interface{
+		{Name: "lo", Up: true, Loopback: true, Addrs: []netip.Prefix{netip.MustParsePrefix("fe80::1/64")}},
+		{Name: "eth0", Up: true, Addrs: []netip.Prefix{netip.MustParsePrefix("fe80::2/64"), netip.MustParsePrefix("2001:db8::2/64")}},
+		{Name: "eth1", Up: false, Addrs: []netip.Prefix{netip.MustParsePrefix("fe80::3/64")}},
+		{Name: "wlan0", Up: true, Addrs: []netip.Prefix{netip.MustParsePrefix("10.0.0.2/24"), netip.MustParsePrefix("fe80::4/64")}},
+	}
+
+	got, err := multicastGroups(ifaces, []netip.Prefix{netip.MustParsePrefix("fe80::/64"), netip.MustParsePrefix("2001:db8::/64")})
+	if err != nil {
+		t.Fatalf("multicastGroups() error = %v", err)
+	}
+	want := []netip.Addr{netip.MustParseAddr("ff02::1%eth0"), netip.MustParseAddr("ff02::1%wlan0")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("multicastGroups() = %v; want %v", got, want)
+	}
+
+	if _, err := multicastGroups(ifaces, []netip.Prefix{netip.MustParsePrefix("2001:db8:1::/64")}); err == nil {
+		t.Error("multicastGroups() should fail for a network no interface is attached to")
+	}
+}
+
+func TestICMPScanner_Scan_ContextCancelled(t *testing.T) {
+	conn := newFakeEchoConn("10.0.0.1")
+	s := newTestICMPScanner(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.Scan(ctx, NewTargets("10.0.0.0/24"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Scan() error = %v; want context.Canceled", err)
+	}
+}
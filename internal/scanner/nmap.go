@@ -2,8 +2,15 @@ package scanner
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Ullaakut/nmap/v3"
@@ -11,11 +18,15 @@ import (
 	"nls/internal/progress"
 )
 
+// statsInterval is how often nmap reports task progress in its XML output.
+const statsInterval = "1s"
+
 // NmapScanner implements the Scanner interface using nmap for network discovery.
 // It performs ping scans to detect active hosts and extract their information.
 type NmapScanner struct {
 	progress progress.Reporter
 	logger   *log.Logger
+	portScan PortScan
 }
 
 // NewNmapScanner creates a new NmapScanner with the provided progress reporter.
@@ -30,33 +41,134 @@ func NewNmapScanner(p progress.Reporter) *NmapScanner {
 	}
 }
 
-// Scan performs an nmap ping scan on the specified CIDR target and returns
+// WithPortScan makes the scanner follow discovery with a port scan of the
+// ports selected by ps, recording each host's open ports.
+func (s *NmapScanner) WithPortScan(ps PortScan) *NmapScanner {
+	s.portScan = ps
+	return s
+}
+
+// Scan performs an nmap ping scan of the specified targets and returns
 // a list of discovered hosts. The scan respects the provided context for
 // cancellation.
 //
+// Exclusions are passed to nmap's --exclude. IPv4 and IPv6 targets are
+// scanned in separate nmap runs, since nmap handles one family at a time.
+// With a port scan configured (see WithPortScan) nmap scans the selected
+// ports of each host it finds instead of only pinging it.
+//
 // The function displays progress feedback during the scan and extracts
 // IP addresses, MAC addresses, vendor information, and hostnames from the results.
 //
 // Returns an error if the scanner cannot be created or if the scan fails.
-func (s *NmapScanner) Scan(ctx context.Context, target string) ([]HostInfo, error) {
+func (s *NmapScanner) Scan(ctx context.Context, targets Targets) ([]HostInfo, error) {
+	return collectHosts(ctx, targets, s.ScanStream)
+}
+
+// ScanStream performs the same ping scan as Scan, delivering each host
+// through found as soon as nmap writes it to its XML output.
+func (s *NmapScanner) ScanStream(ctx context.Context, targets Targets, found func(HostInfo)) error {
 	s.progress.Start("Scanning network...")
+	defer s.progress.Finish()
+
+	if err := targets.Validate(); err != nil {
+		return err
+	}
+	v4, v6, err := splitFamilies(targets)
+	if err != nil {
+		return err
+	}
+	p := &scanProgress{reporter: s.progress, phases: 1}
+	if s.portScan.Top > 0 || len(s.portScan.Ports) > 0 {
+		p.phases = 2
+	}
+	for _, family := range []Targets{v4, v6} {
+		if len(family.Include) > 0 {
+			p.runs++
+		}
+	}
+	if len(v4.Include) > 0 {
+		if err := s.run(ctx, v4, false, p, found); err != nil {
+			return err
+		}
+		p.run++
+	}
+	if len(v6.Include) > 0 {
+		return s.run(ctx, v6, true, p, found)
+	}
+	return nil
+}
+
+// splitFamilies divides targets into IPv4 and IPv6 sets for separate nmap
+// runs. Ranges and hostnames go with IPv4, matching nmap's resolution;
+// excluded hostnames apply to both. IPv6 networks must be small enough
+// for nmap to sweep.
+func splitFamilies(targets Targets) (v4, v6 Targets, err error) {
+	for _, spec := range targets.Include {
+		ts, _ := parseTargetSpec(spec)
+		switch {
+		case ts.prefix.Addr().Is6():
+			if ts.prefix.Addr().BitLen()-ts.prefix.Bits() > maxSweepHostBits {
+				return Targets{}, Targets{}, fmt.Errorf("IPv6 network %s is too large for nmap to sweep: it can only be discovered by multicast (ICMP method)", spec)
+			}
+			v6.Include = append(v6.Include, spec)
+		case ts.first.Is6():
+			v6.Include = append(v6.Include, spec)
+		default:
+			v4.Include = append(v4.Include, spec)
+		}
+	}
+	for _, spec := range targets.Exclude {
+		ts, _ := parseTargetSpec(spec)
+		switch {
+		case ts.host != "":
+			v4.Exclude = append(v4.Exclude, spec)
+			v6.Exclude = append(v6.Exclude, spec)
+		case ts.prefix.Addr().Is6() || ts.first.Is6():
+			v6.Exclude = append(v6.Exclude, spec)
+		default:
+			v4.Exclude = append(v4.Exclude, spec)
+		}
+	}
+	return v4, v6, nil
+}
 
+// run performs one nmap ping scan of targets, which must all belong to
+// one address family, streaming hosts to found and its progress to p.
+func (s *NmapScanner) run(ctx context.Context, targets Targets, ipv6 bool, p *scanProgress, found func(HostInfo)) error {
 	// Buffered channels prevent goroutine leaks on context cancellation
 	resultCh := make(chan *nmap.Run, 1)
 	errCh := make(chan error, 1)
 
+	// nmap's XML output is decoded while it is being written so hosts can
+	// be delivered before the scan finishes.
+	pr, pw := io.Pipe()
+	streamed := make(map[netip.Addr]bool)
+	decodeDone := make(chan struct{})
 	go func() {
-		scanner, err := nmap.NewScanner(
-			ctx,
-			nmap.WithTargets(target),
-			nmap.WithPingScan(),
-		)
+		defer close(decodeDone)
+		if err := decodeStream(pr, func(h nmap.Host) {
+			for _, info := range extractHostInfo(&nmap.Run{Hosts: []nmap.Host{h}}) {
+				streamed[info.IP] = true
+				found(info)
+			}
+		}, p.report); err != nil {
+			s.logger.Printf("decode streamed nmap output: %v\n", err)
+		}
+		// Keep draining so nmap's output copy never blocks.
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	go func() {
+		defer pw.Close()
+
+		scanner, err := nmap.NewScanner(ctx, s.options(targets, ipv6)...)
 		if err != nil {
 			errCh <- fmt.Errorf("create scanner: %w", err)
 			return
 		}
 
-		result, warnings, err := scanner.Run()
+		result, warnings, err := scanner.Streamer(pw).Run()
 		if len(*warnings) > 0 {
 			s.logger.Printf("run finished with warnings: %s\n", *warnings)
 		}
@@ -67,52 +179,299 @@ func (s *NmapScanner) Scan(ctx context.Context, target string) ([]HostInfo, erro
 		resultCh <- result
 	}()
 
-	for {
+	select {
+	case result := <-resultCh:
+		<-decodeDone
+		// Deliver anything the stream decoder missed.
+		for _, info := range extractHostInfo(result) {
+			if !streamed[info.IP] {
+				found(info)
+			}
+		}
+		return nil
+	case err := <-errCh:
+		<-decodeDone
+		return err
+	case <-ctx.Done():
+		// nmap is killed with the context; wait for it and the decoder
+		// so that no host is delivered after returning.
 		select {
-		case result := <-resultCh:
-			s.progress.Finish()
-			return extractHostInfo(result), nil
-		case err := <-errCh:
-			s.progress.Finish()
-			return nil, err
-		case <-ctx.Done():
-			s.progress.Finish()
-			return nil, ctx.Err()
-		default:
-			s.progress.Update()
-			time.Sleep(100 * time.Millisecond)
+		case <-resultCh:
+		case <-errCh:
+		}
+		<-decodeDone
+		return ctx.Err()
+	}
+}
+
+// options returns the nmap options for a scan of targets, which must all
+// belong to one address family.
+func (s *NmapScanner) options(targets Targets, ipv6 bool) []nmap.Option {
+	opts := []nmap.Option{
+		nmap.WithTargets(nmapSpecs(targets.Include)...),
+		nmap.WithStatsEvery(statsInterval),
+	}
+	switch {
+	case len(s.portScan.Ports) > 0:
+		opts = append(opts, nmap.WithPorts(portList(s.portScan.Ports)))
+	case s.portScan.Top > 0:
+		opts = append(opts, nmap.WithMostCommonPorts(s.portScan.Top))
+	default:
+		opts = append(opts, nmap.WithPingScan())
+	}
+	if len(targets.Exclude) > 0 {
+		opts = append(opts, nmap.WithTargetExclusions(nmapSpecs(targets.Exclude)...))
+	}
+	if ipv6 {
+		opts = append(opts, nmap.WithIPv6Scanning())
+	}
+	return opts
+}
+
+// nmapSpecs rewrites target specifications into forms nmap understands.
+// nmap only accepts ranges per octet, so a range between two addresses
+// becomes a last-octet range when both lie in the same /24 and the CIDR
+// networks covering it otherwise. Other specifications are kept as given.
+func nmapSpecs(specs []string) []string {
+	out := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		ts, err := parseTargetSpec(spec)
+		if err != nil || !ts.first.Is4() || ts.first == ts.last {
+			out = append(out, spec)
+			continue
+		}
+		first, last := ts.first.As4(), ts.last.As4()
+		if [3]byte(first[:3]) == [3]byte(last[:3]) {
+			out = append(out, fmt.Sprintf("%s-%d", ts.first, last[3]))
+			continue
+		}
+		for _, prefix := range rangePrefixes(ts.first, ts.last) {
+			out = append(out, prefix.String())
+		}
+	}
+	return out
+}
+
+// DeepScan runs an nmap service/version scan (-sV) of nmap's default
+// ports on addr, adding OS detection (-O, which needs root) when
+// osDetection is set. It does not report progress, so it can run
+// alongside a network scan sharing the same reporter.
+//
+// Returns an error if nmap fails or addr does not answer.
+func (s *NmapScanner) DeepScan(ctx context.Context, addr netip.Addr, osDetection bool) (HostInfo, error) {
+	opts := []nmap.Option{
+		nmap.WithTargets(addr.String()),
+		nmap.WithServiceInfo(),
+	}
+	if osDetection {
+		opts = append(opts, nmap.WithOSDetection())
+	}
+	if addr.Is6() {
+		opts = append(opts, nmap.WithIPv6Scanning())
+	}
+
+	scanner, err := nmap.NewScanner(ctx, opts...)
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("create scanner: %w", err)
+	}
+	result, warnings, err := scanner.Run()
+	if warnings != nil && len(*warnings) > 0 {
+		s.logger.Printf("deep scan finished with warnings: %s\n", *warnings)
+	}
+	if err != nil {
+		return HostInfo{}, fmt.Errorf("run deep scan: %w", err)
+	}
+
+	// Only addr was scanned, so the single host reported up is addr.
+	hosts := extractHostInfo(result)
+	if len(hosts) == 0 {
+		return HostInfo{}, fmt.Errorf("%s did not answer the deep scan", addr)
+	}
+	return hosts[0], nil
+}
+
+// phaseUnits is the share of the overall progress of one phase of one
+// nmap run, in which nmap reports the phase's percentage.
+const phaseUnits = 100
+
+// scanProgress combines the <taskprogress> elements nmap emits with
+// --stats-every into the progress of the whole scan. nmap reports each
+// phase of a run from 0 to 100%: host discovery, then the port scan when
+// there is one; IPv4 and IPv6 targets are scanned in separate runs. Every
+// phase of every run gets an equal share of the total.
+type scanProgress struct {
+	reporter progress.Reporter
+
+	// runs is the number of nmap runs and phases the number of phases
+	// of each
+	runs, phases int
+
+	// run is the index of the current run
+	run int
+
+	// done is the last progress reported, which never goes back: nmap
+	// restarts a phase for each group of hosts it scans
+	done int
+}
+
+// report forwards one <taskprogress> element as the progress of the scan.
+func (p *scanProgress) report(tp nmap.TaskProgress) {
+	phase := 0
+	if p.phases > 1 && !isDiscoveryTask(tp.Task) {
+		phase = 1
+	}
+	done := (p.run*p.phases+phase)*phaseUnits + min(int(tp.Percent), phaseUnits)
+	p.done = max(p.done, done)
+	p.reporter.Progress(p.done, p.runs*p.phases*phaseUnits, time.Duration(tp.Remaining)*time.Second)
+}
+
+// isDiscoveryTask reports whether the nmap task named task belongs to host
+// discovery, e.g. "Ping Scan", "ARP Ping Scan" or the reverse DNS
+// resolution that follows them.
+func isDiscoveryTask(task string) bool {
+	return strings.Contains(task, "Ping Scan") || strings.Contains(task, "DNS resolution")
+}
+
+// decodeStream reads nmap XML output from r and calls found for every
+// <host> element as soon as it has been fully read, and onProgress for
+// every <taskprogress> element emitted by --stats-every.
+func decodeStream(r io.Reader, found func(nmap.Host), onProgress func(nmap.TaskProgress)) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "host":
+			var h nmap.Host
+			if err := dec.DecodeElement(&h, &start); err != nil {
+				return err
+			}
+			found(h)
+		case "taskprogress":
+			var tp nmap.TaskProgress
+			if err := dec.DecodeElement(&tp, &start); err != nil {
+				return err
+			}
+			onProgress(tp)
 		}
 	}
 }
 
 // extractHostInfo converts nmap scan results into a slice of HostInfo structs.
-// It extracts the first IP address, MAC address with vendor, and hostname
-// from each host. Missing fields are set to "none".
+// Addresses are classified by their nmap address type: every IP address is
+// kept (IPv4 before IPv6, the first becoming the primary IP), the MAC
+// address supplies MAC and vendor, and every distinct hostname is kept with
+// the first as the primary one. Open ports, when a port scan was run, are
+// kept ordered by number with their service versions, and the best OS
+// match is kept when OS detection was run. Missing or unparsable fields are left unset.
 func extractHostInfo(scanResult *nmap.Run) []HostInfo {
 	hosts := make([]HostInfo, 0, len(scanResult.Hosts))
 	for _, host := range scanResult.Hosts {
-		ip := "none"
-		mac := "none"
-		vendor := "none"
-		hostname := "none"
-
-		if len(host.Addresses) > 0 {
-			ip = host.Addresses[0].Addr
+		var (
+			info   HostInfo
+			v4, v6 []netip.Addr
+		)
+		for _, a := range host.Addresses {
+			switch addrType(a) {
+			case "ipv4", "ipv6":
+				addr, err := netip.ParseAddr(a.Addr)
+				if err != nil {
+					continue
+				}
+				if addr.Is4() {
+					v4 = append(v4, addr)
+				} else {
+					v6 = append(v6, addr)
+				}
+			case "mac":
+				if mac, err := net.ParseMAC(a.Addr); err == nil && info.MAC == nil {
+					info.MAC = mac
+					info.Vendor = a.Vendor
+				}
+			}
+		}
+		info.Addresses = append(v4, v6...)
+		if len(info.Addresses) > 0 {
+			info.IP = info.Addresses[0]
 		}
-		if len(host.Addresses) > 1 {
-			mac = host.Addresses[1].Addr
-			vendor = host.Addresses[1].Vendor
+
+		seen := make(map[string]bool, len(host.Hostnames))
+		for _, h := range host.Hostnames {
+			if h.Name == "" || seen[h.Name] {
+				continue
+			}
+			seen[h.Name] = true
+			info.Hostnames = append(info.Hostnames, h.Name)
 		}
-		if len(host.Hostnames) > 0 {
-			hostname = host.Hostnames[0].Name
+		if len(info.Hostnames) > 0 {
+			info.Hostname = info.Hostnames[0]
 		}
 
-		hosts = append(hosts, HostInfo{
-			IP:       ip,
-			MAC:      mac,
-			Vendor:   vendor,
-			Hostname: hostname,
+		for _, p := range host.Ports {
+			if p.State.State != "open" {
+				continue
+			}
+			info.Ports = append(info.Ports, Port{
+				Number:   p.ID,
+				Protocol: p.Protocol,
+				Service:  p.Service.Name,
+				Product:  p.Service.Product,
+				Version:  p.Service.Version,
+			})
+		}
+		slices.SortStableFunc(info.Ports, func(a, b Port) int {
+			return int(a.Number) - int(b.Number)
 		})
+
+		// nmap lists OS matches best first.
+		if len(host.OS.Matches) > 0 {
+			info.OS = host.OS.Matches[0].Name
+		}
+
+		info.Reason = host.Status.Reason
+		// nmap reports the smoothed round-trip time in microseconds.
+		if srtt, err := strconv.ParseInt(host.Times.SRTT, 10, 64); err == nil && srtt > 0 {
+			info.RTT = time.Duration(srtt) * time.Microsecond
+		}
+
+		hosts = append(hosts, info)
 	}
 	return hosts
 }
+
+// addrType returns the type of an nmap address ("ipv4", "ipv6" or "mac").
+// Addresses without a type, as in hand-written reports, are classified by
+// their syntax.
+func addrType(a nmap.Address) string {
+	if a.AddrType != "" {
+		return a.AddrType
+	}
+	if addr, err := netip.ParseAddr(a.Addr); err == nil {
+		if addr.Is4() {
+			return "ipv4"
+		}
+		return "ipv6"
+	}
+	if _, err := net.ParseMAC(a.Addr); err == nil {
+		return "mac"
+	}
+	return ""
+}
+
+// portList formats ports as a comma-separated list for nmap's -p.
+func portList(ports []uint16) string {
+	ss := make([]string, 0, len(ports))
+	for _, p := range ports {
+		ss = append(ss, strconv.Itoa(int(p)))
+	}
+	return strings.Join(ss, ",")
+}
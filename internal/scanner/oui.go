@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ouiPaths lists vendor databases that may be present on the system.
+// nmap's prefix file and arp-scan's IEEE list share the same
+// "<6 hex digits> <vendor>" line format. No database is built in: without
+// one of these files, ARPScanner.WithOUIFile names one explicitly.
+var ouiPaths = []string{
+	"/usr/share/nmap/nmap-mac-prefixes",
+	"/usr/local/share/nmap/nmap-mac-prefixes",
+	"/opt/homebrew/share/nmap/nmap-mac-prefixes",
+	"/usr/share/arp-scan/ieee-oui.txt",
+}
+
+// ouiTable maps the upper-case 24-bit OUI (e.g. "001122") to a vendor name.
+type ouiTable map[string]string
+
+var (
+	systemOUIOnce  sync.Once
+	systemOUITable ouiTable
+)
+
+// systemOUI loads the first vendor database found in ouiPaths.
+// An empty table is returned when none is available.
+func systemOUI() ouiTable {
+	systemOUIOnce.Do(func() {
+		systemOUITable = ouiTable{}
+		for _, path := range ouiPaths {
+			f, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			systemOUITable = parseOUI(f)
+			_ = f.Close()
+			return
+		}
+	})
+	return systemOUITable
+}
+
+// loadOUI reads the vendor database at path, failing when it holds no
+// entry so that a mistyped or unsupported file is not silently ignored.
+func loadOUI(path string) (ouiTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OUI file: %w", err)
+	}
+	defer f.Close()
+
+	table := parseOUI(f)
+	if len(table) == 0 {
+		return nil, fmt.Errorf("no vendor found in OUI file %s", path)
+	}
+	return table, nil
+}
+
+// parseOUI reads a vendor database in "<OUI> <vendor>" format, or in the
+// IEEE's own oui.txt format ("00-11-22   (hex)\t\tVendor").
+// Comment lines and malformed entries are skipped.
+func parseOUI(r io.Reader) ouiTable {
+	table := ouiTable{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sep := strings.IndexAny(line, " \t")
+		if sep < 0 {
+			continue
+		}
+		key := strings.ReplaceAll(line[:sep], "-", "")
+		if len(key) != 6 {
+			continue
+		}
+		vendor := strings.TrimSpace(line[sep:])
+		if rest, ok := strings.CutPrefix(vendor, "(hex)"); ok {
+			vendor = strings.TrimSpace(rest)
+		} else if strings.HasPrefix(vendor, "(base 16)") {
+			// Repeats the "(hex)" line of the same IEEE entry.
+			continue
+		}
+		table[strings.ToUpper(key)] = vendor
+	}
+	return table
+}
+
+// Vendor returns the vendor registered for mac, or "" when unknown.
+func (t ouiTable) Vendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	key := strings.ToUpper(strings.ReplaceAll(mac[:3].String(), ":", ""))
+	if vendor, ok := t[key]; ok && vendor != "" {
+		return vendor
+	}
+	return ""
+}
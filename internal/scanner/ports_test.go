@@ -0,0 +1,36 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []uint16
+		wantErr bool
+	}{
+		{spec: "22,80,443", want: []uint16{22, 80, 443}},
+		{spec: " 22 , 80 ", want: []uint16{22, 80}},
+		{spec: "8000-8002,22", want: []uint16{8000, 8001, 8002, 22}},
+		{spec: "22,22,80", want: []uint16{22, 80}},
+		{spec: "", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "65536", wantErr: true},
+		{spec: "ssh", wantErr: true},
+		{spec: "90-80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePorts(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePorts(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePorts(%q) = %v; want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PortScan selects the TCP ports checked on every discovered host: the Top
+// most common ones, or the Ports listed. The zero value disables port
+// scanning.
+type PortScan struct {
+	// Top is the number of most common ports to scan
+	Top int
+
+	// Ports lists the ports to scan; it takes precedence over Top
+	Ports []uint16
+}
+
+// Enabled reports whether ps selects any port.
+func (ps PortScan) Enabled() bool {
+	return ps.Top > 0 || len(ps.Ports) > 0
+}
+
+// PortScanner adds a TCP connect port scan to a discovery scanner: every
+// host the discovery finds is checked for open ports before it is
+// delivered, and the ports that accept a connection are recorded in
+// HostInfo.Ports with their usual service name.
+type PortScanner struct {
+	discovery   Scanner
+	scan        PortScan
+	dialTimeout time.Duration
+	concurrency int
+
+	dial     func(ctx context.Context, network, address string) (net.Conn, error)
+	services func() serviceTable
+}
+
+// NewPortScanner creates a PortScanner that discovers hosts with discovery
+// and then scans the ports selected by ps.
+func NewPortScanner(discovery Scanner, ps PortScan) *PortScanner {
+	dialer := &net.Dialer{}
+	return &PortScanner{
+		discovery:   discovery,
+		scan:        ps,
+		dialTimeout: DefaultTCPDialTimeout,
+		concurrency: DefaultTCPConcurrency,
+		dial:        dialer.DialContext,
+		services:    systemServices,
+	}
+}
+
+// Scan discovers the hosts in targets and returns them, sorted by IP, with
+// their open ports. The scan respects the provided context for
+// cancellation.
+func (s *PortScanner) Scan(ctx context.Context, targets Targets) ([]HostInfo, error) {
+	return collectHosts(ctx, targets, s.ScanStream)
+}
+
+// ScanStream performs the same scan as Scan, delivering each host through
+// found as soon as its ports have been scanned.
+func (s *PortScanner) ScanStream(ctx context.Context, targets Targets, found func(HostInfo)) error {
+	services := s.services()
+	ports := s.scan.Ports
+	if len(ports) == 0 {
+		ports = services.Top(s.scan.Top)
+		if len(ports) < s.scan.Top {
+			return fmt.Errorf("only the %d most common ports are known without nmap's services database: install nmap or list the ports to scan", len(ports))
+		}
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, s.concurrency)
+	)
+	err := Stream(ctx, s.discovery, targets, func(h HostInfo) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Ports = s.openPorts(ctx, h.IP, ports, services, sem)
+
+			mu.Lock()
+			defer mu.Unlock()
+			found(h)
+		}()
+	})
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// openPorts connects to each of ports on addr, at most cap(sem) at a time
+// across all hosts, and returns those that accepted, ordered by number.
+func (s *PortScanner) openPorts(ctx context.Context, addr netip.Addr, ports []uint16, services serviceTable, sem chan struct{}) []Port {
+	if !addr.IsValid() {
+		return nil
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		open []Port
+	)
+	for _, port := range ports {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if !s.isOpen(ctx, addr, port) {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			open = append(open, Port{Number: port, Protocol: "tcp", Service: services.Service(port)})
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(open, func(a, b Port) int {
+		return int(a.Number) - int(b.Number)
+	})
+	return open
+}
+
+// isOpen reports whether a TCP connection to port on addr completes.
+func (s *PortScanner) isOpen(ctx context.Context, addr netip.Addr, port uint16) bool {
+	dialCtx, cancel := context.WithTimeout(ctx, s.dialTimeout)
+	defer cancel()
+	conn, err := s.dial(dialCtx, "tcp", net.JoinHostPort(addr.String(), strconv.Itoa(int(port))))
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
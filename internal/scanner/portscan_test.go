@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestPortScanner_Scan(t *testing.T) {
+	discovery := sliceScanner{hosts: []HostInfo{
+		{IP: netip.MustParseAddr("10.0.0.3"), Reason: ReasonARPResponse},
+		{IP: netip.MustParseAddr("10.0.0.2"), Reason: ReasonARPResponse},
+	}}
+	s := NewPortScanner(discovery, PortScan{Ports: []uint16{443, 22, 8443}})
+	s.dial = fakeDialer(map[string]error{
+		"10.0.0.2:22":   nil,
+		"10.0.0.2:443":  nil,
+		"10.0.0.2:8443": &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED},
+		"10.0.0.3:8443": nil,
+	})
+	s.services = func() serviceTable {
+		return serviceTable{names: map[uint16]string{22: "ssh", 443: "https"}}
+	}
+
+	got, err := s.Scan(context.Background(), NewTargets("10.0.0.0/29"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []HostInfo{
+		{IP: netip.MustParseAddr("10.0.0.2"), Reason: ReasonARPResponse, Ports: []Port{
+			{Number: 22, Protocol: "tcp", Service: "ssh"},
+			{Number: 443, Protocol: "tcp", Service: "https"},
+		}},
+		{IP: netip.MustParseAddr("10.0.0.3"), Reason: ReasonARPResponse, Ports: []Port{
+			{Number: 8443, Protocol: "tcp"},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestPortScanner_Scan_TopPorts(t *testing.T) {
+	discovery := sliceScanner{hosts: []HostInfo{{IP: netip.MustParseAddr("10.0.0.2")}}}
+	services := serviceTable{names: map[uint16]string{80: "http", 22: "ssh"}, ranked: []uint16{80, 22, 8080}}
+
+	var dialed []string
+	s := NewPortScanner(discovery, PortScan{Top: 2})
+	s.concurrency = 1
+	s.dial = func(_ context.Context, _, address string) (net.Conn, error) {
+		dialed = append(dialed, address)
+		return nil, context.DeadlineExceeded
+	}
+	s.services = func() serviceTable { return services }
+
+	if _, err := s.Scan(context.Background(), NewTargets("10.0.0.2")); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if want := []string{"10.0.0.2:80", "10.0.0.2:22"}; !reflect.DeepEqual(dialed, want) {
+		t.Errorf("dialed %v; want the top ports %v", dialed, want)
+	}
+
+	s.scan = PortScan{Top: 5}
+	if _, err := s.Scan(context.Background(), NewTargets("10.0.0.2")); err == nil || !strings.Contains(err.Error(), "only the 3 most common ports") {
+		t.Errorf("Scan() with more top ports than known: error = %v", err)
+	}
+}
+
+func TestPortScanner_Scan_DiscoveryError(t *testing.T) {
+	want := errors.New("no permission")
+	s := NewPortScanner(sliceScanner{err: want}, PortScan{Ports: []uint16{22}})
+
+	if _, err := s.Scan(context.Background(), NewTargets("10.0.0.2")); !errors.Is(err, want) {
+		t.Errorf("Scan() error = %v; want %v", err, want)
+	}
+}
+
+func TestParseServices(t *testing.T) {
+	input := "# comment\n" +
+		"ssh\t22/tcp\t0.182286\t# Secure Shell Login\n" +
+		"domain\t53/udp\t0.213496\n" +
+		"http\t80/tcp\t0.484143\n" +
+		"unknown\t9999/tcp\t0.000100\n" +
+		"bad line\n"
+	table := parseServices(strings.NewReader(input))
+
+	if want := []uint16{80, 22, 9999}; !reflect.DeepEqual(table.ranked, want) {
+		t.Errorf("ranked = %v; want %v", table.ranked, want)
+	}
+	for port, want := range map[uint16]string{22: "ssh", 80: "http", 53: "", 9999: ""} {
+		if got := table.Service(port); got != want {
+			t.Errorf("Service(%d) = %q; want %q", port, got, want)
+		}
+	}
+	if got := table.Top(10); len(got) != 3 {
+		t.Errorf("Top(10) = %v; want all 3 known ports", got)
+	}
+}
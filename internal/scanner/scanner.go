@@ -2,14 +2,63 @@ package scanner
 
 import (
 	"context"
+	"net/netip"
 )
 
 // Scanner defines the interface for network scanning operations.
 // Implementations can use different scanning tools (nmap, custom, etc.)
 // or provide mock implementations for testing.
 type Scanner interface {
-	// Scan performs a network scan on the specified CIDR target.
+	// Scan performs a network scan of the specified targets.
 	// Returns a list of discovered hosts or an error if the scan fails.
 	// The context can be used to cancel the scan operation.
-	Scan(ctx context.Context, target string) ([]HostInfo, error)
+	Scan(ctx context.Context, targets Targets) ([]HostInfo, error)
+}
+
+// StreamScanner is implemented by scanners that can deliver hosts as they
+// are discovered rather than only when the whole scan has finished.
+type StreamScanner interface {
+	Scanner
+
+	// ScanStream performs the same scan as Scan but calls found for each
+	// host as soon as it is known. Calls to found are serialized.
+	// The context can be used to cancel the scan operation.
+	ScanStream(ctx context.Context, targets Targets, found func(HostInfo)) error
+}
+
+// FileScanner is implemented by scanners that read hosts from a saved
+// report instead of probing the network. Rescanning re-reads the file.
+type FileScanner interface {
+	Scanner
+
+	// Path returns the file the hosts are read from.
+	Path() string
+}
+
+// DeepScanner is implemented by scanners that can examine a single host in
+// depth.
+type DeepScanner interface {
+	// DeepScan detects the service and version behind each open port of
+	// addr, and its operating system when osDetection is set. The result
+	// has the host's open Ports, with Product and Version, and OS filled
+	// in. The context can be used to cancel the scan operation.
+	DeepScan(ctx context.Context, addr netip.Addr, osDetection bool) (HostInfo, error)
+}
+
+// Stream scans targets with s, delivering hosts through found as they are
+// discovered when s implements StreamScanner. Other scanners are run to
+// completion with Scan and their hosts delivered afterwards.
+func Stream(ctx context.Context, s Scanner, targets Targets, found func(HostInfo)) error {
+	if ss, ok := s.(StreamScanner); ok {
+		return ss.ScanStream(ctx, targets, found)
+	}
+
+	hosts, err := s.Scan(ctx, targets)
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		found(h)
+	}
+	return nil
 }
@@ -1,12 +1,50 @@
 package scanner
 
 import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Ullaakut/nmap/v3"
+
+	"nls/internal/progress"
 )
 
+// mustParseMAC parses a MAC address, panicking on error.
+func mustParseMAC(s string) net.HardwareAddr {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		panic(err)
+	}
+	return mac
+}
+
+// parseAddrs parses a list of IP addresses, panicking on error.
+func parseAddrs(ss ...string) []netip.Addr {
+	addrs := make([]netip.Addr, 0, len(ss))
+	for _, s := range ss {
+		addrs = append(addrs, netip.MustParseAddr(s))
+	}
+	return addrs
+}
+
+// clearRTTs checks that every host has a round-trip time and then zeroes
+// it, so hosts from a live sweep can be compared with fixed expectations.
+func clearRTTs(t *testing.T, hosts []HostInfo) {
+	t.Helper()
+	for i := range hosts {
+		if hosts[i].RTT <= 0 {
+			t.Errorf("host %s has no RTT", hosts[i].IP)
+		}
+		hosts[i].RTT = 0
+	}
+}
+
 func TestExtractHostInfo(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -30,10 +68,12 @@ func TestExtractHostInfo(t *testing.T) {
 			},
 			expected: []HostInfo{
 				{
-					IP:       "192.168.1.10",
-					MAC:      "AA:BB:CC:DD:EE:FF",
-					Vendor:   "Apple Inc.",
-					Hostname: "macbook.local",
+					IP:        netip.MustParseAddr("192.168.1.10"),
+					MAC:       mustParseMAC("AA:BB:CC:DD:EE:FF"),
+					Vendor:    "Apple Inc.",
+					Hostname:  "macbook.local",
+					Addresses: parseAddrs("192.168.1.10"),
+					Hostnames: []string{"macbook.local"},
 				},
 			},
 		},
@@ -63,16 +103,20 @@ func TestExtractHostInfo(t *testing.T) {
 			},
 			expected: []HostInfo{
 				{
-					IP:       "192.168.1.1",
-					MAC:      "00:11:22:33:44:55",
-					Vendor:   "Router Co",
-					Hostname: "router.local",
+					IP:        netip.MustParseAddr("192.168.1.1"),
+					MAC:       mustParseMAC("00:11:22:33:44:55"),
+					Vendor:    "Router Co",
+					Hostname:  "router.local",
+					Addresses: parseAddrs("192.168.1.1"),
+					Hostnames: []string{"router.local"},
 				},
 				{
-					IP:       "192.168.1.2",
-					MAC:      "AA:BB:CC:DD:EE:00",
-					Vendor:   "Device Inc",
-					Hostname: "device.local",
+					IP:        netip.MustParseAddr("192.168.1.2"),
+					MAC:       mustParseMAC("AA:BB:CC:DD:EE:00"),
+					Vendor:    "Device Inc",
+					Hostname:  "device.local",
+					Addresses: parseAddrs("192.168.1.2"),
+					Hostnames: []string{"device.local"},
 				},
 			},
 		},
@@ -89,10 +133,8 @@ func TestExtractHostInfo(t *testing.T) {
 			},
 			expected: []HostInfo{
 				{
-					IP:       "192.168.1.100",
-					MAC:      "none",
-					Vendor:   "none",
-					Hostname: "none",
+					IP:        netip.MustParseAddr("192.168.1.100"),
+					Addresses: parseAddrs("192.168.1.100"),
 				},
 			},
 		},
@@ -105,14 +147,7 @@ func TestExtractHostInfo(t *testing.T) {
 					},
 				},
 			},
-			expected: []HostInfo{
-				{
-					IP:       "none",
-					MAC:      "none",
-					Vendor:   "none",
-					Hostname: "none",
-				},
-			},
+			expected: []HostInfo{{}},
 		},
 		{
 			name: "empty scan result",
@@ -122,7 +157,7 @@ func TestExtractHostInfo(t *testing.T) {
 			expected: []HostInfo{},
 		},
 		{
-			name: "host with multiple hostnames - takes first",
+			name: "host with multiple hostnames - keeps all, first is primary",
 			input: &nmap.Run{
 				Hosts: []nmap.Host{
 					{
@@ -130,18 +165,130 @@ func TestExtractHostInfo(t *testing.T) {
 							{Addr: "192.168.1.50", AddrType: "ipv4"},
 						},
 						Hostnames: []nmap.Hostname{
-							{Name: "primary.local"},
-							{Name: "secondary.local"},
+							{Name: "primary.local", Type: "user"},
+							{Name: "secondary.local", Type: "PTR"},
+							{Name: "primary.local", Type: "PTR"},
 						},
 					},
 				},
 			},
 			expected: []HostInfo{
 				{
-					IP:       "192.168.1.50",
-					MAC:      "none",
-					Vendor:   "none",
-					Hostname: "primary.local",
+					IP:        netip.MustParseAddr("192.168.1.50"),
+					Hostname:  "primary.local",
+					Addresses: parseAddrs("192.168.1.50"),
+					Hostnames: []string{"primary.local", "secondary.local"},
+				},
+			},
+		},
+		{
+			name: "dual-stack host - addresses picked by type",
+			input: &nmap.Run{
+				Hosts: []nmap.Host{
+					{
+						Addresses: []nmap.Address{
+							{Addr: "fd00::10", AddrType: "ipv6"},
+							{Addr: "192.168.1.60", AddrType: "ipv4"},
+							{Addr: "AA:BB:CC:DD:EE:01", AddrType: "mac"},
+						},
+					},
+				},
+			},
+			expected: []HostInfo{
+				{
+					IP:        netip.MustParseAddr("192.168.1.60"),
+					MAC:       mustParseMAC("AA:BB:CC:DD:EE:01"),
+					Addresses: parseAddrs("192.168.1.60", "fd00::10"),
+				},
+			},
+		},
+		{
+			name: "IPv6 host with MAC - IPv6 stays out of the MAC column",
+			input: &nmap.Run{
+				Hosts: []nmap.Host{
+					{
+						Addresses: []nmap.Address{
+							{Addr: "fe80::1", AddrType: "ipv6"},
+							{Addr: "2001:db8::1", AddrType: "ipv6"},
+							{Addr: "00:11:22:33:44:55", AddrType: "mac", Vendor: "Router Co"},
+						},
+					},
+				},
+			},
+			expected: []HostInfo{
+				{
+					IP:        netip.MustParseAddr("fe80::1"),
+					MAC:       mustParseMAC("00:11:22:33:44:55"),
+					Vendor:    "Router Co",
+					Addresses: parseAddrs("fe80::1", "2001:db8::1"),
+				},
+			},
+		},
+		{
+			name: "addresses without a type - classified by syntax",
+			input: &nmap.Run{
+				Hosts: []nmap.Host{
+					{
+						Addresses: []nmap.Address{
+							{Addr: "AA:BB:CC:DD:EE:02"},
+							{Addr: "10.0.0.5"},
+						},
+					},
+				},
+			},
+			expected: []HostInfo{
+				{
+					IP:        netip.MustParseAddr("10.0.0.5"),
+					MAC:       mustParseMAC("AA:BB:CC:DD:EE:02"),
+					Addresses: parseAddrs("10.0.0.5"),
+				},
+			},
+		},
+		{
+			name: "version scan with OS detection",
+			input: &nmap.Run{
+				Hosts: []nmap.Host{
+					{
+						Addresses: []nmap.Address{{Addr: "10.0.0.7", AddrType: "ipv4"}},
+						Ports: []nmap.Port{
+							{ID: 22, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh", Product: "OpenSSH", Version: "9.6p1"}},
+						},
+						OS: nmap.OS{Matches: []nmap.OSMatch{{Name: "Linux 5.0 - 5.14", Accuracy: 98}, {Name: "Linux 4.15", Accuracy: 90}}},
+					},
+				},
+			},
+			expected: []HostInfo{
+				{
+					IP:        netip.MustParseAddr("10.0.0.7"),
+					Addresses: parseAddrs("10.0.0.7"),
+					Ports:     []Port{{Number: 22, Protocol: "tcp", Service: "ssh", Product: "OpenSSH", Version: "9.6p1"}},
+					OS:        "Linux 5.0 - 5.14",
+				},
+			},
+		},
+		{
+			name: "port scan - only open ports kept, ordered by number",
+			input: &nmap.Run{
+				Hosts: []nmap.Host{
+					{
+						Addresses: []nmap.Address{{Addr: "10.0.0.6", AddrType: "ipv4"}},
+						Ports: []nmap.Port{
+							{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "https"}},
+							{ID: 23, Protocol: "tcp", State: nmap.State{State: "closed"}, Service: nmap.Service{Name: "telnet"}},
+							{ID: 22, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh"}},
+							{ID: 3389, Protocol: "tcp", State: nmap.State{State: "filtered"}},
+						},
+					},
+				},
+			},
+			expected: []HostInfo{
+				{
+					IP:        netip.MustParseAddr("10.0.0.6"),
+					Addresses: parseAddrs("10.0.0.6"),
+					Ports: []Port{
+						{Number: 22, Protocol: "tcp", Service: "ssh"},
+						{Number: 443, Protocol: "tcp", Service: "https"},
+					},
 				},
 			},
 		},
@@ -175,10 +322,192 @@ func TestExtractHostInfo_SlicePreallocation(t *testing.T) {
 		t.Errorf("expected 1000 hosts, got %d", len(results))
 	}
 
-	if results[0].IP != "192.168.1.1" {
-		t.Errorf("first host IP = %q; want %q", results[0].IP, "192.168.1.1")
+	want := netip.MustParseAddr("192.168.1.1")
+	if results[0].IP != want {
+		t.Errorf("first host IP = %v; want %v", results[0].IP, want)
+	}
+	if results[999].IP != want {
+		t.Errorf("last host IP = %v; want %v", results[999].IP, want)
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	const output = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="nmap" args="nmap -sn 192.168.1.0/24">
+<taskprogress task="Ping Scan" percent="50.00" remaining="1"/>
+<host><status state="up" reason="arp-response"/>
+<address addr="192.168.1.1" addrtype="ipv4"/>
+<address addr="00:11:22:33:44:55" addrtype="mac" vendor="Router Co"/>
+<hostnames><hostname name="router.local" type="PTR"/></hostnames>
+<times srtt="1520" rttvar="5000" to="100000"/>
+</host>
+<host><status state="up" reason="echo-reply"/>
+<address addr="192.168.1.7" addrtype="ipv4"/>
+</host>
+<runstats><finished/></runstats>
+</nmaprun>`
+
+	var (
+		got      []nmap.Host
+		progress []nmap.TaskProgress
+	)
+	err := decodeStream(strings.NewReader(output), func(h nmap.Host) {
+		got = append(got, h)
+	}, func(tp nmap.TaskProgress) {
+		progress = append(progress, tp)
+	})
+	if err != nil {
+		t.Fatalf("decodeStream() error = %v", err)
+	}
+	if len(progress) != 1 || progress[0].Percent != 50 || progress[0].Remaining != 1 {
+		t.Errorf("decoded progress = %+v; want one 50%% update", progress)
+	}
+	if len(got) != 2 {
+		t.Fatalf("decoded %d hosts; want 2", len(got))
+	}
+
+	hosts := extractHostInfo(&nmap.Run{Hosts: got})
+	want := []HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("00:11:22:33:44:55"), Vendor: "Router Co", Hostname: "router.local", Addresses: parseAddrs("192.168.1.1"), Hostnames: []string{"router.local"}, RTT: 1520 * time.Microsecond, Reason: "arp-response"},
+		{IP: netip.MustParseAddr("192.168.1.7"), Addresses: parseAddrs("192.168.1.7"), Reason: "echo-reply"},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("decoded hosts mismatch:\ngot:  %+v\nwant: %+v", hosts, want)
+	}
+}
+
+func TestDecodeStream_Truncated(t *testing.T) {
+	var count int
+	err := decodeStream(strings.NewReader(`<nmaprun><host><address addr="10.0.0.1" addrtype="ipv4"/></host><host>`), func(nmap.Host) {
+		count++
+	}, func(nmap.TaskProgress) {})
+	if err == nil {
+		t.Error("expected error for truncated output")
+	}
+	if count != 1 {
+		t.Errorf("decoded %d complete hosts before error; want 1", count)
+	}
+}
+
+// sliceScanner is a Scanner without streaming support.
+type sliceScanner struct {
+	hosts []HostInfo
+	err   error
+}
+
+func (s sliceScanner) Scan(context.Context, Targets) ([]HostInfo, error) {
+	return s.hosts, s.err
+}
+
+func TestStream_FallsBackToScan(t *testing.T) {
+	hosts := []HostInfo{{IP: netip.MustParseAddr("10.0.0.1")}, {IP: netip.MustParseAddr("10.0.0.2")}}
+
+	var got []HostInfo
+	err := Stream(context.Background(), sliceScanner{hosts: hosts}, NewTargets("10.0.0.0/24"), func(h HostInfo) {
+		got = append(got, h)
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, hosts) {
+		t.Errorf("Stream() delivered %+v; want %+v", got, hosts)
+	}
+
+	scanErr := errors.New("boom")
+	if err := Stream(context.Background(), sliceScanner{err: scanErr}, NewTargets("10.0.0.0/24"), func(HostInfo) {}); !errors.Is(err, scanErr) {
+		t.Errorf("Stream() error = %v; want %v", err, scanErr)
+	}
+}
+
+func TestStream_UsesScanStream(t *testing.T) {
+	conn := newFakeEchoConn("10.0.0.3")
+	s := newTestICMPScanner(conn)
+
+	var got []string
+	err := Stream(context.Background(), s, NewTargets("10.0.0.0/29"), func(h HostInfo) {
+		got = append(got, h.IP.String())
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"10.0.0.3"}) {
+		t.Errorf("Stream() delivered %v; want [10.0.0.3]", got)
+	}
+}
+
+func TestSplitFamilies(t *testing.T) {
+	targets := Targets{
+		Include: []string{"10.0.0.0/24", "fd00::/120", "nas.local", "10.0.1.1-9", "fe80::1%eth0"},
+		Exclude: []string{"10.0.0.1", "fd00::1", "printer"},
+	}
+
+	v4, v6, err := splitFamilies(targets)
+	if err != nil {
+		t.Fatalf("splitFamilies() error = %v", err)
 	}
-	if results[999].IP != "192.168.1.1" {
-		t.Errorf("last host IP = %q; want %q", results[999].IP, "192.168.1.1")
+	wantV4 := Targets{Include: []string{"10.0.0.0/24", "nas.local", "10.0.1.1-9"}, Exclude: []string{"10.0.0.1", "printer"}}
+	wantV6 := Targets{Include: []string{"fd00::/120", "fe80::1%eth0"}, Exclude: []string{"fd00::1", "printer"}}
+	if !reflect.DeepEqual(v4, wantV4) {
+		t.Errorf("v4 = %+v; want %+v", v4, wantV4)
+	}
+	if !reflect.DeepEqual(v6, wantV6) {
+		t.Errorf("v6 = %+v; want %+v", v6, wantV6)
+	}
+
+	if _, _, err := splitFamilies(NewTargets("fe80::/64")); err == nil || !strings.Contains(err.Error(), "multicast") {
+		t.Errorf("splitFamilies() error = %v; want a hint about multicast discovery", err)
+	}
+}
+
+func TestNmapScanner_Options(t *testing.T) {
+	targets := Targets{
+		Include: []string{"10.0.0.200-10.0.1.20", "10.0.2.1-50", "10.0.3.5-10.0.3.9", "nas.local"},
+		Exclude: []string{"10.0.0.250-10.0.1.3", "10.0.2.7"},
+	}
+	s := NewNmapScanner(nil)
+
+	scanner, err := nmap.NewScanner(context.Background(), append(s.options(targets, false), nmap.WithBinaryPath("nmap"))...)
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v", err)
+	}
+	args := strings.Join(scanner.Args(), " ")
+	for _, want := range []string{
+		"10.0.0.200/29 10.0.0.208/28 10.0.0.224/27 10.0.1.0/28 10.0.1.16/30 10.0.1.20/32 10.0.2.1-50 10.0.3.5-9 nas.local",
+		"--exclude 10.0.0.250/31,10.0.0.252/30,10.0.1.0/30,10.0.2.7",
+		"-sn",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("nmap arguments %q; want them to contain %q", args, want)
+		}
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	tracker := progress.NewTracker()
+	p := &scanProgress{reporter: tracker, runs: 2, phases: 2}
+
+	steps := []struct {
+		run  int
+		task string
+		pct  float32
+		want int
+	}{
+		{0, "ARP Ping Scan", 50, 50},
+		{0, "ARP Ping Scan", 100, 100},
+		{0, "Parallel DNS resolution of 12 hosts.", 40, 100},
+		{0, "SYN Stealth Scan", 30, 130},
+		// nmap starts over for the next group of hosts
+		{0, "SYN Stealth Scan", 10, 130},
+		{0, "SYN Stealth Scan", 100, 200},
+		{1, "Ping Scan", 50, 250},
+		{1, "SYN Stealth Scan", 100, 400},
+	}
+	for _, st := range steps {
+		p.run = st.run
+		p.report(nmap.TaskProgress{Task: st.task, Percent: st.pct})
+		if s := tracker.Snapshot(); s.Done != st.want || s.Total != 400 {
+			t.Errorf("after %s at %v%% of run %d: %d/%d; want %d/400", st.task, st.pct, st.run, s.Done, s.Total, st.want)
+		}
 	}
 }
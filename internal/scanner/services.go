@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// servicesPaths lists locations of nmap's services database, which names
+// the service usually found on each port and ranks ports by how often
+// they are found open.
+var servicesPaths = []string{
+	"/usr/share/nmap/nmap-services",
+	"/usr/local/share/nmap/nmap-services",
+	"/opt/homebrew/share/nmap/nmap-services",
+}
+
+// builtinTopPorts are nmap's 20 most common TCP ports, most common first,
+// used when its services database is not installed.
+var builtinTopPorts = []struct {
+	port    uint16
+	service string
+}{
+	{80, "http"}, {23, "telnet"}, {443, "https"}, {21, "ftp"}, {22, "ssh"},
+	{25, "smtp"}, {3389, "ms-wbt-server"}, {110, "pop3"}, {445, "microsoft-ds"},
+	{139, "netbios-ssn"}, {143, "imap"}, {53, "domain"}, {135, "msrpc"},
+	{3306, "mysql"}, {8080, "http-proxy"}, {1723, "pptp"}, {111, "rpcbind"},
+	{995, "pop3s"}, {993, "imaps"}, {5900, "vnc"},
+}
+
+// serviceTable holds the TCP entries of a services database.
+type serviceTable struct {
+	// names maps a port to its service name
+	names map[uint16]string
+
+	// ranked lists ports from most to least commonly open
+	ranked []uint16
+}
+
+var (
+	systemServicesOnce  sync.Once
+	systemServicesTable serviceTable
+)
+
+// systemServices loads the first services database found in servicesPaths,
+// falling back to builtinTopPorts when none is available.
+func systemServices() serviceTable {
+	systemServicesOnce.Do(func() {
+		for _, path := range servicesPaths {
+			f, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			systemServicesTable = parseServices(f)
+			_ = f.Close()
+			if len(systemServicesTable.ranked) > 0 {
+				return
+			}
+		}
+		systemServicesTable = serviceTable{names: make(map[uint16]string)}
+		for _, e := range builtinTopPorts {
+			systemServicesTable.names[e.port] = e.service
+			systemServicesTable.ranked = append(systemServicesTable.ranked, e.port)
+		}
+	})
+	return systemServicesTable
+}
+
+// parseServices reads the TCP entries of an nmap-services file, whose
+// lines have the form "<service> <port>/<protocol> <open frequency>".
+// Comments and malformed lines are skipped, as are "unknown" names.
+func parseServices(r io.Reader) serviceTable {
+	type entry struct {
+		port uint16
+		freq float64
+	}
+	var (
+		table   = serviceTable{names: make(map[uint16]string)}
+		entries []entry
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		portStr, proto, ok := strings.Cut(fields[1], "/")
+		if !ok || proto != "tcp" {
+			continue
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || port == 0 {
+			continue
+		}
+		freq, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			continue
+		}
+		if fields[0] != "unknown" {
+			table.names[uint16(port)] = fields[0]
+		}
+		entries = append(entries, entry{uint16(port), freq})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].freq > entries[j].freq
+	})
+	for _, e := range entries {
+		table.ranked = append(table.ranked, e.port)
+	}
+	return table
+}
+
+// Service returns the name of the service usually found on TCP port, or
+// "" when unknown.
+func (t serviceTable) Service(port uint16) string {
+	return t.names[port]
+}
+
+// Top returns the n most commonly open TCP ports, or all known ports when
+// there are fewer.
+func (t serviceTable) Top(n int) []uint16 {
+	if n > len(t.ranked) {
+		n = len(t.ranked)
+	}
+	return t.ranked[:n]
+}
@@ -0,0 +1,193 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nls/internal/progress"
+)
+
+// maxSweepAddrs caps the number of addresses a native sweep will probe.
+// Larger target sets are left to nmap, which paces itself far better.
+const maxSweepAddrs = 1 << maxSweepHostBits
+
+// maxSweepHostBits is the number of host bits in the largest network a
+// native sweep probes address by address (a /16 or an IPv6 /112).
+const maxSweepHostBits = 16
+
+// reverseLookupTimeout bounds the PTR lookup performed for each discovered host.
+const reverseLookupTimeout = 2 * time.Second
+
+// maxConcurrentLookups bounds the number of in-flight PTR lookups.
+const maxConcurrentLookups = 32
+
+// hostEmitter resolves the hostname of each discovered host in the
+// background and hands the completed HostInfo to a found callback.
+// Callbacks are serialized, so found never runs concurrently with itself.
+type hostEmitter struct {
+	ctx    context.Context
+	lookup func(context.Context, string) ([]string, error)
+	found  func(HostInfo)
+
+	mu  sync.Mutex
+	wg  sync.WaitGroup
+	sem chan struct{}
+}
+
+func newHostEmitter(ctx context.Context, lookup func(context.Context, string) ([]string, error), found func(HostInfo)) *hostEmitter {
+	return &hostEmitter{
+		ctx:    ctx,
+		lookup: lookup,
+		found:  found,
+		sem:    make(chan struct{}, maxConcurrentLookups),
+	}
+}
+
+// emit fills in h.Addresses and h.Hostnames with the probed address and its
+// PTR names, setting h.Hostname to the first name (left empty when the
+// lookup fails or yields nothing), and then delivers h. Each lookup is bounded by
+// reverseLookupTimeout.
+func (e *hostEmitter) emit(h HostInfo) {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.sem <- struct{}{}
+		defer func() { <-e.sem }()
+
+		lookupCtx, cancel := context.WithTimeout(e.ctx, reverseLookupTimeout)
+		defer cancel()
+
+		h.Addresses = []netip.Addr{h.IP}
+		if names, err := e.lookup(lookupCtx, h.IP.String()); err == nil {
+			for _, name := range names {
+				h.Hostnames = append(h.Hostnames, strings.TrimSuffix(name, "."))
+			}
+		}
+		if len(h.Hostnames) > 0 {
+			h.Hostname = h.Hostnames[0]
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.found(h)
+	}()
+}
+
+// wait blocks until every emitted host has been delivered.
+func (e *hostEmitter) wait() {
+	e.wg.Wait()
+}
+
+// sendTimes records when each probe of a sweep was sent so that replies,
+// read concurrently, can be timed.
+type sendTimes struct {
+	mu   sync.Mutex
+	sent map[netip.Addr]time.Time
+}
+
+func newSendTimes() *sendTimes {
+	return &sendTimes{sent: make(map[netip.Addr]time.Time)}
+}
+
+// mark records that a probe to addr is being sent now. It must be called
+// before sending, as a reply may be read before the send call returns.
+func (t *sendTimes) mark(addr netip.Addr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent[addr] = time.Now()
+}
+
+// since returns the time elapsed since the probe to addr was sent, or zero
+// when no probe was sent to it.
+func (t *sendTimes) since(addr netip.Addr) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sent, ok := t.sent[addr]
+	if !ok {
+		return 0
+	}
+	return time.Since(sent)
+}
+
+// collectHosts runs a streaming scan to completion and returns the hosts
+// it delivered, sorted by IP.
+func collectHosts(ctx context.Context, targets Targets, stream func(context.Context, Targets, func(HostInfo)) error) ([]HostInfo, error) {
+	var (
+		mu    sync.Mutex
+		hosts = make([]HostInfo, 0)
+	)
+	err := stream(ctx, targets, func(h HostInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		hosts = append(hosts, h)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByIP(hosts)
+	return hosts, nil
+}
+
+// runSweep calls send once per address, pacing sends by interval, then
+// waits for late replies until wait has elapsed. Determinate progress is
+// reported throughout. It returns early with ctx.Err() on cancellation.
+func runSweep(ctx context.Context, p progress.Reporter, addrs []netip.Addr, interval, wait time.Duration, send func(seq int, addr netip.Addr) error) error {
+	start := time.Now()
+	total := len(addrs)
+	for i, addr := range addrs {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := send(i, addr); err != nil {
+			return err
+		}
+
+		done := i + 1
+		perProbe := time.Since(start) / time.Duration(done)
+		p.Progress(done, total, perProbe*time.Duration(total-done)+wait)
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+
+	waitUntil := time.Now().Add(wait)
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return nil
+		case <-ticker.C:
+			p.Progress(total, total, time.Until(waitUntil))
+		}
+	}
+}
+
+// sortByIP orders hosts by numeric IP address in place. Hosts without an
+// IP address sort last.
+func sortByIP(hosts []HostInfo) {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		a, b := hosts[i].IP, hosts[j].IP
+		if !a.IsValid() || !b.IsValid() {
+			return a.IsValid()
+		}
+		return a.Less(b)
+	})
+}
+
+// defaultLookupAddr performs reverse DNS lookups with the system resolver.
+func defaultLookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return net.DefaultResolver.LookupAddr(ctx, addr)
+}
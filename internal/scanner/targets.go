@@ -0,0 +1,355 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// minIPv6PrefixBits is the shortest IPv6 prefix accepted as a target.
+// Networks this large cannot be swept; they are discovered by multicast.
+const minIPv6PrefixBits = 64
+
+// Targets is the set of hosts a scan covers. Each entry of Include and
+// Exclude is a target specification in one of these forms:
+//
+//   - a CIDR network, e.g. "192.168.1.0/24" or "fe80::/64"; IPv6 networks
+//     may be no larger than a /64
+//   - a single IP address, e.g. "10.0.0.7"
+//   - an nmap-style IPv4 range, either of the last octet ("10.0.0.1-50")
+//     or between two addresses ("10.0.0.200-10.0.1.20")
+//   - a hostname, e.g. "nas.local"
+type Targets struct {
+	// Include lists the targets to scan
+	Include []string
+
+	// Exclude lists targets to leave out of the scan
+	Exclude []string
+}
+
+// NewTargets returns Targets that include the given specifications.
+func NewTargets(include ...string) Targets {
+	return Targets{Include: include}
+}
+
+// String returns the targets in a human-readable form.
+func (t Targets) String() string {
+	s := strings.Join(t.Include, " ")
+	if len(t.Exclude) > 0 {
+		s += " (excluding " + strings.Join(t.Exclude, " ") + ")"
+	}
+	return s
+}
+
+// Validate checks that at least one target is included and that every
+// specification is well-formed. Hostnames are not resolved.
+func (t Targets) Validate() error {
+	if len(t.Include) == 0 {
+		return fmt.Errorf("no targets specified")
+	}
+	for _, spec := range t.Include {
+		ts, err := parseTargetSpec(spec)
+		if err != nil {
+			return err
+		}
+		if ts.prefix.Addr().Is6() && ts.prefix.Bits() < minIPv6PrefixBits {
+			return fmt.Errorf("IPv6 network %s is too large to scan (the largest is a /%d)", spec, minIPv6PrefixBits)
+		}
+	}
+	for _, spec := range t.Exclude {
+		if _, err := parseTargetSpec(spec); err != nil {
+			return fmt.Errorf("exclude: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadTargetList reads target specifications separated by whitespace or
+// newlines, as in nmap's -iL and --excludefile files. Text following a
+// '#' on a line is a comment.
+func ReadTargetList(r io.Reader) ([]string, error) {
+	var specs []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		specs = append(specs, strings.Fields(line)...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// targetSpec is a parsed target specification. Exactly one of prefix,
+// the first/last range or host is set.
+type targetSpec struct {
+	prefix      netip.Prefix
+	first, last netip.Addr
+	host        string
+}
+
+// parseTargetSpec parses a single target specification.
+func parseTargetSpec(spec string) (targetSpec, error) {
+	if spec == "" {
+		return targetSpec{}, fmt.Errorf("parse target: empty target")
+	}
+
+	if strings.Contains(spec, "/") {
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return targetSpec{}, fmt.Errorf("parse target %s: %w", spec, err)
+		}
+		return targetSpec{prefix: prefix.Masked()}, nil
+	}
+
+	if addr, err := netip.ParseAddr(spec); err == nil {
+		return targetSpec{first: addr, last: addr}, nil
+	}
+
+	if from, to, ok := strings.Cut(spec, "-"); ok && isNumericIPv4(from) {
+		return parseRange(spec, from, to)
+	}
+
+	if isNumericIPv4(spec) || !isHostname(spec) {
+		return targetSpec{}, fmt.Errorf("parse target %s: not an IP address, CIDR, range or hostname", spec)
+	}
+	return targetSpec{host: spec}, nil
+}
+
+// parseRange parses "a.b.c.d-e" and "a.b.c.d-w.x.y.z" IPv4 ranges.
+func parseRange(spec, from, to string) (targetSpec, error) {
+	first, err := netip.ParseAddr(from)
+	if err != nil || !first.Is4() {
+		return targetSpec{}, fmt.Errorf("parse target %s: invalid range start %q", spec, from)
+	}
+
+	var last netip.Addr
+	if octet, err := strconv.ParseUint(to, 10, 8); err == nil {
+		a := first.As4()
+		a[3] = byte(octet)
+		last = netip.AddrFrom4(a)
+	} else if last, err = netip.ParseAddr(to); err != nil || !last.Is4() {
+		return targetSpec{}, fmt.Errorf("parse target %s: invalid range end %q", spec, to)
+	}
+
+	if last.Less(first) {
+		return targetSpec{}, fmt.Errorf("parse target %s: range end is before its start", spec)
+	}
+	return targetSpec{first: first, last: last}, nil
+}
+
+// isNumericIPv4 reports whether s consists only of digits and dots, i.e.
+// looks like an IPv4 address rather than a hostname.
+func isNumericIPv4(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789.") == ""
+}
+
+// isHostname reports whether s is a syntactically valid DNS name.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// contains reports whether addr is covered by the specification. Hostnames
+// must have been resolved into resolved beforehand.
+func (ts targetSpec) contains(addr netip.Addr, resolved map[netip.Addr]bool) bool {
+	switch {
+	case ts.prefix.IsValid():
+		return ts.prefix.Contains(addr)
+	case ts.host != "":
+		return resolved[addr]
+	default:
+		return addr.BitLen() == ts.first.BitLen() && !addr.Less(ts.first) && !ts.last.Less(addr)
+	}
+}
+
+// expandTargets returns every address a native sweep of targets probes, in
+// the order given and without duplicates or excluded addresses. For IPv4
+// networks larger than /31 the network and broadcast addresses are
+// skipped, matching what nmap reports. Hostnames are resolved with lookup.
+//
+// Returns an error if a specification is invalid, a hostname cannot be
+// resolved, or the targets cover more than maxSweepAddrs addresses.
+func expandTargets(ctx context.Context, targets Targets, lookup func(context.Context, string) ([]netip.Addr, error)) ([]netip.Addr, error) {
+	if err := targets.Validate(); err != nil {
+		return nil, err
+	}
+
+	tooLarge := fmt.Errorf("targets %s are too large for a sweep (max %d addresses)", targets, maxSweepAddrs)
+
+	var (
+		addrs []netip.Addr
+		seen  = make(map[netip.Addr]bool)
+	)
+	add := func(addr netip.Addr) error {
+		if seen[addr] {
+			return nil
+		}
+		if len(addrs) >= maxSweepAddrs {
+			return tooLarge
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+		return nil
+	}
+
+	for _, spec := range targets.Include {
+		ts, _ := parseTargetSpec(spec)
+		switch {
+		case ts.prefix.IsValid():
+			hostBits := ts.prefix.Addr().BitLen() - ts.prefix.Bits()
+			if hostBits > maxSweepHostBits {
+				if ts.prefix.Addr().Is6() {
+					return nil, fmt.Errorf("IPv6 network %s is too large for a sweep: it can only be discovered by multicast (ICMP method)", spec)
+				}
+				return nil, tooLarge
+			}
+			first, last := ts.prefix.Addr(), lastAddr(ts.prefix)
+			if first.Is4() && hostBits > 1 {
+				first, last = first.Next(), last.Prev()
+			}
+			for addr := first; addr.IsValid() && !last.Less(addr); addr = addr.Next() {
+				if err := add(addr); err != nil {
+					return nil, err
+				}
+			}
+		case ts.host != "":
+			resolved, err := lookup(ctx, ts.host)
+			if err != nil {
+				return nil, fmt.Errorf("resolve target %s: %w", ts.host, err)
+			}
+			for _, addr := range resolved {
+				if err := add(addr.Unmap()); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			for addr := ts.first; addr.IsValid() && !ts.last.Less(addr); addr = addr.Next() {
+				if err := add(addr); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	excluded, err := newExcluder(ctx, targets.Exclude, lookup)
+	if err != nil {
+		return nil, err
+	}
+	kept := addrs[:0]
+	for _, addr := range addrs {
+		if !excluded(addr) {
+			kept = append(kept, addr)
+		}
+	}
+	return kept, nil
+}
+
+// newExcluder returns a function reporting whether an address is covered
+// by one of the exclude specifications. Hostnames are resolved with lookup
+// up front; IPv6 zones are ignored when matching.
+func newExcluder(ctx context.Context, exclude []string, lookup func(context.Context, string) ([]netip.Addr, error)) (func(netip.Addr) bool, error) {
+	specs := make([]targetSpec, 0, len(exclude))
+	resolved := make(map[netip.Addr]bool)
+	for _, spec := range exclude {
+		ts, err := parseTargetSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+		if ts.host != "" {
+			hostAddrs, err := lookup(ctx, ts.host)
+			if err != nil {
+				return nil, fmt.Errorf("resolve excluded target %s: %w", ts.host, err)
+			}
+			for _, addr := range hostAddrs {
+				resolved[addr.Unmap()] = true
+			}
+		}
+		specs = append(specs, ts)
+	}
+
+	return func(addr netip.Addr) bool {
+		addr = addr.WithZone("")
+		for _, ts := range specs {
+			if ts.contains(addr, resolved) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// splitMulticast separates IPv6 networks too large to sweep from the
+// other included targets. Scanners that support it discover hosts on
+// those networks by multicast instead.
+func splitMulticast(targets Targets) (Targets, []netip.Prefix) {
+	rest := Targets{Exclude: targets.Exclude}
+	var networks []netip.Prefix
+	for _, spec := range targets.Include {
+		ts, err := parseTargetSpec(spec)
+		if err == nil && ts.prefix.Addr().Is6() && ts.prefix.Addr().BitLen()-ts.prefix.Bits() > maxSweepHostBits {
+			networks = append(networks, ts.prefix)
+			continue
+		}
+		rest.Include = append(rest.Include, spec)
+	}
+	return rest, networks
+}
+
+// lastAddr returns the highest address in a masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// rangePrefixes returns the fewest IPv4 networks that together cover the
+// addresses from first to last inclusive, in order.
+func rangePrefixes(first, last netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	lo, hi := uint64(binary.BigEndian.Uint32(first.AsSlice())), uint64(binary.BigEndian.Uint32(last.AsSlice()))
+	for lo <= hi {
+		// Grow the network while it stays aligned and within the range.
+		bits := 32
+		for bits > 0 {
+			size := uint64(1) << (32 - bits + 1)
+			if lo%size != 0 || lo+size-1 > hi {
+				break
+			}
+			bits--
+		}
+		var a [4]byte
+		binary.BigEndian.PutUint32(a[:], uint32(lo))
+		prefixes = append(prefixes, netip.PrefixFrom(netip.AddrFrom4(a), bits))
+		lo += uint64(1) << (32 - bits)
+	}
+	return prefixes
+}
+
+// defaultLookupHost resolves a hostname to its IPv4 addresses with the
+// system resolver.
+func defaultLookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+}
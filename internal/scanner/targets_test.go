@@ -0,0 +1,170 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeLookupHost resolves a fixed table of hostnames.
+func fakeLookupHost(table map[string][]string) func(context.Context, string) ([]netip.Addr, error) {
+	return func(_ context.Context, host string) ([]netip.Addr, error) {
+		addrs, ok := table[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		var result []netip.Addr
+		for _, a := range addrs {
+			result = append(result, netip.MustParseAddr(a))
+		}
+		return result, nil
+	}
+}
+
+func TestTargets_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets Targets
+		wantErr string
+	}{
+		{name: "CIDR", targets: NewTargets("192.168.1.0/24")},
+		{name: "mixed", targets: NewTargets("10.0.0.0/24", "10.0.1.1-50", "10.0.2.7", "nas.local", "fd00::1")},
+		{name: "full range", targets: NewTargets("10.0.0.200-10.0.1.20")},
+		{name: "exclusions", targets: Targets{Include: []string{"10.0.0.0/24"}, Exclude: []string{"10.0.0.1", "printer"}}},
+		{name: "no targets", targets: Targets{}, wantErr: "no targets"},
+		{name: "bad CIDR", targets: NewTargets("10.0.0.0/33"), wantErr: "parse target"},
+		{name: "bad IP", targets: NewTargets("10.0.0.300"), wantErr: "parse target"},
+		{name: "reversed range", targets: NewTargets("10.0.0.50-1"), wantErr: "before its start"},
+		{name: "bad range end", targets: NewTargets("10.0.0.1-300"), wantErr: "invalid range end"},
+		{name: "bad hostname", targets: NewTargets("bad host"), wantErr: "parse target"},
+		{name: "IPv6 /64", targets: NewTargets("fe80::/64", "fd00::/120")},
+		{name: "IPv6 larger than /64", targets: NewTargets("fd00::/48"), wantErr: "too large"},
+		{name: "bad exclusion", targets: Targets{Include: []string{"10.0.0.0/24"}, Exclude: []string{"-x"}}, wantErr: "exclude"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.targets.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTargets_String(t *testing.T) {
+	targets := Targets{Include: []string{"10.0.0.0/24", "nas"}, Exclude: []string{"10.0.0.1"}}
+	if got, want := targets.String(), "10.0.0.0/24 nas (excluding 10.0.0.1)"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
+
+func TestReadTargetList(t *testing.T) {
+	input := "# office printers\n10.0.0.5 10.0.0.6\n\n10.0.1.0/28  # lab\nnas.local\n"
+	got, err := ReadTargetList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadTargetList() error = %v", err)
+	}
+	want := []string{"10.0.0.5", "10.0.0.6", "10.0.1.0/28", "nas.local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadTargetList() = %v; want %v", got, want)
+	}
+}
+
+func TestExpandTargets(t *testing.T) {
+	lookup := fakeLookupHost(map[string][]string{
+		"nas.local": {"10.9.0.1"},
+		"printer":   {"192.168.1.3"},
+	})
+
+	tests := []struct {
+		name      string
+		targets   Targets
+		wantCount int
+		wantFirst string
+		wantLast  string
+	}{
+		{name: "/24 skips network and broadcast", targets: NewTargets("192.168.1.0/24"), wantCount: 254, wantFirst: "192.168.1.1", wantLast: "192.168.1.254"},
+		{name: "/30", targets: NewTargets("192.168.1.77/30"), wantCount: 2, wantFirst: "192.168.1.77", wantLast: "192.168.1.78"},
+		{name: "/32", targets: NewTargets("10.0.0.1/32"), wantCount: 1, wantFirst: "10.0.0.1", wantLast: "10.0.0.1"},
+		{name: "/31", targets: NewTargets("10.0.0.0/31"), wantCount: 2, wantFirst: "10.0.0.0", wantLast: "10.0.0.1"},
+		{name: "octet range", targets: NewTargets("10.0.0.1-50"), wantCount: 50, wantFirst: "10.0.0.1", wantLast: "10.0.0.50"},
+		{name: "address range", targets: NewTargets("10.0.0.250-10.0.1.4"), wantCount: 11, wantFirst: "10.0.0.250", wantLast: "10.0.1.4"},
+		{name: "several targets", targets: NewTargets("10.0.0.7", "nas.local", "10.1.0.0/30"), wantCount: 4, wantFirst: "10.0.0.7", wantLast: "10.1.0.2"},
+		{name: "IPv6 /120", targets: NewTargets("fd00::/120"), wantCount: 256, wantFirst: "fd00::", wantLast: "fd00::ff"},
+		{name: "duplicates removed", targets: NewTargets("10.0.0.1-5", "10.0.0.0/29"), wantCount: 6, wantFirst: "10.0.0.1", wantLast: "10.0.0.6"},
+		{
+			name:      "exclusions",
+			targets:   Targets{Include: []string{"192.168.1.0/24"}, Exclude: []string{"192.168.1.1", "192.168.1.128/25", "192.168.1.10-19", "printer"}},
+			wantCount: 254 - 1 - 127 - 10 - 1,
+			wantFirst: "192.168.1.2",
+			wantLast:  "192.168.1.127",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrs, err := expandTargets(context.Background(), tt.targets, lookup)
+			if err != nil {
+				t.Fatalf("expandTargets() error = %v", err)
+			}
+			if len(addrs) != tt.wantCount {
+				t.Errorf("len = %d; want %d", len(addrs), tt.wantCount)
+			}
+			if addrs[0].String() != tt.wantFirst {
+				t.Errorf("first = %s; want %s", addrs[0], tt.wantFirst)
+			}
+			if addrs[len(addrs)-1].String() != tt.wantLast {
+				t.Errorf("last = %s; want %s", addrs[len(addrs)-1], tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestExpandTargets_Errors(t *testing.T) {
+	lookup := fakeLookupHost(nil)
+
+	tests := []struct {
+		name    string
+		targets Targets
+		wantErr string
+	}{
+		{name: "too large prefix", targets: NewTargets("10.0.0.0/8"), wantErr: "too large"},
+		{name: "too large in total", targets: NewTargets("10.0.0.0/16", "10.1.0.0/16"), wantErr: "too large"},
+		{name: "unresolvable host", targets: NewTargets("nas.local"), wantErr: "resolve target nas.local"},
+		{name: "unresolvable exclusion", targets: Targets{Include: []string{"10.0.0.0/30"}, Exclude: []string{"printer"}}, wantErr: "resolve excluded target printer"},
+		{name: "invalid", targets: NewTargets("not a target"), wantErr: "parse target"},
+		{name: "IPv6 /64 needs multicast", targets: NewTargets("fd00::/64"), wantErr: "multicast"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := expandTargets(context.Background(), tt.targets, lookup)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expandTargets() error = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitMulticast(t *testing.T) {
+	targets := Targets{Include: []string{"fe80::/64", "fd00::/120", "10.0.0.0/24"}, Exclude: []string{"fe80::1"}}
+	rest, networks := splitMulticast(targets)
+
+	wantRest := Targets{Include: []string{"fd00::/120", "10.0.0.0/24"}, Exclude: []string{"fe80::1"}}
+	if !reflect.DeepEqual(rest, wantRest) {
+		t.Errorf("rest = %+v; want %+v", rest, wantRest)
+	}
+	if len(networks) != 1 || networks[0] != netip.MustParsePrefix("fe80::/64") {
+		t.Errorf("networks = %v; want [fe80::/64]", networks)
+	}
+}
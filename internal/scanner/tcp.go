@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"nls/internal/progress"
+)
+
+// DefaultProbePorts are the TCP ports probed when none are configured.
+var DefaultProbePorts = []uint16{22, 80, 443, 3389}
+
+// Default TCP discovery tuning.
+const (
+	DefaultTCPDialTimeout = time.Second
+	DefaultTCPConcurrency = 256
+)
+
+// TCPScanner implements the Scanner interface with plain TCP connect()
+// probes. A host is considered up when any probe port completes the
+// handshake (SYN-ACK) or actively refuses it (RST), which works
+// unprivileged and on routed networks that drop ICMP.
+type TCPScanner struct {
+	progress    progress.Reporter
+	ports       []uint16
+	dialTimeout time.Duration
+	concurrency int
+
+	dial       func(ctx context.Context, network, address string) (net.Conn, error)
+	lookupHost func(context.Context, string) ([]netip.Addr, error)
+	lookupAddr func(context.Context, string) ([]string, error)
+}
+
+// NewTCPScanner creates a new TCPScanner that probes the given ports in
+// order. If ports is empty, DefaultProbePorts is used. If progress reporter
+// is nil, a no-op reporter is used.
+func NewTCPScanner(p progress.Reporter, ports []uint16) *TCPScanner {
+	if p == nil {
+		p = progress.NoOp{}
+	}
+	if len(ports) == 0 {
+		ports = DefaultProbePorts
+	}
+	dialer := &net.Dialer{}
+	return &TCPScanner{
+		progress:    p,
+		ports:       ports,
+		dialTimeout: DefaultTCPDialTimeout,
+		concurrency: DefaultTCPConcurrency,
+		dial:        dialer.DialContext,
+		lookupHost:  defaultLookupHost,
+		lookupAddr:  defaultLookupAddr,
+	}
+}
+
+// Scan probes every address in targets and returns the hosts that
+// answered on at least one port, sorted by IP. The first answering port is
+// recorded in HostInfo.AnsweredPort. The scan respects the provided context
+// for cancellation.
+func (s *TCPScanner) Scan(ctx context.Context, targets Targets) ([]HostInfo, error) {
+	return collectHosts(ctx, targets, s.ScanStream)
+}
+
+// ScanStream performs the same probes as Scan, delivering each host through
+// found as soon as it answers and its hostname has been resolved.
+func (s *TCPScanner) ScanStream(ctx context.Context, targets Targets, found func(HostInfo)) error {
+	s.progress.Start("Scanning network (TCP)...")
+	defer s.progress.Finish()
+
+	addrs, err := expandTargets(ctx, targets, s.lookupHost)
+	if err != nil {
+		return err
+	}
+
+	var (
+		emitter = newHostEmitter(ctx, s.lookupAddr, found)
+		wg      sync.WaitGroup
+		jobs    = make(chan netip.Addr)
+	)
+	for i := 0; i < s.concurrency && i < len(addrs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				port, reason, rtt, ok := s.probe(ctx, addr)
+				if !ok {
+					continue
+				}
+				emitter.emit(HostInfo{
+					IP:           addr,
+					RTT:          rtt,
+					Reason:       reason,
+					AnsweredPort: port,
+				})
+			}
+		}()
+	}
+
+	func() {
+		defer close(jobs)
+		start := time.Now()
+		for i, addr := range addrs {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- addr:
+				done := i + 1
+				perHost := time.Since(start) / time.Duration(done)
+				s.progress.Progress(done, len(addrs), perHost*time.Duration(len(addrs)-done))
+			}
+		}
+	}()
+	wg.Wait()
+	emitter.wait()
+
+	return ctx.Err()
+}
+
+// probe connects to each port of addr in turn and returns the first port
+// that answered with either a completed handshake or a refusal, along with
+// the kind of answer and how long it took.
+func (s *TCPScanner) probe(ctx context.Context, addr netip.Addr) (uint16, string, time.Duration, bool) {
+	for _, port := range s.ports {
+		if ctx.Err() != nil {
+			return 0, "", 0, false
+		}
+
+		dialCtx, cancel := context.WithTimeout(ctx, s.dialTimeout)
+		start := time.Now()
+		conn, err := s.dial(dialCtx, "tcp", net.JoinHostPort(addr.String(), strconv.Itoa(int(port))))
+		rtt := time.Since(start)
+		cancel()
+
+		if err == nil {
+			_ = conn.Close()
+			return port, ReasonSynAck, rtt, true
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return port, ReasonConnRefused, rtt, true
+		}
+	}
+	return 0, "", 0, false
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+// fakeDialer answers connect() probes from a table of "ip:port" outcomes.
+// Unlisted addresses time out.
+func fakeDialer(outcomes map[string]error) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, _, address string) (net.Conn, error) {
+		err, ok := outcomes[address]
+		if !ok {
+			return nil, context.DeadlineExceeded
+		}
+		if err != nil {
+			return nil, err
+		}
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+}
+
+func TestTCPScanner_Scan(t *testing.T) {
+	s := NewTCPScanner(nil, []uint16{22, 443})
+	s.dial = fakeDialer(map[string]error{
+		"10.0.0.2:443": nil,
+		"10.0.0.3:22":  &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED},
+		"10.0.0.4:22":  errors.New("no route to host"),
+	})
+	s.lookupAddr = func(_ context.Context, addr string) ([]string, error) {
+		return nil, fmt.Errorf("no PTR for %s", addr)
+	}
+
+	got, err := s.Scan(context.Background(), NewTargets("10.0.0.0/29"))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []HostInfo{
+		{IP: netip.MustParseAddr("10.0.0.2"), Addresses: parseAddrs("10.0.0.2"), Reason: ReasonSynAck, AnsweredPort: 443},
+		{IP: netip.MustParseAddr("10.0.0.3"), Addresses: parseAddrs("10.0.0.3"), Reason: ReasonConnRefused, AnsweredPort: 22},
+	}
+	clearRTTs(t, got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestTCPScanner_DefaultPorts(t *testing.T) {
+	s := NewTCPScanner(nil, nil)
+	if !reflect.DeepEqual(s.ports, DefaultProbePorts) {
+		t.Errorf("ports = %v; want %v", s.ports, DefaultProbePorts)
+	}
+}
+
+func TestTCPScanner_Scan_ContextCancelled(t *testing.T) {
+	s := NewTCPScanner(nil, nil)
+	s.dial = fakeDialer(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.Scan(ctx, NewTargets("10.0.0.0/24"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Scan() error = %v; want context.Canceled", err)
+	}
+}
@@ -4,12 +4,110 @@
 // MAC addresses, vendor information, and hostnames.
 package scanner
 
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reasons a host was found to be up, as reported in HostInfo.Reason. The
+// values follow nmap's naming; hosts read from nmap may carry others.
+const (
+	ReasonARPResponse = "arp-response"
+	ReasonEchoReply   = "echo-reply"
+	ReasonSynAck      = "syn-ack"
+	ReasonConnRefused = "conn-refused"
+)
+
 // HostInfo represents information about a discovered network host.
-// All string fields use "none" as a sentinel value when information
-// is not available.
+// Information that is not available is left at its zero value: an invalid
+// netip.Addr, a nil MAC and empty strings and lists. Rendering missing
+// values is up to the caller.
 type HostInfo struct {
-	IP       string
-	MAC      string
-	Vendor   string
+	// IP is the primary address, shown in the table
+	IP netip.Addr
+
+	// MAC is the hardware address of the host's interface
+	MAC net.HardwareAddr
+
+	// Vendor is the NIC vendor derived from the MAC address
+	Vendor string
+
+	// Hostname is the primary name, shown in the table
 	Hostname string
+
+	// Addresses lists every IP address reported for the host, IPv4 before
+	// IPv6; the first is IP.
+	Addresses []netip.Addr
+
+	// Hostnames lists every distinct name reported for the host (PTR and
+	// user-supplied); the first is Hostname.
+	Hostnames []string
+
+	// RTT is the round-trip time of the probe the host answered (nmap's
+	// smoothed RTT for nmap scans)
+	RTT time.Duration
+
+	// Reason is how the host answered, e.g. "arp-response" or "echo-reply"
+	Reason string
+
+	// AnsweredPort is the TCP port that answered a connect probe during
+	// TCP discovery. It is zero for other discovery methods.
+	AnsweredPort uint16
+
+	// Ports lists the open ports found by a port scan, ordered by number.
+	// It is empty when no port scan was run.
+	Ports []Port
+
+	// OS is nmap's best operating system match (e.g. "Linux 5.0 - 5.14"),
+	// set only by a deep scan with OS detection
+	OS string
+}
+
+// Port is an open port found on a host.
+type Port struct {
+	// Number is the port number
+	Number uint16
+
+	// Protocol is the transport protocol, "tcp" or "udp"
+	Protocol string
+
+	// Service is the name of the service usually found on the port (e.g.
+	// "ssh"), or "" when unknown
+	Service string
+
+	// Product and Version identify the software answering on the port
+	// (e.g. "OpenSSH", "9.6p1"); only a version scan sets them
+	Product string
+	Version string
+}
+
+// String returns the port in nmap's "22/tcp" form.
+func (p Port) String() string {
+	return strconv.Itoa(int(p.Number)) + "/" + p.Protocol
+}
+
+// IPString returns the primary IP address as text, or "" when unknown.
+func (h HostInfo) IPString() string {
+	if !h.IP.IsValid() {
+		return ""
+	}
+	return h.IP.String()
+}
+
+// MACString returns the MAC address in upper-case, colon-separated form,
+// or "" when unknown.
+func (h HostInfo) MACString() string {
+	return strings.ToUpper(h.MAC.String())
+}
+
+// Key identifies the host across scans: its MAC address when known, since
+// addresses can change, otherwise its IP. It is "" when neither is known.
+func (h HostInfo) Key() string {
+	if h.MAC != nil {
+		return h.MACString()
+	}
+	return h.IPString()
 }
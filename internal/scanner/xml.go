@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Ullaakut/nmap/v3"
+
+	"nls/internal/progress"
+)
+
+// XMLScanner implements the Scanner interface by reading hosts from a saved
+// nmap XML report (nmap -oX) instead of probing the network. Every Scan
+// re-reads the file, so rescans pick up a report that has been replaced.
+type XMLScanner struct {
+	progress progress.Reporter
+	path     string
+}
+
+// NewXMLScanner creates a new XMLScanner for the nmap XML report at path.
+// If progress reporter is nil, a no-op reporter is used.
+func NewXMLScanner(p progress.Reporter, path string) *XMLScanner {
+	if p == nil {
+		p = progress.NoOp{}
+	}
+	return &XMLScanner{
+		progress: p,
+		path:     path,
+	}
+}
+
+// Path returns the report the scanner reads.
+func (s *XMLScanner) Path() string {
+	return s.path
+}
+
+// Scan parses the report and returns its hosts that were up, sorted by IP.
+// The targets are ignored: the report already defines what was scanned.
+//
+// Returns an error if the file cannot be read or is not nmap XML.
+func (s *XMLScanner) Scan(ctx context.Context, _ Targets) ([]HostInfo, error) {
+	s.progress.Start("Reading " + s.path + "...")
+	defer s.progress.Finish()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read nmap XML: %w", err)
+	}
+	var run nmap.Run
+	if err := nmap.Parse(content, &run); err != nil {
+		return nil, fmt.Errorf("parse nmap XML %s: %w", s.path, err)
+	}
+
+	up := run.Hosts[:0]
+	for _, h := range run.Hosts {
+		if h.Status.State == "" || h.Status.State == "up" {
+			up = append(up, h)
+		}
+	}
+	run.Hosts = up
+
+	hosts := extractHostInfo(&run)
+	sortByIP(hosts)
+	return hosts, nil
+}
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testNmapXML = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -sn -oX scan.xml 192.168.1.0/24" start="1700000000" version="7.94">
+<host><status state="up" reason="arp-response"/>
+<address addr="192.168.1.20" addrtype="ipv4"/>
+<address addr="AA:BB:CC:DD:EE:FF" addrtype="mac" vendor="Acme"/>
+</host>
+<host><status state="down" reason="no-response"/>
+<address addr="192.168.1.30" addrtype="ipv4"/>
+</host>
+<host><status state="up" reason="arp-response"/>
+<address addr="192.168.1.1" addrtype="ipv4"/>
+<address addr="00:11:22:33:44:55" addrtype="mac" vendor="Router Co"/>
+<hostnames><hostname name="router.local" type="PTR"/></hostnames>
+</host>
+<runstats><finished time="1700000005" elapsed="5.00"/><hosts up="2" down="1" total="3"/></runstats>
+</nmaprun>
+`
+
+func TestXMLScanner_Scan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.xml")
+	if err := os.WriteFile(path, []byte(testNmapXML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewXMLScanner(nil, path)
+
+	got, err := s.Scan(context.Background(), Targets{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("00:11:22:33:44:55"), Vendor: "Router Co", Hostname: "router.local", Addresses: parseAddrs("192.168.1.1"), Hostnames: []string{"router.local"}, Reason: "arp-response"},
+		{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Acme", Addresses: parseAddrs("192.168.1.20"), Reason: "arp-response"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	// A rescan re-reads the file.
+	if err := os.WriteFile(path, []byte(strings.Replace(testNmapXML, `state="down"`, `state="up"`, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.Scan(context.Background(), Targets{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("rescan found %d hosts; want 3", len(got))
+	}
+}
+
+func TestXMLScanner_Scan_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.xml")
+	if err := os.WriteFile(invalid, []byte("not xml"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.xml"), wantErr: "read nmap XML"},
+		{name: "invalid XML", path: invalid, wantErr: "parse nmap XML"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewXMLScanner(nil, tt.path).Scan(context.Background(), Targets{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Scan() error = %v; want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package tags normalizes the tags that group hosts by role, such as
+// "printer", "prod" or "iot", whether they come from the known-devices
+// file or were set in the TUI.
+package tags
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Parse splits s into tags at commas, semicolons and white space. Tags are
+// lower-cased, and the result is sorted without duplicates; it is nil when
+// s holds no tag.
+func Parse(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	})
+	var tags []string
+	for _, f := range fields {
+		tags = append(tags, strings.ToLower(f))
+	}
+	return Merge(tags)
+}
+
+// Merge returns the tags of every list, sorted without duplicates, or nil
+// when there are none. The lists are not modified.
+func Merge(lists ...[]string) []string {
+	var merged []string
+	for _, l := range lists {
+		merged = append(merged, l...)
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	slices.Sort(merged)
+	return slices.Compact(merged)
+}
+
+// Has reports whether tags holds tag, ignoring case.
+func Has(tags []string, tag string) bool {
+	return slices.Contains(tags, strings.ToLower(tag))
+}
+
+// String renders tags as Parse reads them, separated by spaces.
+func String(tags []string) string {
+	return strings.Join(tags, " ")
+}
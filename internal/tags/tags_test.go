@@ -0,0 +1,43 @@
+package tags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{" , ; ", nil},
+		{"iot", []string{"iot"}},
+		{"Printer, prod;iot  prod", []string{"iot", "printer", "prod"}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := []string{"prod", "db"}
+	got := Merge(a, nil, []string{"prod", "backup"})
+	if want := []string{"backup", "db", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %q; want %q", got, want)
+	}
+	if a[0] != "prod" {
+		t.Error("Merge() modified its input")
+	}
+	if got := Merge(nil, []string{}); got != nil {
+		t.Errorf("Merge() of no tags = %q; want nil", got)
+	}
+}
+
+func TestHas(t *testing.T) {
+	if !Has([]string{"iot", "prod"}, "IoT") || Has([]string{"iot"}, "io") {
+		t.Error("Has() should match whole tags, ignoring case")
+	}
+}
@@ -1,18 +1,23 @@
 package ui
 
 import (
+	"net/netip"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"nls/internal/known"
+	"nls/internal/notes"
 	"nls/internal/scanner"
 )
 
 func TestFilterHosts(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Apple Inc.", Hostname: "macbook.local"},
-		{IP: "192.168.1.10", MAC: "11:22:33:44:55:66", Vendor: "Samsung", Hostname: "phone.local"},
-		{IP: "10.0.0.1", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Router Co", Hostname: "router"},
-		{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Apple Inc.", Hostname: "ipad"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Apple Inc.", Hostname: "macbook.local"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Samsung", Hostname: "phone.local"},
+		{IP: netip.MustParseAddr("10.0.0.1"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Router Co", Hostname: "router"},
+		{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Apple Inc.", Hostname: "ipad"},
 	}
 
 	tests := []struct {
@@ -29,37 +34,37 @@ func TestFilterHosts(t *testing.T) {
 			name:  "filter by IP",
 			query: "10.0.0",
 			expected: []scanner.HostInfo{
-				{IP: "10.0.0.1", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Router Co", Hostname: "router"},
+				{IP: netip.MustParseAddr("10.0.0.1"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Router Co", Hostname: "router"},
 			},
 		},
 		{
 			name:  "filter by MAC",
 			query: "aa:bb:cc",
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Apple Inc.", Hostname: "macbook.local"},
+				{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Apple Inc.", Hostname: "macbook.local"},
 			},
 		},
 		{
 			name:  "filter by Vendor",
 			query: "apple",
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Apple Inc.", Hostname: "macbook.local"},
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Apple Inc.", Hostname: "ipad"},
+				{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Apple Inc.", Hostname: "macbook.local"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Apple Inc.", Hostname: "ipad"},
 			},
 		},
 		{
 			name:  "filter by Hostname",
 			query: "router",
 			expected: []scanner.HostInfo{
-				{IP: "10.0.0.1", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Router Co", Hostname: "router"},
+				{IP: netip.MustParseAddr("10.0.0.1"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Router Co", Hostname: "router"},
 			},
 		},
 		{
 			name:  "case insensitive search",
 			query: "APPLE",
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Apple Inc.", Hostname: "macbook.local"},
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Apple Inc.", Hostname: "ipad"},
+				{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Apple Inc.", Hostname: "macbook.local"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Apple Inc.", Hostname: "ipad"},
 			},
 		},
 		{
@@ -71,7 +76,7 @@ func TestFilterHosts(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := filterHosts(hosts, tt.query)
+			result := filterHosts(hosts, mustParseQuery(tt.query), hostMarks{})
 
 			if !reflect.DeepEqual(result, tt.expected) {
 				t.Errorf("filterHosts() mismatch:\ngot:  %+v\nwant: %+v", result, tt.expected)
@@ -80,11 +85,149 @@ func TestFilterHosts(t *testing.T) {
 	}
 }
 
+func TestFilterHosts_SecondaryAddressesAndHostnames(t *testing.T) {
+	dualStack := scanner.HostInfo{
+		IP: netip.MustParseAddr("192.168.1.5"), Hostname: "nas.local",
+		Addresses: parseAddrs("192.168.1.5", "fd00::5"),
+		Hostnames: []string{"nas.local", "Backup.lan"},
+	}
+	hosts := []scanner.HostInfo{
+		dualStack,
+		{IP: netip.MustParseAddr("192.168.1.6")},
+	}
+
+	for _, query := range []string{"fd00::5", "backup"} {
+		got := filterHosts(hosts, mustParseQuery(query), hostMarks{})
+		if !reflect.DeepEqual(got, []scanner.HostInfo{dualStack}) {
+			t.Errorf("filterHosts(%q) = %+v; want only the dual-stack host", query, got)
+		}
+	}
+}
+
+func TestFilterHosts_Ports(t *testing.T) {
+	sshHost := scanner.HostInfo{
+		IP:    netip.MustParseAddr("192.168.1.5"),
+		Ports: []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh"}, {Number: 443, Protocol: "tcp", Service: "https"}},
+	}
+	altHost := scanner.HostInfo{
+		IP:    netip.MustParseAddr("192.168.1.6"),
+		Ports: []scanner.Port{{Number: 8022, Protocol: "tcp"}},
+	}
+	hosts := []scanner.HostInfo{sshHost, altHost}
+
+	tests := []struct {
+		query string
+		want  []scanner.HostInfo
+	}{
+		{query: "22", want: []scanner.HostInfo{sshHost}},
+		{query: "22/tcp", want: []scanner.HostInfo{sshHost}},
+		{query: "SSH", want: []scanner.HostInfo{sshHost}},
+		{query: "8022", want: []scanner.HostInfo{altHost}},
+		{query: "3389", want: []scanner.HostInfo{}},
+	}
+
+	for _, tt := range tests {
+		if got := filterHosts(hosts, mustParseQuery(tt.query), hostMarks{}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterHosts(%q) = %+v; want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterHosts_Tags(t *testing.T) {
+	list, err := known.Read(strings.NewReader("AA:BB:CC:DD:EE:01, Printer,,, printer iot\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	printer := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:BB:CC:DD:EE:01")}
+	camera := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.6"), Hostname: "iot-cam"}
+	store := notes.NewMemory()
+	if err := store.Set(notes.Entry{Key: "192.168.1.6", Tags: []string{"camera", "iot"}}); err != nil {
+		t.Fatal(err)
+	}
+	hosts := []scanner.HostInfo{printer, camera}
+	marks := hostMarks{known: list, notes: store}
+
+	tests := []struct {
+		query string
+		want  []scanner.HostInfo
+	}{
+		{query: "tag:iot", want: []scanner.HostInfo{printer, camera}},
+		{query: "tag:Printer", want: []scanner.HostInfo{printer}},
+		{query: "tag:io", want: []scanner.HostInfo{}},
+		{query: "camera", want: []scanner.HostInfo{camera}},
+	}
+	for _, tt := range tests {
+		if got := filterHosts(hosts, mustParseQuery(tt.query), marks); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterHosts(%q) = %+v; want %+v", tt.query, got, tt.want)
+		}
+	}
+
+	rows := buildRows(hosts, marks, colTags)
+	if rows[0][4] != "iot printer" || rows[1][4] != "camera iot" {
+		t.Errorf("Tags column = %q, %q", rows[0][4], rows[1][4])
+	}
+}
+
+func TestFilterHosts_UnknownValuesNeverMatch(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.5")},
+		{IP: netip.MustParseAddr("192.168.1.6"), Hostname: "nonesuch.local"},
+	}
+
+	got := filterHosts(hosts, mustParseQuery("none"), hostMarks{})
+	if len(got) != 1 || got[0].Hostname != "nonesuch.local" {
+		t.Errorf("filterHosts(\"none\") = %+v; want only the host named nonesuch.local", got)
+	}
+	if got := filterHosts(hosts, mustParseQuery(placeholder), hostMarks{}); len(got) != 0 {
+		t.Errorf("filterHosts(%q) = %+v; the placeholder should not match missing data", placeholder, got)
+	}
+}
+
+func TestSortHosts_UnknownValuesLast(t *testing.T) {
+	known := scanner.HostInfo{IP: netip.MustParseAddr("10.0.0.9"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Acme", Hostname: "known.lan", RTT: time.Millisecond, Reason: "echo-reply"}
+	unknown := scanner.HostInfo{}
+	hosts := []scanner.HostInfo{unknown, known}
+
+	for col := colIP; col <= colReason; col++ {
+		for _, ascending := range []bool{true, false} {
+			got := sortHosts(hosts, col, ascending)
+			if !reflect.DeepEqual(got[0], known) {
+				t.Errorf("sortHosts(col %d, ascending %v) put the host with unknown values first", col, ascending)
+			}
+		}
+	}
+}
+
+func TestSortHosts_RTTNumeric(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("10.0.0.1"), RTT: 10 * time.Millisecond},
+		{IP: netip.MustParseAddr("10.0.0.2"), RTT: 900 * time.Microsecond},
+		{IP: netip.MustParseAddr("10.0.0.3"), RTT: 2 * time.Millisecond},
+	}
+
+	tests := []struct {
+		ascending bool
+		want      []string
+	}{
+		{ascending: true, want: []string{"10.0.0.2", "10.0.0.3", "10.0.0.1"}},
+		{ascending: false, want: []string{"10.0.0.1", "10.0.0.3", "10.0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		got := sortHosts(hosts, colRTT, tt.ascending)
+		for i, want := range tt.want {
+			if got[i].IP.String() != want {
+				t.Errorf("sortHosts(RTT, ascending %v)[%d] = %s; want %s", tt.ascending, i, got[i].IP, want)
+			}
+		}
+	}
+}
+
 func TestSortHosts(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"},
-		{IP: "192.168.1.5", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Apple", Hostname: "device1"},
-		{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Samsung", Hostname: "device2"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"},
+		{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Apple", Hostname: "device1"},
+		{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Samsung", Hostname: "device2"},
 	}
 
 	tests := []struct {
@@ -104,9 +247,9 @@ func TestSortHosts(t *testing.T) {
 			col:       1,
 			ascending: true,
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.5", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Apple", Hostname: "device1"},
-				{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"},
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Samsung", Hostname: "device2"},
+				{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Apple", Hostname: "device1"},
+				{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Samsung", Hostname: "device2"},
 			},
 		},
 		{
@@ -114,9 +257,9 @@ func TestSortHosts(t *testing.T) {
 			col:       1,
 			ascending: false,
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Samsung", Hostname: "device2"},
-				{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"},
-				{IP: "192.168.1.5", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Apple", Hostname: "device1"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Samsung", Hostname: "device2"},
+				{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"},
+				{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Apple", Hostname: "device1"},
 			},
 		},
 		{
@@ -124,9 +267,9 @@ func TestSortHosts(t *testing.T) {
 			col:       2,
 			ascending: true,
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.5", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Apple", Hostname: "device1"},
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Samsung", Hostname: "device2"},
-				{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"},
+				{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Apple", Hostname: "device1"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Samsung", Hostname: "device2"},
+				{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"},
 			},
 		},
 		{
@@ -134,9 +277,9 @@ func TestSortHosts(t *testing.T) {
 			col:       3,
 			ascending: true,
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.5", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Apple", Hostname: "device1"},
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Samsung", Hostname: "device2"},
-				{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"},
+				{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Apple", Hostname: "device1"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Samsung", Hostname: "device2"},
+				{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"},
 			},
 		},
 		{
@@ -144,9 +287,9 @@ func TestSortHosts(t *testing.T) {
 			col:       4,
 			ascending: false,
 			expected: []scanner.HostInfo{
-				{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"},
-				{IP: "192.168.1.20", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Samsung", Hostname: "device2"},
-				{IP: "192.168.1.5", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Apple", Hostname: "device1"},
+				{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"},
+				{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Samsung", Hostname: "device2"},
+				{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Apple", Hostname: "device1"},
 			},
 		},
 	}
@@ -160,7 +303,7 @@ func TestSortHosts(t *testing.T) {
 			}
 
 			// Verify original is unchanged
-			if !reflect.DeepEqual(hosts[0], scanner.HostInfo{IP: "192.168.1.10", MAC: "CC:CC:CC:CC:CC:CC", Vendor: "Zebra", Hostname: "device3"}) {
+			if !reflect.DeepEqual(hosts[0], scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("CC:CC:CC:CC:CC:CC"), Vendor: "Zebra", Hostname: "device3"}) {
 				t.Error("sortHosts modified original slice")
 			}
 		})
@@ -172,7 +315,7 @@ func TestCompareIPs(t *testing.T) {
 		name     string
 		ip1      string
 		ip2      string
-		expected bool // true if ip1 < ip2
+		expected bool // true if ip1 < ip2; "" is an unknown address
 	}{
 		{
 			name:     "simple less than",
@@ -205,28 +348,58 @@ func TestCompareIPs(t *testing.T) {
 			expected: true, // 9 < 10 numerically (but "9" > "10" as strings)
 		},
 		{
-			name:     "none value sorts last",
-			ip1:      "none",
+			name:     "unknown sorts last",
+			ip1:      "",
 			ip2:      "192.168.1.1",
 			expected: false,
 		},
 		{
-			name:     "IP beats none",
+			name:     "IP beats unknown",
 			ip1:      "192.168.1.1",
-			ip2:      "none",
+			ip2:      "",
 			expected: true,
 		},
 		{
-			name:     "both none",
-			ip1:      "none",
-			ip2:      "none",
+			name:     "both unknown",
+			ip1:      "",
+			ip2:      "",
 			expected: false,
 		},
+		{
+			name:     "IPv6 numeric comparison",
+			ip1:      "fd00::9",
+			ip2:      "fd00::10",
+			expected: true, // 0x9 < 0x10 (but "9" > "1" as strings)
+		},
+		{
+			name:     "IPv6 compressed forms",
+			ip1:      "fd00::ff",
+			ip2:      "fd00:0:0:0:0:0:1:0",
+			expected: true,
+		},
+		{
+			name:     "IPv4 before IPv6",
+			ip1:      "fe80::1",
+			ip2:      "10.0.0.1",
+			expected: false,
+		},
+		{
+			name:     "link-local zones",
+			ip1:      "fe80::1%eth0",
+			ip2:      "fe80::1%wlan0",
+			expected: true,
+		},
+		{
+			name:     "IPv6 beats unknown",
+			ip1:      "fe80::1%eth0",
+			ip2:      "",
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := compareIPs(tt.ip1, tt.ip2)
+			result := compareIPs(parseAddr(tt.ip1), parseAddr(tt.ip2))
 
 			if result != tt.expected {
 				t.Errorf("compareIPs(%q, %q) = %v; want %v", tt.ip1, tt.ip2, result, tt.expected)
@@ -289,11 +462,11 @@ func TestBuildColumns_WithSortIndicator(t *testing.T) {
 
 func TestUpdateModel_RebuildTable(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Vendor A", Hostname: "host1"},
-		{IP: "192.168.1.5", MAC: "BB:BB:BB:BB:BB:BB", Vendor: "Vendor B", Hostname: "host2"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Vendor A", Hostname: "host1"},
+		{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("BB:BB:BB:BB:BB:BB"), Vendor: "Vendor B", Hostname: "host2"},
 	}
 
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.sortColumn = 1
 	model.sortAscending = true
 
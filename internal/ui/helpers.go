@@ -1,34 +1,86 @@
 package ui
 
 import (
+	"bytes"
+	"net/netip"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 	"golang.org/x/term"
 
+	"nls/internal/diff"
+	"nls/internal/known"
+	"nls/internal/notes"
+	"nls/internal/query"
 	"nls/internal/scanner"
+	"nls/internal/tags"
+)
+
+// placeholder is shown in place of values that are not known.
+const placeholder = "-"
+
+// orPlaceholder returns s, or the placeholder when s is empty.
+func orPlaceholder(s string) string {
+	if s == "" {
+		return placeholder
+	}
+	return s
+}
+
+// Table columns, numbered as the keys that sort by them. colIP through
+// colHostname are always shown; the others are optional.
+const (
+	colIP = 1 + iota
+	colMAC
+	colVendor
+	colHostname
+	colRTT
+	colReason
+	colPorts
+	colChange
+	colLabel
+	colAlias
+	colTags
 )
 
 // ColumnWeights defines the proportional width allocation for table columns.
-// Values represent the percentage of available width each column should occupy.
+// Values represent the share of available width each column should occupy;
+// the weights of the shown columns are scaled to fill the table.
 type ColumnWeights struct {
 	IP       float64
 	MAC      float64
 	Vendor   float64
 	Hostname float64
+	RTT      float64
+	Reason   float64
+	Ports    float64
+	Change   float64
+	Label    float64
+	Alias    float64
+	Tags     float64
 }
 
 // DefaultColumnWeights returns the standard column width distribution.
 // IP gets 20%, while MAC, Vendor, and Hostname each get approximately 26.67%.
+// The optional RTT, Reason, Ports, Change, Label, Alias and Tags columns
+// take their share on top when shown.
 func DefaultColumnWeights() ColumnWeights {
 	return ColumnWeights{
 		IP:       0.20,
 		MAC:      0.27,
 		Vendor:   0.26,
 		Hostname: 0.27,
+		RTT:      0.12,
+		Reason:   0.16,
+		Ports:    0.20,
+		Change:   0.12,
+		Label:    0.22,
+		Alias:    0.18,
+		Tags:     0.18,
 	}
 }
 
@@ -58,15 +110,47 @@ func getTerminalSize() (width, height int) {
 }
 
 // buildColumns creates table column definitions based on terminal width.
-// Columns are proportionally sized using the provided weights.
+// Columns are proportionally sized using the provided weights, and the
+// optional columns (colRTT, colReason, colPorts, colChange, colLabel,
+// colAlias, colTags) are appended in the order given.
 // If sortCol > 0, adds a sort indicator (↑/↓) to the sorted column's title.
-func buildColumns(width int, weights ColumnWeights, sortCol int, ascending bool) []table.Column {
+func buildColumns(width int, weights ColumnWeights, sortCol int, ascending bool, optional ...int) []table.Column {
 	remaining := width - TablePaddingWidth
 
-	ipWidth := int(float64(remaining) * weights.IP)
-	macWidth := int(float64(remaining) * weights.MAC)
-	vendorWidth := int(float64(remaining) * weights.Vendor)
-	hostnameWidth := int(float64(remaining) * weights.Hostname)
+	type spec struct {
+		col    int
+		title  string
+		weight float64
+	}
+	specs := []spec{
+		{colIP, "IP", weights.IP},
+		{colMAC, "MAC", weights.MAC},
+		{colVendor, "Vendor", weights.Vendor},
+		{colHostname, "Hostname", weights.Hostname},
+	}
+	for _, col := range optional {
+		switch col {
+		case colRTT:
+			specs = append(specs, spec{colRTT, "RTT", weights.RTT})
+		case colReason:
+			specs = append(specs, spec{colReason, "Reason", weights.Reason})
+		case colPorts:
+			specs = append(specs, spec{colPorts, "Ports", weights.Ports})
+		case colChange:
+			specs = append(specs, spec{colChange, "Change", weights.Change})
+		case colLabel:
+			specs = append(specs, spec{colLabel, "Label", weights.Label})
+		case colAlias:
+			specs = append(specs, spec{colAlias, "Alias", weights.Alias})
+		case colTags:
+			specs = append(specs, spec{colTags, "Tags", weights.Tags})
+		}
+	}
+
+	total := 0.0
+	for _, sp := range specs {
+		total += sp.weight
+	}
 
 	// Helper to add sort indicator
 	addSortIndicator := func(title string, col int) string {
@@ -79,57 +163,252 @@ func buildColumns(width int, weights ColumnWeights, sortCol int, ascending bool)
 		return title
 	}
 
-	return []table.Column{
-		{Title: addSortIndicator("IP", 1), Width: ipWidth},
-		{Title: addSortIndicator("MAC", 2), Width: macWidth},
-		{Title: addSortIndicator("Vendor", 3), Width: vendorWidth},
-		{Title: addSortIndicator("Hostname", 4), Width: hostnameWidth},
+	columns := make([]table.Column, 0, len(specs))
+	for _, sp := range specs {
+		columns = append(columns, table.Column{
+			Title: addSortIndicator(sp.title, sp.col),
+			Width: int(float64(remaining) * sp.weight / total),
+		})
+	}
+	return columns
+}
+
+// hostMarks is what the table knows about hosts besides the scan results.
+type hostMarks struct {
+	// changes holds how hosts differ from the previous scan, by
+	// HostInfo.Key
+	changes map[string]diff.Change
+
+	// known labels the listed devices and flags the others
+	known *known.List
+
+	// notes holds the aliases, notes and tags users wrote (may be nil)
+	notes notes.Store
+}
+
+// entry returns the alias and note written about h, if any.
+func (hm hostMarks) entry(h scanner.HostInfo) notes.Entry {
+	if hm.notes == nil {
+		return notes.Entry{}
 	}
+	e, _ := hm.notes.Lookup(h.Key())
+	return e
+}
+
+// tags returns the tags of h, from the known-devices list and the notes.
+func (hm hostMarks) tags(h scanner.HostInfo) []string {
+	d, _ := hm.known.Lookup(h)
+	return tags.Merge(d.Tags, hm.entry(h).Tags)
+}
+
+// subject returns h with what marks know about it, for matching queries.
+func (hm hostMarks) subject(h scanner.HostInfo) query.Subject {
+	e := hm.entry(h)
+	return query.Subject{HostInfo: h, Label: hm.label(h), Alias: e.Alias, Note: e.Note, Tags: hm.tags(h)}
 }
 
-// buildRows converts a slice of HostInfo into table rows.
+// label returns the label of h in the known-devices list, or "".
+func (hm hostMarks) label(h scanner.HostInfo) string {
+	d, _ := hm.known.Lookup(h)
+	return d.Label
+}
+
+// buildRows converts a slice of HostInfo into table rows, with a cell for
+// each optional column given, as passed to buildColumns. The Change column
+// shows how each host differs from the previous scan, the Label column its
+// label in the known-devices list, the Alias column the alias the user
+// gave it and the Tags column its tags, according to marks.
 // Returns a single "No hosts found" row if the input is empty.
-func buildRows(hosts []scanner.HostInfo) []table.Row {
+func buildRows(hosts []scanner.HostInfo, marks hostMarks, optional ...int) []table.Row {
 	if len(hosts) == 0 {
-		return []table.Row{{"No hosts found", "-", "-", "-"}}
+		row := table.Row{"No hosts found", placeholder, placeholder, placeholder}
+		for range optional {
+			row = append(row, placeholder)
+		}
+		return []table.Row{row}
 	}
 
 	rows := make([]table.Row, 0, len(hosts))
 	for _, h := range hosts {
-		rows = append(rows, table.Row{
-			h.IP,
-			h.MAC,
-			h.Vendor,
-			h.Hostname,
-		})
+		row := table.Row{
+			orPlaceholder(h.IPString()),
+			orPlaceholder(h.MACString()),
+			orPlaceholder(h.Vendor),
+			orPlaceholder(h.Hostname),
+		}
+		for _, col := range optional {
+			switch col {
+			case colRTT:
+				row = append(row, orPlaceholder(formatRTT(h.RTT)))
+			case colReason:
+				row = append(row, orPlaceholder(h.Reason))
+			case colPorts:
+				row = append(row, orPlaceholder(formatPorts(h.Ports)))
+			case colChange:
+				row = append(row, changeLabel(marks.changes[h.Key()].Kind))
+			case colLabel:
+				row = append(row, knownLabel(marks.known, h))
+			case colAlias:
+				row = append(row, orPlaceholder(marks.entry(h).Alias))
+			case colTags:
+				row = append(row, orPlaceholder(tags.String(marks.tags(h))))
+			}
+		}
+		rows = append(rows, row)
 	}
 	return rows
 }
 
-// filterHosts returns a filtered slice of hosts matching the search query.
-// The query is matched case-insensitively against IP, MAC, Vendor, and Hostname fields.
-func filterHosts(hosts []scanner.HostInfo, query string) []scanner.HostInfo {
-	if query == "" {
-		return hosts
+// Labels of the Change column.
+const (
+	labelNew     = "+ new"
+	labelGone    = "− gone"
+	labelChanged = "~ changed"
+)
+
+// changeLabel returns the Change column label of k, or "" for hosts that
+// did not change.
+func changeLabel(k diff.Kind) string {
+	switch k {
+	case diff.Added:
+		return labelNew
+	case diff.Removed:
+		return labelGone
+	case diff.Changed:
+		return labelChanged
+	default:
+		return ""
 	}
+}
 
-	query = strings.ToLower(query)
-	filtered := make([]scanner.HostInfo, 0)
+// labelUnknown is the Label column of hosts missing from the known-devices
+// list.
+const labelUnknown = "? unknown"
 
+// knownLabel returns the Label column of h: its label in list, or
+// labelUnknown.
+func knownLabel(list *known.List, h scanner.HostInfo) string {
+	d, ok := list.Lookup(h)
+	if !ok {
+		return labelUnknown
+	}
+	return d.Label
+}
+
+// changedHosts returns the hosts in changes.
+func changedHosts(hosts []scanner.HostInfo, changes map[string]diff.Change) []scanner.HostInfo {
+	filtered := make([]scanner.HostInfo, 0)
 	for _, h := range hosts {
-		if strings.Contains(strings.ToLower(h.IP), query) ||
-			strings.Contains(strings.ToLower(h.MAC), query) ||
-			strings.Contains(strings.ToLower(h.Vendor), query) ||
-			strings.Contains(strings.ToLower(h.Hostname), query) {
+		if _, ok := changes[h.Key()]; ok {
 			filtered = append(filtered, h)
 		}
 	}
+	return filtered
+}
+
+// formatRTT renders a round-trip time to a useful precision: whole
+// microseconds below a millisecond, tenths of a millisecond below a second
+// and milliseconds above. Zero (unknown) gives "".
+func formatRTT(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return ""
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+// formatInterval renders an interval without trailing zero units: "5m"
+// rather than "5m0s", "1h" rather than "1h0m0s".
+func formatInterval(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
 
+// formatPorts renders open ports compactly as "22,80,443".
+func formatPorts(ports []scanner.Port) string {
+	numbers := make([]string, 0, len(ports))
+	for _, p := range ports {
+		numbers = append(numbers, strconv.Itoa(int(p.Number)))
+	}
+	return strings.Join(numbers, ",")
+}
+
+// describeService joins the known service name, product and version of p.
+func describeService(p scanner.Port) string {
+	var parts []string
+	for _, s := range []string{p.Service, p.Product, p.Version} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// hasAliases reports whether any of hosts has an alias in marks.
+func hasAliases(hosts []scanner.HostInfo, marks hostMarks) bool {
+	for _, h := range hosts {
+		if marks.entry(h).Alias != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTags reports whether any of hosts has tags in marks.
+func hasTags(hosts []scanner.HostInfo, marks hostMarks) bool {
+	for _, h := range hosts {
+		if len(marks.tags(h)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPorts reports whether any of hosts has open ports from a port scan.
+func hasPorts(hosts []scanner.HostInfo) bool {
+	for _, h := range hosts {
+		if len(h.Ports) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// filterHosts returns the hosts matching q, which sees each host with its
+// label, alias, note and tags in marks (see query.Parse).
+func filterHosts(hosts []scanner.HostInfo, q query.Query, marks hostMarks) []scanner.HostInfo {
+	filtered := make([]scanner.HostInfo, 0, len(hosts))
+	for _, h := range hosts {
+		if q.Match(marks.subject(h)) {
+			filtered = append(filtered, h)
+		}
+	}
 	return filtered
 }
 
+// addrStrings returns the text form of each address.
+func addrStrings(addrs []netip.Addr) []string {
+	ss := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ss = append(ss, addr.String())
+	}
+	return ss
+}
+
 // sortHosts returns a sorted copy of hosts based on the specified column.
-// col: 1=IP, 2=MAC, 3=Vendor, 4=Hostname
+// col: 1=IP, 2=MAC, 3=Vendor, 4=Hostname, 5=RTT (numerically), 6=Reason.
+// Hosts missing the value sort last in either direction.
 func sortHosts(hosts []scanner.HostInfo, col int, ascending bool) []scanner.HostInfo {
 	if col == 0 || len(hosts) == 0 {
 		return hosts
@@ -139,17 +418,40 @@ func sortHosts(hosts []scanner.HostInfo, col int, ascending bool) []scanner.Host
 	sorted := make([]scanner.HostInfo, len(hosts))
 	copy(sorted, hosts)
 
-	sort.Slice(sorted, func(i, j int) bool {
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
 		var less bool
 		switch col {
-		case 1: // IP - compare numerically
-			less = compareIPs(sorted[i].IP, sorted[j].IP)
-		case 2: // MAC
-			less = strings.Compare(sorted[i].MAC, sorted[j].MAC) < 0
-		case 3: // Vendor
-			less = strings.Compare(sorted[i].Vendor, sorted[j].Vendor) < 0
-		case 4: // Hostname
-			less = strings.Compare(sorted[i].Hostname, sorted[j].Hostname) < 0
+		case colIP: // compare numerically
+			if !a.IP.IsValid() || !b.IP.IsValid() {
+				return a.IP.IsValid()
+			}
+			less = compareIPs(a.IP, b.IP)
+		case colMAC:
+			if a.MAC == nil || b.MAC == nil {
+				return a.MAC != nil
+			}
+			less = bytes.Compare(a.MAC, b.MAC) < 0
+		case colVendor:
+			if a.Vendor == "" || b.Vendor == "" {
+				return a.Vendor != ""
+			}
+			less = strings.Compare(a.Vendor, b.Vendor) < 0
+		case colHostname:
+			if a.Hostname == "" || b.Hostname == "" {
+				return a.Hostname != ""
+			}
+			less = strings.Compare(a.Hostname, b.Hostname) < 0
+		case colRTT: // compare durations, not their text
+			if a.RTT <= 0 || b.RTT <= 0 {
+				return a.RTT > 0
+			}
+			less = a.RTT < b.RTT
+		case colReason:
+			if a.Reason == "" || b.Reason == "" {
+				return a.Reason != ""
+			}
+			less = strings.Compare(a.Reason, b.Reason) < 0
 		default:
 			return false
 		}
@@ -164,38 +466,13 @@ func sortHosts(hosts []scanner.HostInfo, col int, ascending bool) []scanner.Host
 }
 
 // compareIPs compares two IP addresses numerically.
+// IPv4 addresses sort before IPv6 addresses, and link-local IPv6 addresses
+// with the same address are ordered by zone. Unknown (invalid) addresses
+// sort last.
 // Returns true if ip1 < ip2.
-func compareIPs(ip1, ip2 string) bool {
-	// Handle "none" sentinel value
-	if ip1 == "none" && ip2 == "none" {
-		return false
-	}
-	if ip1 == "none" {
-		return false // "none" sorts last
-	}
-	if ip2 == "none" {
-		return true
-	}
-
-	// Split into octets
-	parts1 := strings.Split(ip1, ".")
-	parts2 := strings.Split(ip2, ".")
-
-	// Compare each octet numerically
-	for i := 0; i < 4 && i < len(parts1) && i < len(parts2); i++ {
-		num1, err1 := strconv.Atoi(parts1[i])
-		num2, err2 := strconv.Atoi(parts2[i])
-
-		if err1 != nil || err2 != nil {
-			// Fall back to string comparison if not valid numbers
-			return strings.Compare(ip1, ip2) < 0
-		}
-
-		if num1 != num2 {
-			return num1 < num2
-		}
+func compareIPs(ip1, ip2 netip.Addr) bool {
+	if !ip1.IsValid() || !ip2.IsValid() {
+		return ip1.IsValid()
 	}
-
-	// If all octets equal, IPs are equal
-	return false
+	return ip1.Less(ip2)
 }
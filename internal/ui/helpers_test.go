@@ -1,14 +1,53 @@
 package ui
 
 import (
+	"net"
+	"net/netip"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 
+	"nls/internal/query"
 	"nls/internal/scanner"
 )
 
+// mustParseMAC parses a MAC address, panicking on error.
+func mustParseMAC(s string) net.HardwareAddr {
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		panic(err)
+	}
+	return mac
+}
+
+// mustParseQuery parses a search query, panicking on error.
+func mustParseQuery(s string) query.Query {
+	q, err := query.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// parseAddr parses an IP address; "" gives the invalid (unknown) address.
+func parseAddr(s string) netip.Addr {
+	if s == "" {
+		return netip.Addr{}
+	}
+	return netip.MustParseAddr(s)
+}
+
+// parseAddrs parses a list of IP addresses, panicking on error.
+func parseAddrs(ss ...string) []netip.Addr {
+	addrs := make([]netip.Addr, 0, len(ss))
+	for _, s := range ss {
+		addrs = append(addrs, netip.MustParseAddr(s))
+	}
+	return addrs
+}
+
 func TestBuildColumns_DefaultWeights(t *testing.T) {
 	weights := DefaultColumnWeights()
 
@@ -130,8 +169,8 @@ func TestBuildRows(t *testing.T) {
 			name: "single host",
 			hosts: []scanner.HostInfo{
 				{
-					IP:       "192.168.1.10",
-					MAC:      "AA:BB:CC:DD:EE:FF",
+					IP:       netip.MustParseAddr("192.168.1.10"),
+					MAC:      mustParseMAC("AA:BB:CC:DD:EE:FF"),
 					Vendor:   "Apple Inc.",
 					Hostname: "macbook.local",
 				},
@@ -144,14 +183,14 @@ func TestBuildRows(t *testing.T) {
 			name: "multiple hosts",
 			hosts: []scanner.HostInfo{
 				{
-					IP:       "192.168.1.1",
-					MAC:      "00:11:22:33:44:55",
+					IP:       netip.MustParseAddr("192.168.1.1"),
+					MAC:      mustParseMAC("00:11:22:33:44:55"),
 					Vendor:   "Router Co",
 					Hostname: "router.local",
 				},
 				{
-					IP:       "192.168.1.2",
-					MAC:      "AA:BB:CC:DD:EE:00",
+					IP:       netip.MustParseAddr("192.168.1.2"),
+					MAC:      mustParseMAC("AA:BB:CC:DD:EE:00"),
 					Vendor:   "Device Inc",
 					Hostname: "device.local",
 				},
@@ -169,24 +208,21 @@ func TestBuildRows(t *testing.T) {
 			},
 		},
 		{
-			name: "hosts with 'none' values",
+			name: "hosts with unknown values",
 			hosts: []scanner.HostInfo{
-				{
-					IP:       "192.168.1.100",
-					MAC:      "none",
-					Vendor:   "none",
-					Hostname: "none",
-				},
+				{IP: netip.MustParseAddr("192.168.1.100")},
+				{MAC: mustParseMAC("AA:BB:CC:DD:EE:01"), Hostname: "unaddressed.local"},
 			},
 			want: []table.Row{
-				{"192.168.1.100", "none", "none", "none"},
+				{"192.168.1.100", "-", "-", "-"},
+				{"-", "AA:BB:CC:DD:EE:01", "-", "unaddressed.local"},
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := buildRows(tt.hosts)
+			got := buildRows(tt.hosts, hostMarks{})
 
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("buildRows() mismatch:\ngot:  %+v\nwant: %+v", got, tt.want)
@@ -195,18 +231,91 @@ func TestBuildRows(t *testing.T) {
 	}
 }
 
+func TestBuildColumnsAndRows_Optional(t *testing.T) {
+	optional := []int{colRTT, colReason}
+
+	columns := buildColumns(100, DefaultColumnWeights(), colRTT, true, optional...)
+	var titles []string
+	for _, c := range columns {
+		titles = append(titles, c.Title)
+	}
+	if want := []string{"IP", "MAC", "Vendor", "Hostname", "RTT ↑", "Reason"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("column titles = %v; want %v", titles, want)
+	}
+
+	rows := buildRows([]scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.10"), RTT: 1520 * time.Microsecond, Reason: "arp-response"},
+		{IP: netip.MustParseAddr("192.168.1.11")},
+	}, hostMarks{}, optional...)
+	want := []table.Row{
+		{"192.168.1.10", "-", "-", "-", "1.5ms", "arp-response"},
+		{"192.168.1.11", "-", "-", "-", "-", "-"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("buildRows() mismatch:\ngot:  %+v\nwant: %+v", rows, want)
+	}
+
+	if empty := buildRows(nil, hostMarks{}, optional...); len(empty[0]) != len(columns) {
+		t.Errorf("empty table row has %d cells; want %d", len(empty[0]), len(columns))
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	ports := []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh"}, {Number: 80, Protocol: "tcp"}, {Number: 3389, Protocol: "tcp"}}
+	if got, want := formatPorts(ports), "22,80,3389"; got != want {
+		t.Errorf("formatPorts() = %q; want %q", got, want)
+	}
+	if got := formatPorts(nil); got != "" {
+		t.Errorf("formatPorts(nil) = %q; want empty", got)
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	for in, want := range map[time.Duration]string{
+		30 * time.Second:             "30s",
+		5 * time.Minute:              "5m",
+		90 * time.Second:             "1m30s",
+		time.Hour:                    "1h",
+		time.Hour + 30*time.Minute:   "1h30m",
+		2*time.Hour + 15*time.Second: "2h0m15s",
+	} {
+		if got := formatInterval(in); got != want {
+			t.Errorf("formatInterval(%v) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatRTT(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, ""},
+		{412*time.Microsecond + 300*time.Nanosecond, "412µs"},
+		{1520 * time.Microsecond, "1.5ms"},
+		{243*time.Millisecond + 180*time.Microsecond, "243.2ms"},
+		{2*time.Second + 345*time.Millisecond + 600*time.Microsecond, "2.346s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRTT(tt.in); got != tt.want {
+			t.Errorf("formatRTT(%v) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestBuildRows_Preallocation(t *testing.T) {
 	hosts := make([]scanner.HostInfo, 1000)
 	for i := range hosts {
 		hosts[i] = scanner.HostInfo{
-			IP:       "192.168.1.1",
-			MAC:      "AA:BB:CC:DD:EE:FF",
+			IP:       netip.MustParseAddr("192.168.1.1"),
+			MAC:      mustParseMAC("AA:BB:CC:DD:EE:FF"),
 			Vendor:   "Test Vendor",
 			Hostname: "test.local",
 		}
 	}
 
-	rows := buildRows(hosts)
+	rows := buildRows(hosts, hostMarks{})
 
 	if len(rows) != 1000 {
 		t.Errorf("expected 1000 rows, got %d", len(rows))
@@ -246,7 +355,7 @@ func TestNewUIModel(t *testing.T) {
 		{
 			name: "with hosts",
 			hosts: []scanner.HostInfo{
-				{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+				{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 			},
 		},
 		{
@@ -257,7 +366,7 @@ func TestNewUIModel(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			model := NewUIModel(tt.hosts, nil, "")
+			model := NewUIModel(tt.hosts, nil, scanner.Targets{})
 
 			if model.table.Cursor() < 0 {
 				t.Error("table cursor not initialized")
@@ -283,7 +392,7 @@ func TestNewUIModel(t *testing.T) {
 }
 
 func TestUIModel_Init(t *testing.T) {
-	model := NewUIModel([]scanner.HostInfo{}, nil, "")
+	model := NewUIModel([]scanner.HostInfo{}, nil, scanner.Targets{})
 	cmd := model.Init()
 
 	if cmd == nil {
@@ -311,8 +420,8 @@ func TestUIModel_View(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			model := NewUIModel([]scanner.HostInfo{
-				{IP: "192.168.1.10", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test"},
-			}, nil, "")
+				{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test"},
+			}, nil, scanner.Targets{})
 			model.mode = tt.mode
 			model.selectedIP = tt.selectedIP
 
@@ -1,18 +1,23 @@
 package ui
 
 import (
+	"net/netip"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"nls/internal/notes"
 	"nls/internal/scanner"
 )
 
 func TestHandleNormalKeys_HelpScreen(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	// Press ? to show help
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")}
@@ -26,9 +31,9 @@ func TestHandleNormalKeys_HelpScreen(t *testing.T) {
 
 func TestHandleHelpKeys_Exit(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.mode = modeHelp
 
 	tests := []struct {
@@ -62,9 +67,9 @@ func TestHandleHelpKeys_Exit(t *testing.T) {
 
 func TestHandleNormalKeys_SearchMode(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	// Press / to activate search
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")}
@@ -78,9 +83,9 @@ func TestHandleNormalKeys_SearchMode(t *testing.T) {
 
 func TestHandleSearchKeys_Cancel(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.mode = modeSearch
 	model.searchInput.SetValue("test query")
 
@@ -99,10 +104,10 @@ func TestHandleSearchKeys_Cancel(t *testing.T) {
 
 func TestHandleSearchKeys_ApplyFilter(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Apple", Hostname: "test.local"},
-		{IP: "192.168.1.2", MAC: "11:22:33:44:55:66", Vendor: "Samsung", Hostname: "phone.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Apple", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.2"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Samsung", Hostname: "phone.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.mode = modeSearch
 	model.searchInput.SetValue("apple")
 
@@ -125,12 +130,117 @@ func TestHandleSearchKeys_ApplyFilter(t *testing.T) {
 	}
 }
 
+func TestHandleSearchKeys_InvalidQuery(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("10.0.1.5"), Vendor: "Apple"},
+		{IP: netip.MustParseAddr("10.0.2.5"), Vendor: "Samsung"},
+	}
+	updatedModel, _ := NewUIModel(hosts, nil, scanner.Targets{}).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ip:10.0.1.0/33")})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m := updatedModel.(UIModel)
+	if m.mode != modeSearch || m.searchActive || len(m.filteredHosts) != 2 {
+		t.Errorf("mode %v, searchActive %v, %d hosts; want the search kept open and the table unfiltered", m.mode, m.searchActive, len(m.filteredHosts))
+	}
+	if view := m.View(); !strings.Contains(view, "Invalid query: invalid network") {
+		t.Errorf("the footer should show the parse error, got:\n%s", view)
+	}
+
+	// Fixing the query clears the error and applies it
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	updatedModel, _ = updatedModel.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("24 -vendor:apple")})
+	m = updatedModel.(UIModel)
+	if m.searchErr != nil {
+		t.Errorf("searchErr = %v after editing the query", m.searchErr)
+	}
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(UIModel)
+	if m.mode != modeNormal || len(m.filteredHosts) != 0 {
+		t.Errorf("ip:10.0.1.0/24 -vendor:apple kept %v; want no host", m.filteredHosts)
+	}
+}
+
+func TestHandleSearchKeys_LongQuery(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("10.0.1.5"), Vendor: "Apple", Hostname: "apple-tv"},
+		{IP: netip.MustParseAddr("10.0.2.5"), Vendor: "Apple", Hostname: "macbook"},
+		{IP: netip.MustParseAddr("10.0.1.6"), Vendor: "Samsung", Hostname: "tv"},
+	}
+	// The last term, past the 50th character, is the one that drops tv
+	query := "ip:10.0.0.0/16 -hostname:printer -vendor:hewlett vendor:apple"
+	var m tea.Model = NewUIModel(hosts, nil, scanner.Targets{})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range query {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	got := m.(UIModel)
+	if got.searchQuery != query {
+		t.Errorf("searchQuery = %q; want %q", got.searchQuery, query)
+	}
+	if len(got.filteredHosts) != 2 {
+		t.Errorf("filteredHosts = %v; want the two Apple hosts", got.filteredHosts)
+	}
+}
+
+func TestHandleSearchKeys_LiveFilter(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("10.0.1.5"), Vendor: "Apple", Hostname: "apple-tv"},
+		{IP: netip.MustParseAddr("10.0.1.6"), Vendor: "Samsung", Hostname: "tv"},
+		{IP: netip.MustParseAddr("10.0.1.7"), Vendor: "Dell", Hostname: "db1"},
+	}
+	var m tea.Model = NewUIModel(hosts, nil, scanner.Targets{})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+
+	// Every keystroke filters the table, which stays visible; a lone "-"
+	// is a word until something follows it
+	for _, step := range []struct {
+		key  string
+		want int
+	}{
+		{"t", 2}, {"v", 2}, {" ", 2}, {"-", 1}, {"v", 0}, {"e", 2}, {"n", 2},
+	} {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(step.key)})
+		if got := len(m.(UIModel).filteredHosts); got != step.want {
+			t.Fatalf("after typing %q: %d hosts; want %d", m.(UIModel).searchInput.Value(), got, step.want)
+		}
+	}
+	view := m.View()
+	if !strings.Contains(view, "apple-tv") || strings.Contains(view, "db1") || !strings.Contains(view, "/ tv -ven") {
+		t.Errorf("the table should show the match with the input in the footer, got:\n%s", view)
+	}
+
+	// A half-typed field keeps the last filter and says why
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("dor:")})
+	if got := m.(UIModel); got.searchErr == nil || got.searchQuery != "tv -ven" || len(got.filteredHosts) != 2 || !strings.Contains(m.View(), "Invalid query") {
+		t.Errorf("searchErr %v, %d hosts; want the error shown and the last filter kept", got.searchErr, len(got.filteredHosts))
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("apple")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := m.(UIModel); got.mode != modeNormal || got.searchQuery != "tv -vendor:apple" || len(got.filteredHosts) != 1 {
+		t.Errorf("enter kept %q with %d hosts; want tv -vendor:apple", got.searchQuery, len(got.filteredHosts))
+	}
+
+	// esc undoes what was typed since / was pressed
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" OR db")})
+	if got := len(m.(UIModel).filteredHosts); got != 2 {
+		t.Fatalf("%d hosts while typing; want 2", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got := m.(UIModel); got.searchQuery != "tv -vendor:apple" || len(got.filteredHosts) != 1 {
+		t.Errorf("esc left %q with %d hosts; want the previous filter back", got.searchQuery, len(got.filteredHosts))
+	}
+}
+
 func TestHandleNormalKeys_SortColumns(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Vendor A", Hostname: "host1"},
-		{IP: "192.168.1.5", MAC: "11:22:33:44:55:66", Vendor: "Vendor B", Hostname: "host2"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Vendor A", Hostname: "host1"},
+		{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Vendor B", Hostname: "host2"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	tests := []struct {
 		name            string
@@ -163,9 +273,9 @@ func TestHandleNormalKeys_SortColumns(t *testing.T) {
 
 func TestHandleNormalKeys_SortToggle(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Vendor A", Hostname: "host1"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Vendor A", Hostname: "host1"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.sortColumn = 1
 	model.sortAscending = true
 
@@ -184,9 +294,9 @@ func TestHandleNormalKeys_SortToggle(t *testing.T) {
 
 func TestView_HelpMode(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.mode = modeHelp
 
 	view := model.View()
@@ -202,9 +312,9 @@ func TestView_HelpMode(t *testing.T) {
 
 func TestView_SearchMode(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.mode = modeSearch
 
 	view := model.View()
@@ -216,9 +326,9 @@ func TestView_SearchMode(t *testing.T) {
 
 func TestView_NormalModeWithFilter(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Apple", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Apple", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.searchActive = true
 	model.searchQuery = "apple"
 
@@ -232,3 +342,167 @@ func TestView_NormalModeWithFilter(t *testing.T) {
 		t.Error("View with filter seems too short")
 	}
 }
+
+func TestHandleNormalKeys_DetailPane(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.9"), RTT: 1520 * time.Microsecond, Reason: "arp-response"},
+		{
+			IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "nas.local",
+			Addresses: parseAddrs("192.168.1.5", "fd00::5"),
+			Hostnames: []string{"nas.local", "backup.lan"},
+			Ports:     []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh"}},
+		},
+	}
+	model := NewUIModel(hosts, nil, scanner.Targets{})
+	model.sortColumn = 1 // the table shows 192.168.1.5 first
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	m := updatedModel.(UIModel)
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updatedModel.(UIModel)
+	if !m.showDetail || !m.detailBeside() {
+		t.Fatal("enter should open the detail pane beside the table")
+	}
+	if got, want := m.tableWidth(), 160-DetailPaneWidth; got != want {
+		t.Errorf("tableWidth() = %d; want %d to leave room for the pane", got, want)
+	}
+	view := m.View()
+	for _, want := range []string{"192.168.1.5 (primary)", "fd00::5", "nas.local (primary)", "backup.lan", "22/tcp    ssh", "First seen:"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail pane should contain %q", want)
+		}
+	}
+
+	// The pane follows the selection.
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updatedModel.(UIModel)
+	view = m.View()
+	if strings.Contains(view, "backup.lan") || !strings.Contains(view, "1.5ms (arp-response)") {
+		t.Error("detail pane should show the newly selected host 192.168.1.9")
+	}
+
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m = updatedModel.(UIModel); m.showDetail || !m.table.Focused() {
+		t.Errorf("esc should close the pane and leave the table focused")
+	}
+}
+
+func TestHandleNormalKeys_DetailPaneNarrowTerminal(t *testing.T) {
+	hosts := []scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.5"), Hostnames: []string{"nas.local", "backup.lan"}}}
+	model := NewUIModel(hosts, nil, scanner.Targets{})
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 80, Height: 40})
+	updatedModel, _ = updatedModel.(UIModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m := updatedModel.(UIModel)
+
+	if m.detailBeside() || m.tableWidth() != 80 {
+		t.Error("on a narrow terminal the pane should replace the table, not squeeze it")
+	}
+	if !strings.Contains(m.View(), "backup.lan") {
+		t.Error("detail pane should be shown")
+	}
+}
+
+func TestHandleNormalKeys_DetailPaneNoHosts(t *testing.T) {
+	model := NewUIModel(nil, nil, scanner.Targets{})
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if view := updatedModel.(UIModel).View(); !strings.Contains(view, "No host selected") {
+		t.Errorf("detail pane on the empty table should say no host is selected, got:\n%s", view)
+	}
+}
+
+func TestHandleNormalKeys_ToggleTiming(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.10"), RTT: 2 * time.Millisecond, Reason: "arp-response"},
+		{IP: netip.MustParseAddr("192.168.1.5"), RTT: 900 * time.Microsecond, Reason: "arp-response"},
+	}
+	m := NewUIModel(hosts, nil, scanner.Targets{})
+	press := func(key string) {
+		t.Helper()
+		updatedModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = updatedModel.(UIModel)
+		_ = m.View() // must not panic as the column count changes
+	}
+
+	press("5")
+	if m.sortColumn != 0 {
+		t.Errorf("sorting by the hidden RTT column should be ignored, got sortColumn %d", m.sortColumn)
+	}
+
+	press("t")
+	if !m.showTiming || len(m.table.Columns()) != 6 {
+		t.Fatalf("t should show the RTT and Reason columns, got %d columns", len(m.table.Columns()))
+	}
+	press("5")
+	if m.sortColumn != colRTT {
+		t.Errorf("sortColumn = %d; want %d", m.sortColumn, colRTT)
+	}
+	if got := m.table.Rows()[0][0]; got != "192.168.1.5" {
+		t.Errorf("first row after sorting by RTT = %s; want 192.168.1.5", got)
+	}
+
+	press("t")
+	if m.showTiming || len(m.table.Columns()) != 4 {
+		t.Fatalf("t should hide the RTT and Reason columns, got %d columns", len(m.table.Columns()))
+	}
+	if m.sortColumn != 0 {
+		t.Errorf("hiding the sorted column should reset the sort, got sortColumn %d", m.sortColumn)
+	}
+}
+
+func TestHandleNoteKeys(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF")},
+		{IP: netip.MustParseAddr("192.168.1.2")},
+	}
+	store := notes.NewMemory()
+	model := NewUIModel(hosts, nil, scanner.Targets{}).WithNotes(store)
+
+	var m tea.Model = model
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("n")},
+		{Type: tea.KeyRunes, Runes: []rune("NAS")},
+		{Type: tea.KeyTab},
+		{Type: tea.KeyRunes, Runes: []rune("Backups, in the rack")},
+		{Type: tea.KeyTab},
+		{Type: tea.KeyRunes, Runes: []rune("NAS, Backup backup")},
+	} {
+		m, _ = m.Update(msg)
+	}
+	if view := m.View(); !strings.Contains(view, "Alias, note and tags for 192.168.1.1") {
+		t.Errorf("the editor should name the host, got:\n%s", view)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	result := m.(UIModel)
+
+	want := notes.Entry{Key: "AA:BB:CC:DD:EE:FF", Alias: "NAS", Note: "Backups, in the rack", Tags: []string{"backup", "nas"}}
+	if got, _ := store.Lookup(want.Key); !reflect.DeepEqual(got, want) || result.mode != modeNormal {
+		t.Errorf("stored %+v in mode %v; want %+v back in normal mode", got, result.mode, want)
+	}
+	rows := result.table.Rows()
+	if alias, tags := rows[0][len(rows[0])-2], rows[0][len(rows[0])-1]; alias != "NAS" || tags != "backup nas" {
+		t.Errorf("Alias and Tags columns = %q, %q; want NAS, backup nas", alias, tags)
+	}
+	pane := result.renderDetailPane()
+	if !strings.Contains(pane, "Note:       Backups") || !strings.Contains(pane, "Tags:       backup, nas") {
+		t.Errorf("detail pane should show the note and tags, got:\n%s", pane)
+	}
+
+	// The alias, the note and the tags are searchable
+	for _, query := range []string{"nas", "rack", "tag:backup", "TAG:NAS"} {
+		if got := filterHosts(hosts, mustParseQuery(query), result.marks()); len(got) != 1 || got[0].IP != hosts[0].IP {
+			t.Errorf("filterHosts(%q) = %v; want the annotated host", query, got)
+		}
+	}
+
+	// Opening the editor again starts from the saved entry; esc keeps it
+	m, _ = result.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if v, tv := m.(UIModel).aliasInput.Value(), m.(UIModel).tagsInput.Value(); v != "NAS" || tv != "backup nas" {
+		t.Errorf("alias and tags inputs = %q, %q; want the saved entry", v, tv)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got, _ := store.Lookup(want.Key); !reflect.DeepEqual(got, want) {
+		t.Errorf("esc changed the entry to %+v", got)
+	}
+}
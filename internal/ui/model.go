@@ -4,26 +4,55 @@
 package ui
 
 import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
 
+	"nls/internal/diff"
+	"nls/internal/inventory"
+	"nls/internal/known"
+	"nls/internal/notes"
+	"nls/internal/progress"
 	"nls/internal/scanner"
 )
 
 // UI layout constants
 const (
-	TablePaddingWidth     = 8
-	MinTableHeight        = 7
-	DefaultTermWidth      = 100
-	DefaultTermHeight     = 20
-	DefaultTermHeightPad  = 5
-	SSHUsernameMaxLen     = 32
-	SSHUsernameInputWidth = 40
-	SSHPromptWidth        = 50
-	SSHPromptPadding      = 1
-	HelpBoxWidth          = 70
-	HelpBoxPadding        = 2
-	SearchInputWidth      = 50
+	TablePaddingWidth      = 8
+	MinTableHeight         = 7
+	DefaultTermWidth       = 100
+	DefaultTermHeight      = 20
+	DefaultTermHeightPad   = 5
+	SSHUsernameMaxLen      = 32
+	SSHUsernameInputWidth  = 40
+	SSHPromptWidth         = 50
+	SSHPromptPadding       = 1
+	HelpBoxWidth           = 70
+	HelpBoxPadding         = 2
+	SearchInputWidth       = 50
+	SearchMaxLen           = 256
+	AliasMaxLen            = 32
+	NoteMaxLen             = 200
+	TagsMaxLen             = 100
+	scanEventBuffer        = 64
+	progressTickInterval   = 250 * time.Millisecond
+	deepScanTimeout        = 10 * time.Minute
+	DefaultRefreshInterval = 5 * time.Minute
+	DetailPaneWidth        = 46
+	MinDetailTableWidth    = 60
+)
+
+// Fields of the alias, note and tags editor, in tab order.
+const (
+	noteFieldAlias = iota
+	noteFieldNote
+	noteFieldTags
+	noteFields
 )
 
 // viewMode represents the current view/screen mode
@@ -34,6 +63,7 @@ const (
 	modeHelp
 	modeSearch
 	modeSSHPrompt
+	modeNote
 )
 
 // Help screen content
@@ -45,16 +75,28 @@ const helpText = `Keyboard Shortcuts:
     esc          Toggle table focus
 
   Actions:
+    enter        Show/hide the detail pane of the selected host
+    d            Deep scan selected host: service versions (nmap -sV)
+    D            Deep scan with OS detection too (needs root)
+                 Press d or D again to cancel a running deep scan
     s            SSH to selected host
+    n            Set an alias, note and tags for the selected host
     c            Copy IP to clipboard
-    r            Rescan network
+    r            Rescan network (reload the file with --from-xml)
+    a            Turn automatic rescans on or off
 
   Search & Sort:
-    /            Search/filter hosts
+    /            Search/filter hosts as you type, e.g. vendor:apple
+                 -tag:iot, ip:10.0.1.0/24, host:/^db/, port:22 OR port:3389
+                 (enter keeps the filter, esc undoes it)
     1            Sort by IP
     2            Sort by MAC
     3            Sort by Vendor
     4            Sort by Hostname
+    5/6          Sort by RTT/Reason (when shown)
+    t            Show/hide RTT and Reason columns
+    C            Show only new, gone and changed hosts (after a rescan)
+    u            Show only hosts missing from the known-devices file
 
   Other:
     ?            Show this help
@@ -71,11 +113,21 @@ type UIModel struct {
 	table         table.Model
 	usernameInput textinput.Model
 	searchInput   textinput.Model
+	aliasInput    textinput.Model
+	noteInput     textinput.Model
+	tagsInput     textinput.Model
 
 	// Data storage
 	allHosts      []scanner.HostInfo // Original host data
 	filteredHosts []scanner.HostInfo // After applying search filter
 
+	// cursor is the index in displayedHosts() of the selected host and
+	// offset that of the first host in view. The table only holds the
+	// rows in view (see rebuildTable), so the model scrolls it itself and
+	// knows which host is on each line.
+	cursor int
+	offset int
+
 	// View state
 	mode          viewMode
 	statusMessage string
@@ -83,29 +135,92 @@ type UIModel struct {
 	// SSH state
 	selectedIP string
 
-	// Search/Filter state
+	// notes holds the aliases, notes and tags written about hosts;
+	// noteHost is the host whose entry is being edited and noteField the
+	// editor field with the focus (one of the noteField* constants)
+	notes     notes.Store
+	noteHost  scanner.HostInfo
+	noteField int
+
+	// showDetail shows the detail pane of the selected host beside the table
+	showDetail bool
+
+	// inventory records every host found, for the first and last seen
+	// times and the addresses and names it used before. It is shared by
+	// copies of the model.
+	inventory inventory.Store
+	now       func() time.Time
+
+	// Search/Filter state; searchQuery only ever holds a query that
+	// parses, and searchErr is why the one being typed does not.
+	// searchBefore is the query esc goes back to.
 	searchActive bool
 	searchQuery  string
+	searchErr    error
+	searchBefore string
+
+	// changes marks the hosts that differ from the previous scan, by
+	// HostInfo.Key; it is nil until the first rescan. Gone hosts stay in
+	// allHosts, greyed out, until the next rescan.
+	changes map[string]diff.Change
+
+	// changesOnly limits the table to the hosts in changes
+	changesOnly bool
+
+	// known is the known-devices list, which adds the Label column and
+	// flags the hosts it does not list; nil without a list
+	known *known.List
+
+	// unknownOnly limits the table to the hosts known does not list
+	unknownOnly bool
 
 	// Sort state
-	sortColumn    int // 0=none, 1=IP, 2=MAC, 3=Vendor, 4=Hostname
+	sortColumn    int // 0=none, otherwise one of the col* constants
 	sortAscending bool
 
+	// showTiming adds the optional RTT and Reason columns
+	showTiming bool
+
 	// Terminal dimensions
 	width  int
 	height int
 
 	// Rescan state
 	scanner    scanner.Scanner
-	cidr       string
+	targets    scanner.Targets
 	isScanning bool
+
+	// Auto-refresh rescans every refreshInterval while autoRefresh is on.
+	// Each scheduled rescan carries the refreshGen it was scheduled with,
+	// so only the latest one runs.
+	autoRefresh     bool
+	refreshInterval time.Duration
+	refreshGen      int
+
+	// Initial (streaming) scan state
+	scanCtx    context.Context
+	cancelScan func()        // cancels the scan and closes scanQuit
+	scanQuit   chan struct{} // closed once the UI stops consuming events
+	scanEvents chan tea.Msg
+	scanErr    error
+
+	// progress is polled while scanning to show percentage and ETA (may be nil)
+	progress *progress.Tracker
+
+	// Deep scan state: one host at a time, in the background
+	deepScanner    scanner.DeepScanner // nil disables deep scans
+	deepTarget     netip.Addr          // host being scanned, invalid when idle
+	deepCtx        context.Context
+	cancelDeepScan context.CancelFunc
+	deepResults    map[netip.Addr]scanner.HostInfo // finished scans by IP
 }
 
 // NewUIModel creates a new UI model. UIModel requires initialization
 // and cannot be used with its zero value due to dependencies on
 // the Bubbletea table component.
-// The scanner and cidr parameters enable rescan functionality.
-func NewUIModel(hosts []scanner.HostInfo, s scanner.Scanner, cidr string) UIModel {
+// The scanner and targets parameters enable rescan functionality; rescans
+// cover the full target set, exclusions included.
+func NewUIModel(hosts []scanner.HostInfo, s scanner.Scanner, targets scanner.Targets) UIModel {
 	width, height := getTerminalSize()
 	tableHeight := height
 	if tableHeight < MinTableHeight {
@@ -113,11 +228,13 @@ func NewUIModel(hosts []scanner.HostInfo, s scanner.Scanner, cidr string) UIMode
 	}
 
 	weights := DefaultColumnWeights()
-	columns := buildColumns(width, weights, 0, false) // No initial sort
-	rows := buildRows(hosts)
+	var optional []int
+	if hasPorts(hosts) {
+		optional = append(optional, colPorts)
+	}
+	columns := buildColumns(width, weights, 0, false, optional...) // No initial sort
 	t := table.New(
 		table.WithColumns(columns),
-		table.WithRows(rows),
 		table.WithFocused(true),
 		table.WithHeight(tableHeight),
 	)
@@ -133,24 +250,111 @@ func NewUIModel(hosts []scanner.HostInfo, s scanner.Scanner, cidr string) UIMode
 
 	// Search input
 	si := textinput.New()
-	si.Placeholder = "Search (IP, MAC, Vendor, Hostname)..."
-	si.CharLimit = 50
+	si.Prompt = "/ "
+	si.Placeholder = "Search, e.g. apple, vendor:dell -tag:iot, ip:10.0.1.0/24"
+	si.CharLimit = SearchMaxLen
 	si.Width = SearchInputWidth
 
-	return UIModel{
+	// Alias, note and tags inputs
+	ai := textinput.New()
+	ai.Placeholder = "alias"
+	ai.CharLimit = AliasMaxLen
+	ai.Width = SSHUsernameInputWidth
+	ni := textinput.New()
+	ni.Placeholder = "note"
+	ni.CharLimit = NoteMaxLen
+	ni.Width = SSHUsernameInputWidth
+	tgi := textinput.New()
+	tgi.Placeholder = "tags, e.g. printer iot"
+	tgi.CharLimit = TagsMaxLen
+	tgi.Width = SSHUsernameInputWidth
+
+	m := UIModel{
 		table:         t,
 		allHosts:      hosts,
 		filteredHosts: hosts, // Initially, no filter applied
 		usernameInput: ti,
 		searchInput:   si,
+		aliasInput:    ai,
+		noteInput:     ni,
+		tagsInput:     tgi,
 		mode:          modeNormal,
 		searchActive:  false,
 		sortColumn:    0,
 		width:         width,
 		height:        height,
 		scanner:       s,
-		cidr:          cidr,
+		targets:       targets,
 		isScanning:    false,
 		sortAscending: true,
+		inventory:     inventory.NewMemory(),
+		notes:         notes.NewMemory(),
+		now:           time.Now,
 	}
+	return m.rebuildTable().recordSeen(hosts...)
+}
+
+// StartScan makes the model run the initial scan itself once the program
+// starts. Hosts are added to the table as they are discovered, and the
+// table stays fully usable while the scan is running. The scan is bound to
+// ctx and is cancelled when the user quits.
+func (m UIModel) StartScan(ctx context.Context) UIModel {
+	scanCtx, cancel := context.WithCancel(ctx)
+	quit := make(chan struct{})
+	m.scanCtx = scanCtx
+	m.scanQuit = quit
+	m.cancelScan = sync.OnceFunc(func() {
+		cancel()
+		close(quit)
+	})
+	m.scanEvents = make(chan tea.Msg, scanEventBuffer)
+	m.isScanning = true
+	return m
+}
+
+// WithProgress makes the scanning indicator show the percentage and ETA
+// recorded by t, which should be the reporter the scanner was built with.
+func (m UIModel) WithProgress(t *progress.Tracker) UIModel {
+	m.progress = t
+	return m
+}
+
+// WithDeepScanner enables deep scans of the selected host with ds.
+func (m UIModel) WithDeepScanner(ds scanner.DeepScanner) UIModel {
+	m.deepScanner = ds
+	return m
+}
+
+// WithInventory records the hosts found in store instead of a store
+// limited to the session. Hosts already in the table are recorded too.
+func (m UIModel) WithInventory(store inventory.Store) UIModel {
+	m.inventory = store
+	return m.recordSeen(m.allHosts...)
+}
+
+// WithNotes keeps the aliases, notes and tags written about hosts in store
+// instead of a store limited to the session.
+func (m UIModel) WithNotes(store notes.Store) UIModel {
+	m.notes = store
+	return m.applyFilter().rebuildTable()
+}
+
+// WithKnownDevices labels the hosts listed in list and flags the others.
+func (m UIModel) WithKnownDevices(list *known.List) UIModel {
+	m.known = list
+	return m.rebuildTable()
+}
+
+// WithAutoRefresh makes the model rescan every interval, counted from the
+// end of the previous scan.
+func (m UIModel) WithAutoRefresh(interval time.Duration) UIModel {
+	m.autoRefresh = true
+	m.refreshInterval = interval
+	return m
+}
+
+// Err returns the error that ended the initial scan before any host was
+// found, or nil. It is meant to be inspected after the program exits.
+func (m UIModel) Err() error {
+	return m.scanErr
 }
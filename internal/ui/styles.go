@@ -21,6 +21,26 @@ var (
 			BorderForeground(lipgloss.Color("99")).
 			Padding(HelpBoxPadding, 3).
 			Width(HelpBoxWidth)
+
+	// detailStyle frames the detail pane; the border takes two columns
+	detailStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("99")).
+			Padding(0, 1).
+			Width(DetailPaneWidth - 2)
+
+	// goneStyle greys out the rows of hosts the last rescan did not find
+	goneStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	// unknownStyle flags the rows of hosts missing from the known-devices
+	// list
+	unknownStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+
+	// matchStyle highlights what the search query matched in the table
+	matchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("220"))
+
+	// searchErrStyle shows why a search query is invalid
+	searchErrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 )
 
 func tableStyles() table.Styles {
@@ -2,14 +2,25 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"net/netip"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"nls/internal/diff"
+	"nls/internal/notes"
+	"nls/internal/query"
 	"nls/internal/scanner"
+	"nls/internal/tags"
 )
 
 // clearStatusMsg is sent after a delay to clear the status message.
@@ -25,18 +36,46 @@ type rescanErrorMsg struct {
 	err error
 }
 
+// hostFoundMsg is sent by the initial scan for every discovered host.
+type hostFoundMsg struct {
+	host scanner.HostInfo
+}
+
+// scanDoneMsg is sent when the initial scan finishes, with a non-nil err on failure.
+type scanDoneMsg struct {
+	err error
+}
+
+// autoRefreshMsg is sent when an automatic rescan is due; gen is the
+// refreshGen it was scheduled with.
+type autoRefreshMsg struct {
+	gen int
+}
+
+// progressTickMsg is sent periodically while scanning to refresh the
+// progress indicator.
+type progressTickMsg struct{}
+
 // sshDoneMsg is sent when an SSH process exits, with a non-nil err on failure.
 type sshDoneMsg struct {
 	err error
 }
 
+// deepScanDoneMsg is sent when a deep scan of addr finishes, with a
+// non-nil err on failure or cancellation.
+type deepScanDoneMsg struct {
+	addr netip.Addr
+	host scanner.HostInfo
+	err  error
+}
+
 // doRescan performs a network rescan in a goroutine and returns the result as a message.
-func doRescan(s scanner.Scanner, cidr string) tea.Cmd {
+func doRescan(s scanner.Scanner, targets scanner.Targets) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
 
-		hosts, err := s.Scan(ctx, cidr)
+		hosts, err := s.Scan(ctx, targets)
 		if err != nil {
 			return rescanErrorMsg{err: err}
 		}
@@ -44,10 +83,64 @@ func doRescan(s scanner.Scanner, cidr string) tea.Cmd {
 	}
 }
 
+// doDeepScan examines addr with ds in a goroutine and returns the result as
+// a message. Cancelling ctx stops the scan.
+func doDeepScan(ctx context.Context, ds scanner.DeepScanner, addr netip.Addr, osDetection bool) tea.Cmd {
+	return func() tea.Msg {
+		host, err := ds.DeepScan(ctx, addr, osDetection)
+		return deepScanDoneMsg{addr: addr, host: host, err: err}
+	}
+}
+
+// runScan streams the initial scan into events, finishing with a scanDoneMsg.
+// Sends are abandoned once quit is closed so the goroutine never leaks.
+func runScan(ctx context.Context, quit <-chan struct{}, s scanner.Scanner, targets scanner.Targets, events chan<- tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		send := func(msg tea.Msg) {
+			select {
+			case events <- msg:
+			case <-quit:
+			}
+		}
+
+		err := scanner.Stream(ctx, s, targets, func(h scanner.HostInfo) {
+			send(hostFoundMsg{host: h})
+		})
+		send(scanDoneMsg{err: err})
+		return nil
+	}
+}
+
+// waitForScanEvent delivers the next event from the initial scan.
+func waitForScanEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// tickProgress schedules the next refresh of the scanning indicator.
+func tickProgress() tea.Cmd {
+	return tea.Tick(progressTickInterval, func(time.Time) tea.Msg {
+		return progressTickMsg{}
+	})
+}
+
 // Init initializes the UI model.
-// Requests initial window size to ensure proper layout.
+// Requests initial window size to ensure proper layout, and starts the
+// initial scan when StartScan was used.
 func (m UIModel) Init() tea.Cmd {
-	return tea.WindowSize()
+	if m.scanEvents == nil {
+		if m.autoRefresh {
+			return tea.Batch(tea.WindowSize(), refreshAfter(m.refreshInterval, m.refreshGen))
+		}
+		return tea.WindowSize()
+	}
+	return tea.Batch(
+		tea.WindowSize(),
+		runScan(m.scanCtx, m.scanQuit, m.scanner, m.targets, m.scanEvents),
+		waitForScanEvent(m.scanEvents),
+		tickProgress(),
+	)
 }
 
 // Update handles keyboard input and updates the model state.
@@ -61,33 +154,95 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case rescanCompleteMsg:
-		// Update hosts with new scan results
+		// Update hosts with new scan results, marking what changed since
+		// the previous scan
 		m.isScanning = false
-		m.allHosts = msg.hosts
-
-		// Reapply current filter if active
-		if m.searchActive {
-			m.filteredHosts = filterHosts(m.allHosts, m.searchQuery)
-		} else {
-			m.filteredHosts = m.allHosts
+		changes := diff.Hosts(m.scannedHosts(), msg.hosts)
+		m.changes = make(map[string]diff.Change, len(changes))
+		m.allHosts = slices.Clone(msg.hosts)
+		for _, c := range changes {
+			m.changes[c.Host().Key()] = c
+			if c.Kind == diff.Removed {
+				m.allHosts = append(m.allHosts, c.Old)
+			}
 		}
 
-		// Rebuild the table
-		m = m.rebuildTable()
+		// Reapply current filter and rebuild the table
+		m = m.applyFilter().rebuildTable()
 
 		// Show success message
-		m.statusMessage = fmt.Sprintf("Rescan complete: %d host(s) found", len(m.allHosts))
-		return m, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+		summary := diff.Summarize(changes)
+		m.statusMessage = fmt.Sprintf("Rescan complete: %d host(s) found, %s", len(msg.hosts), summary)
+		if name, ok := m.sourceFile(); ok {
+			m.statusMessage = fmt.Sprintf("Reloaded %s: %d host(s) found, %s", name, len(msg.hosts), summary)
+		}
+		m = m.recordSeen(msg.hosts...)
+		var refresh tea.Cmd
+		m, refresh = m.scheduleRefresh()
+		return m, tea.Batch(refresh, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
 			return clearStatusMsg{}
-		})
+		}))
+
+	case autoRefreshMsg:
+		// Only the latest scheduled rescan runs, and not over another scan
+		if !m.autoRefresh || msg.gen != m.refreshGen || m.isScanning {
+			return m, nil
+		}
+		return m.startRescan()
+
+	case progressTickMsg:
+		// Keep refreshing only while a scan is running
+		if !m.isScanning {
+			return m, nil
+		}
+		return m, tickProgress()
+
+	case hostFoundMsg:
+		m.allHosts = append(m.allHosts, msg.host)
+		m = m.recordSeen(msg.host)
+		m = m.applyFilter().rebuildTable()
+		return m, waitForScanEvent(m.scanEvents)
+
+	case scanDoneMsg:
+		m.isScanning = false
+		if msg.err != nil {
+			if len(m.allHosts) == 0 {
+				// Nothing to browse: leave and let the caller report it.
+				m.scanErr = msg.err
+				return m, tea.Quit
+			}
+			m.statusMessage = fmt.Sprintf("Scan failed: %v", msg.err)
+			var refresh tea.Cmd
+			m, refresh = m.scheduleRefresh()
+			return m, tea.Batch(refresh, tea.Tick(5*time.Second, func(time.Time) tea.Msg {
+				return clearStatusMsg{}
+			}))
+		}
+		m.statusMessage = fmt.Sprintf("Scan complete: %d host(s) found", len(m.allHosts))
+		if name, ok := m.sourceFile(); ok {
+			m.statusMessage = fmt.Sprintf("Loaded %s: %d host(s) found", name, len(m.allHosts))
+		}
+		var refresh tea.Cmd
+		m, refresh = m.scheduleRefresh()
+		return m, tea.Batch(refresh, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		}))
 
 	case rescanErrorMsg:
 		// Handle scan error
 		m.isScanning = false
 		m.statusMessage = fmt.Sprintf("Rescan failed: %v", msg.err)
-		return m, tea.Tick(5*time.Second, func(time.Time) tea.Msg {
+		if name, ok := m.sourceFile(); ok {
+			m.statusMessage = fmt.Sprintf("Reloading %s failed: %v", name, msg.err)
+		}
+		var refresh tea.Cmd
+		m, refresh = m.scheduleRefresh()
+		return m, tea.Batch(refresh, tea.Tick(5*time.Second, func(time.Time) tea.Msg {
 			return clearStatusMsg{}
-		})
+		}))
+
+	case deepScanDoneMsg:
+		return m.finishDeepScan(msg)
 
 	case sshDoneMsg:
 		m.mode = modeNormal
@@ -106,16 +261,11 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.height < MinTableHeight {
 			m.height = MinTableHeight
 		}
-		m = m.rebuildTable()
 		m.table.SetHeight(m.height)
+		m = m.rebuildTable()
 		return m, nil
 
 	case tea.KeyMsg:
-		// Ignore keyboard input while scanning
-		if m.isScanning {
-			return m, nil
-		}
-
 		// Route to appropriate handler based on view mode
 		switch m.mode {
 		case modeHelp:
@@ -124,6 +274,8 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleSearchKeys(msg)
 		case modeSSHPrompt:
 			return m.handleSSHPromptKeys(msg)
+		case modeNote:
+			return m.handleNoteKeys(msg)
 		default: // modeNormal
 			return m.handleNormalKeys(msg)
 		}
@@ -144,33 +296,27 @@ func (m UIModel) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// handleSearchKeys handles keyboard input when search mode is active.
+// handleSearchKeys handles keyboard input when search mode is active. The
+// table is filtered as the query is typed; enter keeps the filter and esc
+// goes back to the one in place before the search was opened.
 func (m UIModel) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc":
 		// Cancel search, return to normal mode
 		m.mode = modeNormal
-		m.searchInput.SetValue("")
+		m.searchInput.SetValue(m.searchBefore)
 		m.searchInput.Blur()
+		m = m.search()
 		m.table.Focus()
 		return m, nil
 
 	case "enter":
-		// Apply search filter
-		query := m.searchInput.Value()
-		m.searchQuery = query
-		m.searchActive = query != ""
-
-		// Filter hosts
-		if m.searchActive {
-			m.filteredHosts = filterHosts(m.allHosts, query)
-		} else {
-			m.filteredHosts = m.allHosts
+		// Keep the search filter, unless the query is invalid: the error
+		// is shown in the footer until the query is fixed
+		if m = m.search(); m.searchErr != nil {
+			return m, nil
 		}
 
-		// Rebuild table with filtered and sorted data
-		m = m.rebuildTable()
-
 		m.mode = modeNormal
 		m.searchInput.Blur()
 		m.table.Focus()
@@ -184,10 +330,28 @@ func (m UIModel) handleSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	default:
 		var cmd tea.Cmd
 		m.searchInput, cmd = m.searchInput.Update(msg)
-		return m, cmd
+		return m.search(), cmd
 	}
 }
 
+// search filters the table with the query in the search input. While the
+// query is invalid, e.g. half typed, searchErr says why and the last valid
+// filter stays.
+func (m UIModel) search() UIModel {
+	input := m.searchInput.Value()
+	if _, err := query.Parse(input); err != nil {
+		m.searchErr = err
+		return m
+	}
+	m.searchErr = nil
+	if input == m.searchQuery && m.searchActive == (strings.TrimSpace(input) != "") {
+		return m
+	}
+	m.searchQuery = input
+	m.searchActive = strings.TrimSpace(input) != ""
+	return m.applyFilter().rebuildTable()
+}
+
 // handleSSHPromptKeys handles keyboard input when SSH prompt is shown.
 func (m UIModel) handleSSHPromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -216,6 +380,74 @@ func (m UIModel) handleSSHPromptKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// handleNoteKeys handles keyboard input when the alias, note and tags
+// editor is shown. tab moves between the fields and enter saves them all.
+func (m UIModel) handleNoteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeNormal
+		m = m.focusNoteField(-1)
+		m.table.Focus()
+		return m, nil
+
+	case "tab", "down":
+		return m.focusNoteField((m.noteField + 1) % noteFields), nil
+
+	case "shift+tab", "up":
+		return m.focusNoteField((m.noteField + noteFields - 1) % noteFields), nil
+
+	case "enter":
+		m.mode = modeNormal
+		m = m.focusNoteField(-1)
+		m.table.Focus()
+
+		e := notes.Entry{
+			Key:   m.noteHost.Key(),
+			Alias: strings.TrimSpace(m.aliasInput.Value()),
+			Note:  strings.TrimSpace(m.noteInput.Value()),
+			Tags:  tags.Parse(m.tagsInput.Value()),
+		}
+		m.statusMessage = "Alias, note and tags saved"
+		if err := m.notes.Set(e); err != nil {
+			m.statusMessage = fmt.Sprintf("Alias, note and tags not saved: %v", err)
+		}
+		m = m.applyFilter().rebuildTable()
+		return m, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		})
+
+	default:
+		var cmd tea.Cmd
+		switch m.noteField {
+		case noteFieldAlias:
+			m.aliasInput, cmd = m.aliasInput.Update(msg)
+		case noteFieldNote:
+			m.noteInput, cmd = m.noteInput.Update(msg)
+		case noteFieldTags:
+			m.tagsInput, cmd = m.tagsInput.Update(msg)
+		}
+		return m, cmd
+	}
+}
+
+// focusNoteField moves the focus of the editor to field, or blurs every
+// field when field is -1.
+func (m UIModel) focusNoteField(field int) UIModel {
+	m.aliasInput.Blur()
+	m.noteInput.Blur()
+	m.tagsInput.Blur()
+	m.noteField = field
+	switch field {
+	case noteFieldAlias:
+		m.aliasInput.Focus()
+	case noteFieldNote:
+		m.noteInput.Focus()
+	case noteFieldTags:
+		m.tagsInput.Focus()
+	}
+	return m
+}
+
 // handleNormalKeys handles keyboard input in normal table view mode.
 func (m UIModel) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -228,11 +460,19 @@ func (m UIModel) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "/":
 		// Activate search mode
 		m.mode = modeSearch
+		m.searchBefore = m.searchQuery
+		m.searchInput.SetValue(m.searchQuery)
 		m.searchInput.Focus()
 		m.table.Blur()
 		return m, nil
 
 	case "esc":
+		if m.showDetail {
+			// Close the detail pane first
+			m.showDetail = false
+			m = m.rebuildTable()
+			return m, nil
+		}
 		if m.table.Focused() {
 			m.table.Blur()
 		} else {
@@ -240,11 +480,59 @@ func (m UIModel) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case "q", "ctrl+c":
+		if m.cancelScan != nil {
+			m.cancelScan()
+		}
+		if m.cancelDeepScan != nil {
+			m.cancelDeepScan()
+		}
 		return m, tea.Quit
 
-	case "1", "2", "3", "4":
+	case "d", "D":
+		// Deep scan the selected host (D adds OS detection), or cancel
+		// the deep scan already running
+		if m.cancelDeepScan != nil {
+			m.cancelDeepScan()
+			m.statusMessage = fmt.Sprintf("Cancelling deep scan of %s...", m.deepTarget)
+			return m, nil
+		}
+		h, ok := m.selectedHost()
+		if !ok || !h.IP.IsValid() {
+			return m, nil
+		}
+		_, fromFile := m.sourceFile()
+		if m.deepScanner == nil || fromFile {
+			m.statusMessage = "Deep scan is not available"
+			if fromFile {
+				// The hosts of a report may not be on this network at all
+				m.statusMessage += " for hosts read from a file"
+			}
+			return m, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+				return clearStatusMsg{}
+			})
+		}
+		// Not bound to the initial scan's context, whose timeout would
+		// cut short deep scans started later; quitting cancels it.
+		m.deepTarget = h.IP
+		m.deepCtx, m.cancelDeepScan = context.WithTimeout(context.Background(), deepScanTimeout)
+		return m, doDeepScan(m.deepCtx, m.deepScanner, h.IP, msg.String() == "D")
+
+	case "t":
+		// Toggle the RTT and Reason columns
+		m.showTiming = !m.showTiming
+		if !m.showTiming && (m.sortColumn == colRTT || m.sortColumn == colReason) {
+			m.sortColumn = 0
+		}
+		m = m.rebuildTable()
+		return m, nil
+
+	case "1", "2", "3", "4", "5", "6":
 		// Sort by column
 		col := int(msg.String()[0] - '0')
+		if col > colHostname && !m.showTiming {
+			// Not shown, nothing to sort by
+			return m, nil
+		}
 		if m.sortColumn == col {
 			// Toggle sort direction
 			m.sortAscending = !m.sortAscending
@@ -256,21 +544,60 @@ func (m UIModel) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m = m.rebuildTable()
 		return m, nil
 
+	case "C":
+		// Show only the hosts that changed in the last rescan
+		if m.changes == nil {
+			m.statusMessage = "Nothing to compare yet: rescan first"
+			return m, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+				return clearStatusMsg{}
+			})
+		}
+		m.changesOnly = !m.changesOnly
+		m = m.applyFilter().rebuildTable()
+		return m, nil
+
+	case "u":
+		// Show only the hosts missing from the known-devices list
+		if m.known == nil {
+			m.statusMessage = "No known-devices file loaded (see --known)"
+			return m, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+				return clearStatusMsg{}
+			})
+		}
+		m.unknownOnly = !m.unknownOnly
+		m = m.applyFilter().rebuildTable()
+		return m, nil
+
 	case "r":
-		// Trigger network rescan
+		// Trigger network rescan (or re-read the report in file mode)
 		if m.isScanning {
 			// Already scanning, ignore
 			return m, nil
 		}
-		m.isScanning = true
-		return m, doRescan(m.scanner, m.cidr)
+		return m.startRescan()
+
+	case "a":
+		// Turn automatic rescans on or off
+		m.autoRefresh = !m.autoRefresh
+		if m.refreshInterval == 0 {
+			m.refreshInterval = DefaultRefreshInterval
+		}
+		m.statusMessage = "Auto-refresh off"
+		var refresh tea.Cmd
+		if m.autoRefresh {
+			m.statusMessage = fmt.Sprintf("Auto-refresh every %s", formatInterval(m.refreshInterval))
+			if !m.isScanning {
+				m, refresh = m.scheduleRefresh()
+			}
+		}
+		return m, tea.Batch(refresh, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		}))
 
 	case "c":
 		// Copy IP to clipboard
-		selectedRow := m.table.SelectedRow()
-		if len(selectedRow) > 0 && selectedRow[0] != "No hosts found" {
-			ip := selectedRow[0]
-			if err := clipboard.WriteAll(ip); err == nil {
+		if h, ok := m.selectedHost(); ok && h.IP.IsValid() {
+			if err := clipboard.WriteAll(h.IP.String()); err == nil {
 				m.statusMessage = "IP copied to clipboard!"
 				return m, tea.Tick(2*time.Second, func(time.Time) tea.Msg {
 					return clearStatusMsg{}
@@ -278,11 +605,30 @@ func (m UIModel) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case "enter":
+		// Show or hide the detail pane, which follows the selection
+		m.showDetail = !m.showDetail
+		m = m.rebuildTable()
+		return m, nil
+
+	case "n":
+		// Edit the alias, note and tags of the selected host
+		if h, ok := m.selectedHost(); ok && h.Key() != "" {
+			e, _ := m.notes.Lookup(h.Key())
+			m.noteHost = h
+			m.aliasInput.SetValue(e.Alias)
+			m.noteInput.SetValue(e.Note)
+			m.tagsInput.SetValue(tags.String(e.Tags))
+			m = m.focusNoteField(noteFieldAlias)
+			m.mode = modeNote
+			m.table.Blur()
+			return m, nil
+		}
+
 	case "s":
 		// SSH to selected host
-		selectedRow := m.table.SelectedRow()
-		if len(selectedRow) > 0 && selectedRow[0] != "No hosts found" {
-			m.selectedIP = selectedRow[0]
+		if h, ok := m.selectedHost(); ok && h.IP.IsValid() {
+			m.selectedIP = h.IP.String()
 			m.mode = modeSSHPrompt
 			m.table.Blur()
 			m.usernameInput.Focus()
@@ -290,30 +636,266 @@ func (m UIModel) handleNormalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	var cmd tea.Cmd
-	m.table, cmd = m.table.Update(msg)
-	return m, cmd
+	return m.moveCursor(msg), nil
+}
+
+// moveCursor moves the selection for the table's navigation keys, which
+// the table cannot act on itself as it only holds the rows in view.
+func (m UIModel) moveCursor(msg tea.KeyMsg) UIModel {
+	keys, page := m.table.KeyMap, m.table.Height()
+	switch {
+	case !m.table.Focused():
+		return m
+	case key.Matches(msg, keys.LineUp):
+		m.cursor--
+	case key.Matches(msg, keys.LineDown):
+		m.cursor++
+	case key.Matches(msg, keys.PageUp):
+		m.cursor -= page
+	case key.Matches(msg, keys.PageDown):
+		m.cursor += page
+	case key.Matches(msg, keys.HalfPageUp):
+		m.cursor -= page / 2
+	case key.Matches(msg, keys.HalfPageDown):
+		m.cursor += page / 2
+	case key.Matches(msg, keys.GotoTop):
+		m.cursor = 0
+	case key.Matches(msg, keys.GotoBottom):
+		m.cursor = len(m.filteredHosts) - 1
+	default:
+		return m
+	}
+	return m.rebuildTable()
+}
+
+// startRescan starts a rescan of the targets (or a reload of the report).
+func (m UIModel) startRescan() (UIModel, tea.Cmd) {
+	m.isScanning = true
+	return m, tea.Batch(doRescan(m.scanner, m.targets), tickProgress())
+}
+
+// scheduleRefresh schedules the next automatic rescan, replacing any that
+// is pending. It returns a nil command when auto-refresh is off.
+func (m UIModel) scheduleRefresh() (UIModel, tea.Cmd) {
+	if !m.autoRefresh {
+		return m, nil
+	}
+	m.refreshGen++
+	return m, refreshAfter(m.refreshInterval, m.refreshGen)
+}
+
+// refreshAfter sends an autoRefreshMsg for gen after interval.
+func refreshAfter(interval time.Duration, gen int) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return autoRefreshMsg{gen: gen}
+	})
+}
+
+// finishDeepScan records the outcome of a deep scan. A successful result
+// is kept for the host's detail pane, which is opened on the host right
+// away unless an overlay is in use or the host is filtered out.
+func (m UIModel) finishDeepScan(msg deepScanDoneMsg) (tea.Model, tea.Cmd) {
+	cancelled := m.deepCtx != nil && errors.Is(m.deepCtx.Err(), context.Canceled)
+	if m.cancelDeepScan != nil {
+		m.cancelDeepScan()
+	}
+	m.deepTarget = netip.Addr{}
+	m.deepCtx, m.cancelDeepScan = nil, nil
+
+	switch {
+	case cancelled:
+		m.statusMessage = fmt.Sprintf("Deep scan of %s cancelled", msg.addr)
+	case msg.err != nil:
+		m.statusMessage = fmt.Sprintf("Deep scan of %s failed: %v", msg.addr, msg.err)
+		return m, tea.Tick(5*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		})
+	default:
+		// Copy before writing: earlier models share the map.
+		results := maps.Clone(m.deepResults)
+		if results == nil {
+			results = make(map[netip.Addr]scanner.HostInfo)
+		}
+		results[msg.addr] = msg.host
+		m.deepResults = results
+
+		m.statusMessage = fmt.Sprintf("Deep scan of %s complete", msg.addr)
+		if m.mode == modeNormal {
+			// Show the results: select the host and open its pane
+			for i, h := range m.displayedHosts() {
+				if h.IP == msg.addr {
+					m.cursor = i
+					m.showDetail = true
+					m = m.rebuildTable()
+					break
+				}
+			}
+		}
+	}
+	return m, tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+		return clearStatusMsg{}
+	})
+}
+
+// withDeepResult returns h with the ports and OS found by a finished deep
+// scan of it, if any.
+func (m UIModel) withDeepResult(h scanner.HostInfo) scanner.HostInfo {
+	if r, ok := m.deepResults[h.IP]; ok {
+		h.Ports = r.Ports
+		h.OS = r.OS
+	}
+	return h
+}
+
+// recordSeen notes in the inventory that hosts were found now. A failure
+// is reported in the footer; the hosts stay in the table.
+func (m UIModel) recordSeen(hosts ...scanner.HostInfo) UIModel {
+	if len(hosts) == 0 {
+		return m
+	}
+	if err := m.inventory.Record(m.now(), hosts...); err != nil {
+		m.statusMessage = fmt.Sprintf("Inventory not updated: %v", err)
+	}
+	return m
+}
+
+// tableWidth returns the width available to the table, which leaves room
+// for the detail pane when it is shown beside it.
+func (m UIModel) tableWidth() int {
+	if m.detailBeside() {
+		return m.width - DetailPaneWidth
+	}
+	return m.width
+}
+
+// detailBeside reports whether the detail pane is shown beside the table.
+// On terminals too narrow for both, the pane replaces the table.
+func (m UIModel) detailBeside() bool {
+	return m.showDetail && m.width-DetailPaneWidth >= MinDetailTableWidth
+}
+
+// sourceFile returns the base name of the report the hosts are read from
+// when the scanner reads a file instead of probing the network.
+func (m UIModel) sourceFile() (string, bool) {
+	fs, ok := m.scanner.(scanner.FileScanner)
+	if !ok {
+		return "", false
+	}
+	return filepath.Base(fs.Path()), true
+}
+
+// scannedHosts returns the hosts found by the last scan, leaving out the
+// gone hosts kept from the scan before.
+func (m UIModel) scannedHosts() []scanner.HostInfo {
+	hosts := make([]scanner.HostInfo, 0, len(m.allHosts))
+	for _, h := range m.allHosts {
+		if m.changes[h.Key()].Kind != diff.Removed {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// applyFilter recomputes filteredHosts from allHosts, the search query and
+// the changes-only and unknown-only toggles.
+func (m UIModel) applyFilter() UIModel {
+	hosts := m.allHosts
+	if m.searchActive {
+		q, _ := query.Parse(m.searchQuery)
+		hosts = filterHosts(hosts, q, m.marks())
+	}
+	if m.changesOnly {
+		hosts = changedHosts(hosts, m.changes)
+	}
+	if m.unknownOnly {
+		hosts = m.known.Unknown(hosts)
+	}
+	m.filteredHosts = hosts
+	return m
+}
+
+// displayedHosts returns the filtered hosts in the order shown in the table.
+func (m UIModel) displayedHosts() []scanner.HostInfo {
+	return sortHosts(m.filteredHosts, m.sortColumn, m.sortAscending)
+}
+
+// selectedHost returns the host under the table cursor, if any.
+func (m UIModel) selectedHost() (scanner.HostInfo, bool) {
+	hosts := m.displayedHosts()
+	if m.cursor < 0 || m.cursor >= len(hosts) {
+		return scanner.HostInfo{}, false
+	}
+	return hosts[m.cursor], true
+}
+
+// optionalColumns returns the optional columns currently shown. The Label
+// column appears with a known-devices list, the Alias column once a host
+// has an alias, the Tags column once a host has tags, the Ports column
+// once a host with open ports has been found, and the Change column once a
+// rescan found differences.
+func (m UIModel) optionalColumns() []int {
+	var cols []int
+	if hasAliases(m.allHosts, m.marks()) {
+		cols = append(cols, colAlias)
+	}
+	if hasTags(m.allHosts, m.marks()) {
+		cols = append(cols, colTags)
+	}
+	if m.known != nil {
+		cols = append(cols, colLabel)
+	}
+	if m.showTiming {
+		cols = append(cols, colRTT, colReason)
+	}
+	if hasPorts(m.allHosts) {
+		cols = append(cols, colPorts)
+	}
+	if len(m.changes) > 0 {
+		cols = append(cols, colChange)
+	}
+	return cols
+}
+
+// marks returns what the table shows about hosts besides the scan results.
+func (m UIModel) marks() hostMarks {
+	return hostMarks{changes: m.changes, known: m.known, notes: m.notes}
 }
 
 // rebuildTable rebuilds the table with current filter and sort settings.
-// Uses stored terminal dimensions for responsive column sizing.
+// Uses stored terminal dimensions for responsive column sizing. The cursor
+// is kept on the displayed hosts and scrolled into view, and only the rows
+// in view are given to the table.
 func (m UIModel) rebuildTable() UIModel {
 	// Apply sort to filtered hosts
-	hostsToDisplay := m.filteredHosts
-	if m.sortColumn > 0 {
-		hostsToDisplay = sortHosts(hostsToDisplay, m.sortColumn, m.sortAscending)
-	}
+	hostsToDisplay := m.displayedHosts()
 
 	// Rebuild columns with stored width
 	weights := DefaultColumnWeights()
-	columns := buildColumns(m.width, weights, m.sortColumn, m.sortAscending)
+	optional := m.optionalColumns()
+	columns := buildColumns(m.tableWidth(), weights, m.sortColumn, m.sortAscending, optional...)
+
+	// Scroll as little as needed to keep the cursor in view, without
+	// leaving blank lines below the last host
+	height := max(m.table.Height(), 1)
+	m.cursor = max(min(m.cursor, len(hostsToDisplay)-1), 0)
+	m.offset = min(max(m.offset, m.cursor-height+1), m.cursor)
+	m.offset = max(min(m.offset, len(hostsToDisplay)-height), 0)
 
 	// Rebuild rows
-	rows := buildRows(hostsToDisplay)
+	visible := hostsToDisplay[m.offset:min(m.offset+height, len(hostsToDisplay))]
+	rows := buildRows(visible, m.marks(), optional...)
 
 	// Update table
-	m.table.SetColumns(columns)
-	m.table.SetRows(rows)
+	// The table renders every cell of a row against its column, so when
+	// the column count changes the narrower of the two must be set first.
+	if len(columns) < len(m.table.Columns()) {
+		m.table.SetRows(rows)
+		m.table.SetColumns(columns)
+	} else {
+		m.table.SetColumns(columns)
+		m.table.SetRows(rows)
+	}
+	m.table.SetCursor(m.cursor - m.offset)
 
 	return m
 }
@@ -2,20 +2,31 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
+	"nls/internal/inventory"
+	"nls/internal/known"
+	"nls/internal/progress"
+	"nls/internal/query"
 	"nls/internal/scanner"
 )
 
 func TestUpdate_ClearStatusMsg(t *testing.T) {
 	// Create model with a status message
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.statusMessage = "Test message"
 
 	// Send clearStatusMsg
@@ -30,9 +41,9 @@ func TestUpdate_ClearStatusMsg(t *testing.T) {
 
 func TestUpdate_QuitKey(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	tests := []struct {
 		name string
@@ -59,9 +70,9 @@ func TestUpdate_QuitKey(t *testing.T) {
 
 func TestUpdate_EscToggleFocus(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	initialFocus := model.table.Focused()
 
 	msg := tea.KeyMsg{Type: tea.KeyEsc}
@@ -75,9 +86,9 @@ func TestUpdate_EscToggleFocus(t *testing.T) {
 
 func TestUpdate_SSHPrompt(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	// Simulate pressing 's' key
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")}
@@ -94,9 +105,9 @@ func TestUpdate_SSHPrompt(t *testing.T) {
 
 func TestUpdate_SSHPromptEscape(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 	model.mode = modeSSHPrompt
 	model.selectedIP = "192.168.1.10"
 	model.usernameInput.SetValue("testuser")
@@ -116,10 +127,10 @@ func TestUpdate_SSHPromptEscape(t *testing.T) {
 
 func TestHandleNormalKeys_CopyIP_ValidHost(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.100", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
-		{IP: "192.168.1.101", MAC: "11:22:33:44:55:66", Vendor: "Test2", Hostname: "test2.local"},
+		{IP: netip.MustParseAddr("192.168.1.100"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.101"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Test2", Hostname: "test2.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	// Note: We can't reliably test clipboard.WriteAll() without mocking or system access.
 	// This test verifies the state changes when 'c' is pressed.
@@ -142,7 +153,7 @@ func TestHandleNormalKeys_CopyIP_ValidHost(t *testing.T) {
 
 func TestHandleNormalKeys_CopyIP_NoHostsFound(t *testing.T) {
 	// Empty hosts list creates a "No hosts found" row
-	model := NewUIModel([]scanner.HostInfo{}, nil, "")
+	model := NewUIModel([]scanner.HostInfo{}, nil, scanner.Targets{})
 	initialMessage := model.statusMessage
 
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")}
@@ -160,7 +171,7 @@ func TestHandleNormalKeys_CopyIP_NoHostsFound(t *testing.T) {
 
 func TestHandleNormalKeys_SSHWithNoHostsFound(t *testing.T) {
 	// Empty hosts list creates a "No hosts found" row
-	model := NewUIModel([]scanner.HostInfo{}, nil, "")
+	model := NewUIModel([]scanner.HostInfo{}, nil, scanner.Targets{})
 
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")}
 	updatedModel, _ := model.Update(msg)
@@ -174,7 +185,7 @@ func TestHandleNormalKeys_SSHWithNoHostsFound(t *testing.T) {
 
 func TestUpdate_SSHDoneMsg(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.10", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.10"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
 
 	tests := []struct {
@@ -199,7 +210,7 @@ func TestUpdate_SSHDoneMsg(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			model := NewUIModel(hosts, nil, "")
+			model := NewUIModel(hosts, nil, scanner.Targets{})
 			model.mode = modeSSHPrompt
 
 			updatedModel, _ := model.Update(sshDoneMsg{err: tt.err})
@@ -222,9 +233,13 @@ func TestUpdate_SSHDoneMsg(t *testing.T) {
 type mockScanner struct {
 	hosts []scanner.HostInfo
 	err   error
+
+	// scanned records the targets of the last scan
+	scanned scanner.Targets
 }
 
-func (m *mockScanner) Scan(ctx context.Context, target string) ([]scanner.HostInfo, error) {
+func (m *mockScanner) Scan(ctx context.Context, targets scanner.Targets) ([]scanner.HostInfo, error) {
+	m.scanned = targets
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -233,16 +248,16 @@ func (m *mockScanner) Scan(ctx context.Context, target string) ([]scanner.HostIn
 
 func TestUpdate_RescanTrigger(t *testing.T) {
 	initialHosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Initial", Hostname: "test1"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Initial", Hostname: "test1"},
 	}
 
 	mockScan := &mockScanner{
 		hosts: []scanner.HostInfo{
-			{IP: "192.168.1.2", MAC: "11:22:33:44:55:66", Vendor: "Rescanned", Hostname: "test2"},
+			{IP: netip.MustParseAddr("192.168.1.2"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Rescanned", Hostname: "test2"},
 		},
 	}
 
-	model := NewUIModel(initialHosts, mockScan, "192.168.1.0/24")
+	model := NewUIModel(initialHosts, mockScan, scanner.NewTargets("192.168.1.0/24"))
 
 	// Trigger rescan with 'r' key
 	keyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}}
@@ -258,17 +273,34 @@ func TestUpdate_RescanTrigger(t *testing.T) {
 	}
 }
 
+func TestDoRescan_ReusesAllTargets(t *testing.T) {
+	mockScan := &mockScanner{}
+	targets := scanner.Targets{
+		Include: []string{"10.0.0.0/24", "10.0.1.1-50", "nas.local"},
+		Exclude: []string{"10.0.0.1"},
+	}
+	model := NewUIModel(nil, mockScan, targets)
+
+	msg := doRescan(model.scanner, model.targets)()
+	if _, ok := msg.(rescanCompleteMsg); !ok {
+		t.Fatalf("doRescan() = %T; want rescanCompleteMsg", msg)
+	}
+	if !reflect.DeepEqual(mockScan.scanned, targets) {
+		t.Errorf("rescan targets = %+v; want %+v", mockScan.scanned, targets)
+	}
+}
+
 func TestUpdate_RescanComplete(t *testing.T) {
 	initialHosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Initial", Hostname: "test1"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Initial", Hostname: "test1"},
 	}
 
 	newHosts := []scanner.HostInfo{
-		{IP: "192.168.1.2", MAC: "11:22:33:44:55:66", Vendor: "Rescanned", Hostname: "test2"},
-		{IP: "192.168.1.3", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "New", Hostname: "test3"},
+		{IP: netip.MustParseAddr("192.168.1.2"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Rescanned", Hostname: "test2"},
+		{IP: netip.MustParseAddr("192.168.1.3"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "New", Hostname: "test3"},
 	}
 
-	model := NewUIModel(initialHosts, nil, "192.168.1.0/24")
+	model := NewUIModel(initialHosts, nil, scanner.NewTargets("192.168.1.0/24"))
 	model.isScanning = true
 
 	// Simulate rescan completion
@@ -280,16 +312,17 @@ func TestUpdate_RescanComplete(t *testing.T) {
 		t.Error("isScanning should be false after rescan completes")
 	}
 
-	if len(m.allHosts) != 2 {
-		t.Errorf("allHosts length = %d; want 2", len(m.allHosts))
+	// The host that is gone stays in the table
+	if len(m.allHosts) != 3 {
+		t.Errorf("allHosts length = %d; want 3", len(m.allHosts))
 	}
 
-	if len(m.filteredHosts) != 2 {
-		t.Errorf("filteredHosts length = %d; want 2", len(m.filteredHosts))
+	if len(m.filteredHosts) != 3 {
+		t.Errorf("filteredHosts length = %d; want 3", len(m.filteredHosts))
 	}
 
-	if m.statusMessage != "Rescan complete: 2 host(s) found" {
-		t.Errorf("statusMessage = %q; want 'Rescan complete: 2 host(s) found'", m.statusMessage)
+	if want := "Rescan complete: 2 host(s) found, +2 new, −1 gone, 0 changed"; m.statusMessage != want {
+		t.Errorf("statusMessage = %q; want %q", m.statusMessage, want)
 	}
 
 	if cmd == nil {
@@ -297,12 +330,302 @@ func TestUpdate_RescanComplete(t *testing.T) {
 	}
 }
 
+// mockFileScanner is a mockScanner that reads its hosts from a file.
+type mockFileScanner struct {
+	mockScanner
+	path string
+}
+
+func (m *mockFileScanner) Path() string {
+	return m.path
+}
+
+func TestUpdate_RescanComplete_FromFile(t *testing.T) {
+	s := &mockFileScanner{path: "/tmp/scans/office.xml"}
+	model := NewUIModel(nil, s, scanner.Targets{})
+	model.isScanning = true
+
+	if got := model.renderScanIndicator(); got != "⏳ Reading office.xml... [0 found]" {
+		t.Errorf("renderScanIndicator() = %q", got)
+	}
+
+	updatedModel, _ := model.Update(rescanCompleteMsg{hosts: []scanner.HostInfo{{IP: netip.MustParseAddr("10.0.0.1")}}})
+	m := updatedModel.(UIModel)
+	if want := "Reloaded office.xml: 1 host(s) found, +1 new, −0 gone, 0 changed"; m.statusMessage != want {
+		t.Errorf("statusMessage = %q; want %q", m.statusMessage, want)
+	}
+	if view := m.View(); !strings.Contains(view, "[r: reload]") {
+		t.Errorf("footer should offer a reload, got %q", view)
+	}
+}
+
+func TestUpdate_RescanChanges(t *testing.T) {
+	router := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("00:11:22:33:44:01")}
+	laptop := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.20"), MAC: mustParseMAC("00:11:22:33:44:20")}
+	printer := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.40"), MAC: mustParseMAC("00:11:22:33:44:40")}
+	phone := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.50"), MAC: mustParseMAC("00:11:22:33:44:50")}
+	moved := laptop
+	moved.IP = netip.MustParseAddr("192.168.1.31")
+
+	model := NewUIModel([]scanner.HostInfo{router, laptop, printer}, nil, scanner.NewTargets("192.168.1.0/24"))
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	updatedModel, _ = updatedModel.(UIModel).Update(rescanCompleteMsg{hosts: []scanner.HostInfo{router, moved, phone}})
+	m := updatedModel.(UIModel)
+
+	if want := "Rescan complete: 3 host(s) found, +1 new, −1 gone, 1 changed"; m.statusMessage != want {
+		t.Errorf("statusMessage = %q; want %q", m.statusMessage, want)
+	}
+	// Gone hosts follow the hosts found until the table is sorted
+	var labels []string
+	for _, row := range m.table.Rows() {
+		labels = append(labels, row[len(row)-1])
+	}
+	if want := []string{"", labelChanged, labelNew, labelGone}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("Change column = %q; want %q", labels, want)
+	}
+
+	// Only the changed hosts are shown with C
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("C")})
+	m = updatedModel.(UIModel)
+	if len(m.filteredHosts) != 3 || !strings.Contains(m.View(), "[Changes only]") {
+		t.Errorf("changes only shows %d host(s); want 3 with the filter in the footer", len(m.filteredHosts))
+	}
+
+	// A second rescan compares with the hosts found, not the gone ones
+	updatedModel, _ = m.Update(rescanCompleteMsg{hosts: []scanner.HostInfo{router, moved, phone}})
+	m = updatedModel.(UIModel)
+	if len(m.allHosts) != 3 || len(m.changes) != 0 || len(m.filteredHosts) != 0 {
+		t.Errorf("after an identical rescan: %d host(s), %d change(s), %d shown; want 3, 0, 0", len(m.allHosts), len(m.changes), len(m.filteredHosts))
+	}
+	if !strings.HasSuffix(m.statusMessage, "no changes") {
+		t.Errorf("statusMessage = %q; want no changes reported", m.statusMessage)
+	}
+}
+
+func TestUpdate_ChangesOnlyBeforeRescan(t *testing.T) {
+	model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24"))
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("C")})
+	m := updatedModel.(UIModel)
+	if m.changesOnly || !strings.Contains(m.statusMessage, "rescan first") {
+		t.Errorf("changesOnly = %v, statusMessage %q; want the filter refused until a rescan", m.changesOnly, m.statusMessage)
+	}
+}
+
+func TestStyleRows_Gone(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+
+	var hosts []scanner.HostInfo
+	for i := 1; i <= 20; i++ {
+		hosts = append(hosts, scanner.HostInfo{IP: netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})})
+	}
+	// A hostname reading like the Change label must not grey its row
+	hosts[1].Hostname = labelGone
+
+	model := NewUIModel(hosts, nil, scanner.Targets{})
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 60, Height: 12})
+	updatedModel, _ = updatedModel.(UIModel).Update(rescanCompleteMsg{hosts: hosts[:18]})
+	m := updatedModel.(UIModel)
+
+	on, _, _ := strings.Cut(goneStyle.Render("x"), "x")
+	check := func(m UIModel) {
+		t.Helper()
+		selected := m.displayedHosts()[m.cursor].IPString()
+		lines := strings.Split(m.styleRows(m.table.View()), "\n")
+		for _, line := range lines[tableHeaderLines:] {
+			fields := strings.Fields(stripANSI(line))
+			if len(fields) == 0 {
+				continue
+			}
+			ip := fields[0]
+			gone := (ip == "10.0.0.19" || ip == "10.0.0.20") && ip != selected
+			if strings.HasPrefix(line, on) != gone {
+				t.Errorf("row of %s greyed = %v; want %v", ip, !gone, gone)
+			}
+		}
+	}
+	check(m)
+
+	// The rows are still told apart once the table has scrolled
+	for _, k := range []string{"G", "k", "k", "k", "g"} {
+		updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = updatedModel.(UIModel)
+		check(m)
+	}
+}
+
+func TestMoveCursor(t *testing.T) {
+	var hosts []scanner.HostInfo
+	for i := 1; i <= 20; i++ {
+		hosts = append(hosts, scanner.HostInfo{IP: netip.AddrFrom4([4]byte{10, 0, 0, byte(i)})})
+	}
+	model := NewUIModel(hosts, nil, scanner.Targets{})
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 80, Height: 12})
+	m := updatedModel.(UIModel)
+	page := m.table.Height()
+
+	tests := []struct {
+		key        tea.KeyMsg
+		wantCursor int
+		wantOffset int
+	}{
+		{tea.KeyMsg{Type: tea.KeyDown}, 1, 0},
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")}, 19, 20 - page},
+		{tea.KeyMsg{Type: tea.KeyUp}, 18, 20 - page},
+		{tea.KeyMsg{Type: tea.KeyPgUp}, 18 - page, 18 - page},
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")}, 0, 0},
+		{tea.KeyMsg{Type: tea.KeyUp}, 0, 0},
+		{tea.KeyMsg{Type: tea.KeyPgDown}, page, 1},
+	}
+	for _, tt := range tests {
+		updatedModel, _ = m.Update(tt.key)
+		m = updatedModel.(UIModel)
+		if m.cursor != tt.wantCursor || m.offset != tt.wantOffset {
+			t.Fatalf("after %s: cursor %d, offset %d; want %d, %d", tt.key, m.cursor, m.offset, tt.wantCursor, tt.wantOffset)
+		}
+		// The table holds the rows in view, the first one on top
+		rows := m.table.Rows()
+		if len(rows) != page || rows[0][0] != hosts[m.offset].IPString() || m.table.Cursor() != m.cursor-m.offset {
+			t.Fatalf("after %s: table rows from %s with cursor %d; want %d rows from %s", tt.key, rows[0][0], m.table.Cursor(), page, hosts[m.offset].IPString())
+		}
+		if h, _ := m.selectedHost(); h.IP != hosts[m.cursor].IP {
+			t.Fatalf("after %s: selected %s; want %s", tt.key, h.IP, hosts[m.cursor].IP)
+		}
+	}
+
+	// A filter that leaves fewer hosts keeps the cursor on one of them
+	m.searchActive, m.searchQuery = true, "10.0.0.2"
+	m = m.applyFilter().rebuildTable()
+	if m.cursor >= len(m.filteredHosts) || m.offset != 0 {
+		t.Errorf("after filtering: cursor %d, offset %d for %d hosts", m.cursor, m.offset, len(m.filteredHosts))
+	}
+}
+
+func TestWithKnownDevices(t *testing.T) {
+	list, err := known.Read(strings.NewReader("00:11:22:33:44:01, Router, IT, 192.168.1.1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("00:11:22:33:44:01")}
+	stranger := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.66"), MAC: mustParseMAC("66:11:22:33:44:66")}
+
+	model := NewUIModel([]scanner.HostInfo{router, stranger}, nil, scanner.NewTargets("192.168.1.0/24")).
+		WithKnownDevices(list)
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	m := updatedModel.(UIModel)
+
+	var labels []string
+	for _, row := range m.table.Rows() {
+		labels = append(labels, row[len(row)-1])
+	}
+	if want := []string{"Router", labelUnknown}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("Label column = %q; want %q", labels, want)
+	}
+	if pane := m.renderDetailPane(); !strings.Contains(pane, "Owner:      IT") || !strings.Contains(pane, "Expected:   192.168.1.1") {
+		t.Errorf("detail pane should describe the known device, got:\n%s", pane)
+	}
+
+	// Only the unknown host is shown with u
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	m = updatedModel.(UIModel)
+	if len(m.filteredHosts) != 1 || m.filteredHosts[0].IP != stranger.IP || !strings.Contains(m.View(), "[Unknown only]") {
+		t.Errorf("unknown only shows %v; want %s with the filter in the footer", m.filteredHosts, stranger.IP)
+	}
+
+	// Without a list there is nothing to flag
+	updatedModel, _ = NewUIModel(nil, nil, scanner.Targets{}).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	if m := updatedModel.(UIModel); m.unknownOnly || !strings.Contains(m.statusMessage, "No known-devices file") {
+		t.Errorf("unknownOnly = %v, statusMessage %q; want the filter refused without a list", m.unknownOnly, m.statusMessage)
+	}
+}
+
+func TestStyleRows_Unknown(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+
+	list, err := known.Read(strings.NewReader("00:11:22:33:44:01, Router\n00:11:22:33:44:02, NAS\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("00:11:22:33:44:01")},
+		// A hostname reading like the unknown label must not flag its row
+		{IP: netip.MustParseAddr("192.168.1.2"), MAC: mustParseMAC("00:11:22:33:44:02"), Hostname: labelUnknown},
+		{IP: netip.MustParseAddr("192.168.1.3"), MAC: mustParseMAC("66:11:22:33:44:03")},
+		{IP: netip.MustParseAddr("192.168.1.4"), MAC: mustParseMAC("66:11:22:33:44:04")},
+	}
+	model := NewUIModel(hosts, nil, scanner.Targets{}).WithKnownDevices(list)
+	// Narrow enough to truncate the Label column
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 60, Height: 30})
+	m := updatedModel.(UIModel)
+
+	on, _, _ := strings.Cut(unknownStyle.Render("x"), "x")
+	lines := strings.Split(m.styleRows(m.table.View()), "\n")
+	var flagged []int
+	for k, line := range lines[tableHeaderLines:] {
+		if strings.HasPrefix(line, on) {
+			flagged = append(flagged, k)
+		}
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(flagged, want) {
+		t.Errorf("flagged rows %v; want %v", flagged, want)
+	}
+}
+
+func TestHighlightLine(t *testing.T) {
+	const on = "\x1b[7m"
+	spans := []query.Span{{Start: 1, End: 3}}
+	if got, want := highlightLine("abcd", spans, on), "a"+on+"bc"+ansiReset+"d"; got != want {
+		t.Errorf("highlightLine() = %q; want %q", got, want)
+	}
+
+	// In a styled row the style comes back after the span, and the row's
+	// own sequences inside it do not end it
+	sel := "\x1b[1m"
+	line := sel + "a" + ansiReset + sel + "b" + ansiReset + sel + "c" + ansiReset
+	want := sel + "a" + ansiReset + sel + on + "b" + ansiReset + on + sel + on + "c" + ansiReset + sel + ansiReset
+	if got := highlightLine(line, []query.Span{{Start: 1, End: 3}}, on); got != want {
+		t.Errorf("highlightLine() = %q; want %q", got, want)
+	}
+	if got := stripANSI(want); got != "abc" {
+		t.Errorf("stripANSI() = %q", got)
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("10.0.1.5"), Vendor: "Apple", Hostname: "apple-tv"},
+		{IP: netip.MustParseAddr("10.0.2.6"), Vendor: "Samsung", Hostname: "tv"},
+	}
+	model := NewUIModel(hosts, nil, scanner.Targets{})
+	model.searchActive = true
+	model.searchQuery = "host:tv OR ip:10.0.1.0/24"
+	m := model.applyFilter().rebuildTable()
+
+	on, _, _ := strings.Cut(matchStyle.Render("x"), "x")
+	lines := strings.Split(m.View(), "\n")
+	header, selected, other := lines[1], lines[3], lines[4]
+	if strings.Contains(header, on) {
+		t.Errorf("the header should not be highlighted: %q", header)
+	}
+	// The selected row styles each character on its own
+	if !strings.Contains(selected, on+"1") || !strings.Contains(selected, on+"v") || !strings.Contains(stripANSI(selected), "apple-tv") {
+		t.Errorf("the selected row should highlight its IP and tv: %q", selected)
+	}
+	if !strings.Contains(other, on+"tv"+ansiReset) || strings.Contains(other, on+"10.0.2.6") {
+		t.Errorf("the other row should highlight tv only: %q", other)
+	}
+}
+
 func TestUpdate_RescanError(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test"},
 	}
 
-	model := NewUIModel(hosts, nil, "192.168.1.0/24")
+	model := NewUIModel(hosts, nil, scanner.NewTargets("192.168.1.0/24"))
 	model.isScanning = true
 
 	// Simulate rescan error
@@ -324,42 +647,180 @@ func TestUpdate_RescanError(t *testing.T) {
 	}
 }
 
-func TestUpdate_IgnoreKeysWhileScanning(t *testing.T) {
+func TestUpdate_KeysWorkWhileScanning(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test"},
+	}
+
+	tests := []struct {
+		key      string
+		wantMode viewMode
+	}{
+		{key: "/", wantMode: modeSearch},
+		{key: "?", wantMode: modeHelp},
+		{key: "s", wantMode: modeSSHPrompt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			model := NewUIModel(hosts, nil, scanner.NewTargets("192.168.1.0/24"))
+			model.isScanning = true
+
+			keyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)}
+			updatedModel, _ := model.Update(keyMsg)
+			m := updatedModel.(UIModel)
+
+			if m.mode != tt.wantMode {
+				t.Errorf("mode = %v after %q during scan; want %v", m.mode, tt.key, tt.wantMode)
+			}
+		})
 	}
+}
 
-	model := NewUIModel(hosts, nil, "192.168.1.0/24")
+func TestUpdate_RescanIgnoredWhileScanning(t *testing.T) {
+	model := NewUIModel(nil, &mockScanner{}, scanner.NewTargets("192.168.1.0/24"))
 	model.isScanning = true
 
-	// Try to trigger actions while scanning
-	keys := []string{"q", "/", "?", "s", "y", "1"}
-	for _, key := range keys {
-		keyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{rune(key[0])}}
-		updatedModel, cmd := model.Update(keyMsg)
+	keyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}}
+	_, cmd := model.Update(keyMsg)
+	if cmd != nil {
+		t.Error("rescan should not start while a scan is running")
+	}
+}
+
+func TestUIModel_StartScan(t *testing.T) {
+	mockScan := &mockScanner{
+		hosts: []scanner.HostInfo{
+			{IP: netip.MustParseAddr("192.168.1.2"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Streamed", Hostname: "test2"},
+		},
+	}
+	model := NewUIModel(nil, mockScan, scanner.NewTargets("192.168.1.0/24")).StartScan(context.Background())
+
+	if !model.isScanning {
+		t.Error("isScanning should be true after StartScan")
+	}
+	if model.Init() == nil {
+		t.Fatal("Init should return commands that start the scan")
+	}
+
+	// Run the scan directly and feed its events back into the model.
+	go runScan(model.scanCtx, model.scanQuit, model.scanner, model.targets, model.scanEvents)()
+	var m tea.Model = model
+	for {
+		msg := <-model.scanEvents
+		m, _ = m.Update(msg)
+		if _, done := msg.(scanDoneMsg); done {
+			break
+		}
+	}
+
+	got := m.(UIModel)
+	if got.isScanning {
+		t.Error("isScanning should be false after the scan completes")
+	}
+	if len(got.allHosts) != 1 || got.allHosts[0].IP != netip.MustParseAddr("192.168.1.2") {
+		t.Errorf("allHosts = %+v; want the streamed host", got.allHosts)
+	}
+	if got.statusMessage != "Scan complete: 1 host(s) found" {
+		t.Errorf("statusMessage = %q", got.statusMessage)
+	}
+}
+
+func TestUpdate_HostFound(t *testing.T) {
+	model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24")).StartScan(context.Background())
+	model.searchActive = true
+	model.searchQuery = "apple"
+
+	updatedModel, cmd := model.Update(hostFoundMsg{host: scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5"), Vendor: "Apple"}})
+	m := updatedModel.(UIModel)
+	updatedModel, _ = m.Update(hostFoundMsg{host: scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.6"), Vendor: "Dell"}})
+	m = updatedModel.(UIModel)
+
+	if cmd == nil {
+		t.Error("hostFoundMsg should wait for the next scan event")
+	}
+	if len(m.allHosts) != 2 {
+		t.Errorf("allHosts length = %d; want 2", len(m.allHosts))
+	}
+	if len(m.filteredHosts) != 1 {
+		t.Errorf("filteredHosts length = %d; want 1 (active filter applies to streamed hosts)", len(m.filteredHosts))
+	}
+	if len(m.table.Rows()) != 1 {
+		t.Errorf("table rows = %d; want 1", len(m.table.Rows()))
+	}
+}
+
+func TestUpdate_HostFound_ShowsPortsColumn(t *testing.T) {
+	model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24")).StartScan(context.Background())
+
+	updatedModel, _ := model.Update(hostFoundMsg{host: scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5")}})
+	m := updatedModel.(UIModel)
+	if n := len(m.table.Columns()); n != 4 {
+		t.Fatalf("table columns = %d; want 4 before any open port is found", n)
+	}
+
+	updatedModel, _ = m.Update(hostFoundMsg{host: scanner.HostInfo{
+		IP:    netip.MustParseAddr("192.168.1.6"),
+		Ports: []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh"}, {Number: 80, Protocol: "tcp", Service: "http"}},
+	}})
+	m = updatedModel.(UIModel)
+	_ = m.View()
+
+	columns := m.table.Columns()
+	if len(columns) != 5 || columns[4].Title != "Ports" {
+		t.Fatalf("table columns = %+v; want a Ports column", columns)
+	}
+	rows := m.table.Rows()
+	if rows[0][4] != placeholder || rows[1][4] != "22,80" {
+		t.Errorf("ports cells = %q, %q; want %q, %q", rows[0][4], rows[1][4], placeholder, "22,80")
+	}
+}
+
+func TestUpdate_ScanDoneWithError(t *testing.T) {
+	scanErr := fmt.Errorf("permission denied")
+
+	t.Run("no hosts quits and reports error", func(t *testing.T) {
+		model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24")).StartScan(context.Background())
+		updatedModel, cmd := model.Update(scanDoneMsg{err: scanErr})
 		m := updatedModel.(UIModel)
 
-		if m.mode != modeNormal {
-			t.Errorf("mode changed to %v when key %q pressed during scan; want modeNormal", m.mode, key)
+		if cmd == nil {
+			t.Fatal("expected quit command")
 		}
+		if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Error("expected tea.QuitMsg")
+		}
+		if m.Err() != scanErr {
+			t.Errorf("Err() = %v; want %v", m.Err(), scanErr)
+		}
+	})
+
+	t.Run("partial results stay browsable", func(t *testing.T) {
+		model := NewUIModel([]scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.1")}}, nil, scanner.NewTargets("192.168.1.0/24"))
+		model.isScanning = true
+		updatedModel, _ := model.Update(scanDoneMsg{err: scanErr})
+		m := updatedModel.(UIModel)
 
-		if cmd != nil {
-			t.Errorf("command returned for key %q during scan; want nil", key)
+		if m.Err() != nil {
+			t.Errorf("Err() = %v; want nil", m.Err())
 		}
-	}
+		if m.statusMessage != "Scan failed: permission denied" {
+			t.Errorf("statusMessage = %q", m.statusMessage)
+		}
+	})
 }
 
 func TestUpdate_RescanWithActiveFilter(t *testing.T) {
 	initialHosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Initial", Hostname: "test1"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Initial", Hostname: "test1"},
 	}
 
 	newHosts := []scanner.HostInfo{
-		{IP: "192.168.1.2", MAC: "11:22:33:44:55:66", Vendor: "Apple", Hostname: "test2"},
-		{IP: "192.168.1.3", MAC: "AA:AA:AA:AA:AA:AA", Vendor: "Samsung", Hostname: "test3"},
+		{IP: netip.MustParseAddr("192.168.1.2"), MAC: mustParseMAC("11:22:33:44:55:66"), Vendor: "Apple", Hostname: "test2"},
+		{IP: netip.MustParseAddr("192.168.1.3"), MAC: mustParseMAC("AA:AA:AA:AA:AA:AA"), Vendor: "Samsung", Hostname: "test3"},
 	}
 
-	model := NewUIModel(initialHosts, nil, "192.168.1.0/24")
+	model := NewUIModel(initialHosts, nil, scanner.NewTargets("192.168.1.0/24"))
 	model.searchActive = true
 	model.searchQuery = "Apple"
 	model.filteredHosts = initialHosts // Simulate previous filter
@@ -382,9 +843,9 @@ func TestUpdate_RescanWithActiveFilter(t *testing.T) {
 
 func TestUpdate_WindowResize(t *testing.T) {
 	hosts := []scanner.HostInfo{
-		{IP: "192.168.1.1", MAC: "AA:BB:CC:DD:EE:FF", Vendor: "Test", Hostname: "test.local"},
+		{IP: netip.MustParseAddr("192.168.1.1"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF"), Vendor: "Test", Hostname: "test.local"},
 	}
-	model := NewUIModel(hosts, nil, "")
+	model := NewUIModel(hosts, nil, scanner.Targets{})
 
 	tests := []struct {
 		name           string
@@ -434,3 +895,321 @@ func TestUpdate_WindowResize(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdate_ProgressTick(t *testing.T) {
+	model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24"))
+
+	model.isScanning = true
+	if _, cmd := model.Update(progressTickMsg{}); cmd == nil {
+		t.Error("progress ticks should continue while scanning")
+	}
+
+	model.isScanning = false
+	if _, cmd := model.Update(progressTickMsg{}); cmd != nil {
+		t.Error("progress ticks should stop once the scan is done")
+	}
+}
+
+func TestRenderScanIndicator(t *testing.T) {
+	hosts := []scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.1")}}
+
+	tests := []struct {
+		name  string
+		setup func(*progress.Tracker)
+		want  string
+	}{
+		{
+			name:  "indeterminate",
+			setup: func(tr *progress.Tracker) { tr.Start("Scanning") },
+			want:  "⏳ Scanning network... [1 found]",
+		},
+		{
+			name: "percent and ETA",
+			setup: func(tr *progress.Tracker) {
+				tr.Start("Scanning")
+				tr.Progress(42, 100, 63*time.Second+400*time.Millisecond)
+			},
+			want: "⏳ Scanning network... 42% (ETA 1m3s) [1 found]",
+		},
+		{
+			name: "percent without ETA",
+			setup: func(tr *progress.Tracker) {
+				tr.Start("Scanning")
+				tr.Progress(1, 4, 0)
+			},
+			want: "⏳ Scanning network... 25% [1 found]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := progress.NewTracker()
+			tt.setup(tracker)
+			model := NewUIModel(hosts, nil, scanner.NewTargets("192.168.1.0/24")).WithProgress(tracker)
+			if got := model.renderScanIndicator(); got != tt.want {
+				t.Errorf("renderScanIndicator() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// mockDeepScanner is a test double that implements scanner.DeepScanner.
+// With block set it waits for the scan to be cancelled.
+type mockDeepScanner struct {
+	host  scanner.HostInfo
+	err   error
+	block bool
+
+	// scanned and osDetection record the last deep scan
+	scanned     netip.Addr
+	osDetection bool
+}
+
+func (m *mockDeepScanner) DeepScan(ctx context.Context, addr netip.Addr, osDetection bool) (scanner.HostInfo, error) {
+	m.scanned, m.osDetection = addr, osDetection
+	if m.block {
+		<-ctx.Done()
+		return scanner.HostInfo{}, ctx.Err()
+	}
+	return m.host, m.err
+}
+
+func TestUpdate_DeepScan(t *testing.T) {
+	hosts := []scanner.HostInfo{
+		{IP: netip.MustParseAddr("192.168.1.5"), Vendor: "Acme", Ports: []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh"}}},
+	}
+	ds := &mockDeepScanner{host: scanner.HostInfo{
+		IP:    netip.MustParseAddr("192.168.1.5"),
+		Ports: []scanner.Port{{Number: 22, Protocol: "tcp", Service: "ssh", Product: "OpenSSH", Version: "9.6p1"}},
+		OS:    "Linux 5.0 - 5.14",
+	}}
+	model := NewUIModel(hosts, nil, scanner.Targets{}).WithDeepScanner(ds)
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	updatedModel, cmd := updatedModel.(UIModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	m := updatedModel.(UIModel)
+	if cmd == nil {
+		t.Fatal("D should return a command running the deep scan")
+	}
+	if !strings.Contains(m.View(), "Deep scan of 192.168.1.5") {
+		t.Error("footer should show the running deep scan")
+	}
+
+	msg := cmd()
+	if ds.scanned != netip.MustParseAddr("192.168.1.5") || !ds.osDetection {
+		t.Errorf("deep scan of %s (OS detection %v); want 192.168.1.5 with OS detection", ds.scanned, ds.osDetection)
+	}
+	updatedModel, _ = m.Update(msg)
+	m = updatedModel.(UIModel)
+
+	if m.deepTarget.IsValid() || m.cancelDeepScan != nil {
+		t.Error("deep scan state should be reset once it finishes")
+	}
+	if !m.showDetail {
+		t.Fatal("the detail pane should open with the results")
+	}
+	view := m.View()
+	for _, want := range []string{"ssh OpenSSH 9.6p1", "Linux 5.0 - 5.14", "Acme"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail pane should contain %q", want)
+		}
+	}
+
+	// The results stay available from the table.
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	updatedModel, _ = updatedModel.(UIModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if view := updatedModel.(UIModel).View(); !strings.Contains(view, "Linux 5.0 - 5.14") {
+		t.Error("reopened detail pane should still show the deep scan result")
+	}
+}
+
+func TestUpdate_DeepScanCancel(t *testing.T) {
+	hosts := []scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.5")}}
+	model := NewUIModel(hosts, nil, scanner.Targets{}).WithDeepScanner(&mockDeepScanner{block: true})
+
+	updatedModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m := updatedModel.(UIModel)
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m = updatedModel.(UIModel)
+
+	// The cancelled scan returns promptly.
+	updatedModel, _ = m.Update(cmd())
+	m = updatedModel.(UIModel)
+	if m.showDetail {
+		t.Error("a cancelled deep scan should not open the detail pane")
+	}
+	if !strings.Contains(m.statusMessage, "cancelled") {
+		t.Errorf("statusMessage = %q; want a cancellation notice", m.statusMessage)
+	}
+	if m.deepTarget.IsValid() {
+		t.Error("deep scan state should be reset after cancellation")
+	}
+}
+
+func TestUpdate_DeepScanOutlivesScanTimeout(t *testing.T) {
+	hosts := []scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.5")}}
+	// The initial scan's context times out long before the user is done
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	ds := &mockDeepScanner{host: hosts[0]}
+	model := NewUIModel(hosts, nil, scanner.Targets{}).WithDeepScanner(ds).StartScan(ctx)
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if msg, ok := cmd().(deepScanDoneMsg); !ok || msg.err != nil {
+		t.Errorf("deep scan finished with %+v; want it unaffected by the expired scan context", msg)
+	}
+}
+
+func TestUpdate_DeepScanCancelledOnQuit(t *testing.T) {
+	hosts := []scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.5")}}
+	model := NewUIModel(hosts, nil, scanner.Targets{}).WithDeepScanner(&mockDeepScanner{block: true})
+
+	updatedModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	updatedModel.(UIModel).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	// The blocked scan returns once the user quits.
+	if msg, ok := cmd().(deepScanDoneMsg); !ok || !errors.Is(msg.err, context.Canceled) {
+		t.Errorf("deep scan finished with %+v; want it cancelled on quit", msg)
+	}
+}
+
+func TestUpdate_DeepScanErrors(t *testing.T) {
+	hosts := []scanner.HostInfo{{IP: netip.MustParseAddr("192.168.1.5")}}
+
+	model := NewUIModel(hosts, nil, scanner.Targets{})
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if m := updatedModel.(UIModel); !strings.Contains(m.statusMessage, "not available") {
+		t.Errorf("statusMessage = %q; want a notice that deep scans are unavailable", m.statusMessage)
+	}
+
+	// Hosts read from a file may not be on this network
+	ds := &mockDeepScanner{}
+	fromFile := NewUIModel(hosts, &mockFileScanner{path: "office.xml"}, scanner.Targets{}).WithDeepScanner(ds)
+	updatedModel, _ = fromFile.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if m := updatedModel.(UIModel); !strings.Contains(m.statusMessage, "not available for hosts read from a file") || m.deepTarget.IsValid() {
+		t.Errorf("statusMessage = %q; want deep scans refused for a file", m.statusMessage)
+	}
+	if ds.scanned.IsValid() {
+		t.Errorf("deep scanned %s; want no scan of hosts read from a file", ds.scanned)
+	}
+
+	model = model.WithDeepScanner(&mockDeepScanner{err: fmt.Errorf("nmap not found")})
+	updatedModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	updatedModel, _ = updatedModel.(UIModel).Update(cmd())
+	m := updatedModel.(UIModel)
+	if m.showDetail || !strings.Contains(m.statusMessage, "nmap not found") {
+		t.Errorf("showDetail %v, statusMessage %q; want the error reported in the footer", m.showDetail, m.statusMessage)
+	}
+}
+
+func TestWithInventory(t *testing.T) {
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF")}
+	store := inventory.NewMemory()
+	earlier := time.Date(2025, 12, 24, 18, 0, 0, 0, time.Local)
+	if err := store.Record(earlier, host); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewUIModel([]scanner.HostInfo{host}, nil, scanner.NewTargets("192.168.1.0/24")).WithInventory(store)
+	d, _ := store.Lookup(host.Key())
+	if !d.FirstSeen.Equal(earlier) || !d.LastSeen.After(earlier) {
+		t.Errorf("device seen %v - %v; want first seen kept and last seen updated", d.FirstSeen, d.LastSeen)
+	}
+
+	m.inventory = failingInventory{store}
+	updatedModel, _ := m.Update(hostFoundMsg{host: host})
+	if got := updatedModel.(UIModel).statusMessage; !strings.Contains(got, "disk full") {
+		t.Errorf("statusMessage = %q; want the inventory error", got)
+	}
+}
+
+// failingInventory is a store whose Record always fails.
+type failingInventory struct {
+	inventory.Store
+}
+
+func (failingInventory) Record(time.Time, ...scanner.HostInfo) error {
+	return fmt.Errorf("disk full")
+}
+
+func TestUpdate_SeenTimes(t *testing.T) {
+	first := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	later := first.Add(2 * time.Hour)
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.5"), MAC: mustParseMAC("AA:BB:CC:DD:EE:FF")}
+
+	model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24")).StartScan(context.Background())
+	model.now = func() time.Time { return first }
+	updatedModel, _ := model.Update(hostFoundMsg{host: host})
+
+	// A rescan finds the same device at a new address.
+	m := updatedModel.(UIModel)
+	m.now = func() time.Time { return later }
+	moved := host
+	moved.IP = netip.MustParseAddr("192.168.1.77")
+	updatedModel, _ = m.Update(rescanCompleteMsg{hosts: []scanner.HostInfo{moved}})
+	m = updatedModel.(UIModel)
+
+	d, ok := m.inventory.Lookup(moved.Key())
+	if !ok || !d.FirstSeen.Equal(first) || !d.LastSeen.Equal(later) {
+		t.Errorf("seen = %v - %v; want %v - %v", d.FirstSeen, d.LastSeen, first, later)
+	}
+
+	updatedModel, _ = m.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	updatedModel, _ = updatedModel.(UIModel).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	view := updatedModel.(UIModel).View()
+	for _, want := range []string{"First seen: 2026-03-01 09:30:00", "Last seen:  2026-03-01 11:30:00", "Previously used addresses:", "192.168.1.5"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("detail pane should contain %q", want)
+		}
+	}
+}
+
+func TestUpdate_AutoRefresh(t *testing.T) {
+	host := scanner.HostInfo{IP: netip.MustParseAddr("192.168.1.1")}
+	model := NewUIModel(nil, &mockScanner{hosts: []scanner.HostInfo{host}}, scanner.NewTargets("192.168.1.0/24")).
+		WithAutoRefresh(time.Minute).
+		StartScan(context.Background())
+
+	// The end of the initial scan schedules the first refresh
+	updatedModel, cmd := model.Update(scanDoneMsg{})
+	m := updatedModel.(UIModel)
+	if cmd == nil || m.refreshGen != 1 {
+		t.Fatalf("refreshGen = %d; want a refresh scheduled after the scan", m.refreshGen)
+	}
+	if !strings.Contains(m.View(), "[Auto-refresh: 1m]") {
+		t.Error("footer should show the auto-refresh interval")
+	}
+
+	// A stale refresh is ignored, the latest one rescans
+	updatedModel, _ = m.Update(autoRefreshMsg{gen: 0})
+	if updatedModel.(UIModel).isScanning {
+		t.Error("a stale refresh should not rescan")
+	}
+	updatedModel, _ = m.Update(autoRefreshMsg{gen: 1})
+	m = updatedModel.(UIModel)
+	if !m.isScanning {
+		t.Fatal("the scheduled refresh should rescan")
+	}
+
+	// Its completion schedules the next one
+	updatedModel, _ = m.Update(rescanCompleteMsg{hosts: []scanner.HostInfo{host}})
+	if m = updatedModel.(UIModel); m.refreshGen != 2 {
+		t.Errorf("refreshGen = %d; want the next refresh scheduled", m.refreshGen)
+	}
+
+	// Turning auto-refresh off drops the pending refresh
+	updatedModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = updatedModel.(UIModel)
+	updatedModel, _ = m.Update(autoRefreshMsg{gen: m.refreshGen})
+	if m = updatedModel.(UIModel); m.autoRefresh || m.isScanning {
+		t.Errorf("autoRefresh %v, isScanning %v; want auto-refresh off", m.autoRefresh, m.isScanning)
+	}
+}
+
+func TestHandleNormalKeys_AutoRefreshDefault(t *testing.T) {
+	model := NewUIModel(nil, nil, scanner.NewTargets("192.168.1.0/24"))
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m := updatedModel.(UIModel)
+	if !m.autoRefresh || m.refreshInterval != DefaultRefreshInterval || m.statusMessage != "Auto-refresh every 5m" {
+		t.Errorf("autoRefresh %v every %v, statusMessage %q; want on every %v", m.autoRefresh, m.refreshInterval, m.statusMessage, DefaultRefreshInterval)
+	}
+}
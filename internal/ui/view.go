@@ -2,9 +2,17 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"nls/internal/diff"
+	"nls/internal/known"
+	"nls/internal/query"
+	"nls/internal/scanner"
 )
 
 // View renders the UI based on current state.
@@ -13,11 +21,11 @@ func (m UIModel) View() string {
 	switch m.mode {
 	case modeHelp:
 		return m.renderHelpView()
-	case modeSearch:
-		return m.renderSearchView()
 	case modeSSHPrompt:
 		return m.renderSSHPromptView()
-	default: // modeNormal
+	case modeNote:
+		return m.renderNoteView()
+	default: // modeNormal and modeSearch, which docks the input in the footer
 		return m.renderNormalView()
 	}
 }
@@ -38,30 +46,33 @@ func (m UIModel) renderHelpView() string {
 	return overlay
 }
 
-// renderSearchView renders the search input overlay.
-func (m UIModel) renderSearchView() string {
-	prompt := fmt.Sprintf("Search/Filter Hosts\n\n%s\n\n[enter: apply filter] [esc: cancel]",
-		m.searchInput.View(),
+// renderSSHPromptView renders the SSH prompt overlay.
+func (m UIModel) renderSSHPromptView() string {
+	prompt := fmt.Sprintf("SSH to %s\n\n%s\n\n[enter: connect] [esc: cancel]",
+		m.selectedIP,
+		m.usernameInput.View(),
 	)
-	searchBox := promptStyle.Render(prompt)
+	promptBox := promptStyle.Render(prompt)
 
 	overlay := lipgloss.Place(
 		m.width,
 		m.height,
 		lipgloss.Center,
 		lipgloss.Center,
-		searchBox,
+		promptBox,
 		lipgloss.WithWhitespaceChars(" "),
 		lipgloss.WithWhitespaceForeground(lipgloss.Color("0")),
 	)
 	return overlay
 }
 
-// renderSSHPromptView renders the SSH prompt overlay.
-func (m UIModel) renderSSHPromptView() string {
-	prompt := fmt.Sprintf("SSH to %s\n\n%s\n\n[enter: connect] [esc: cancel]",
-		m.selectedIP,
-		m.usernameInput.View(),
+// renderNoteView renders the alias, note and tags editor overlay.
+func (m UIModel) renderNoteView() string {
+	prompt := fmt.Sprintf("Alias, note and tags for %s\n\nAlias: %s\nNote:  %s\nTags:  %s\n\n[tab: next field] [enter: save] [esc: cancel]",
+		orPlaceholder(m.noteHost.IPString()),
+		m.aliasInput.View(),
+		m.noteInput.View(),
+		m.tagsInput.View(),
 	)
 	promptBox := promptStyle.Render(prompt)
 
@@ -77,19 +88,161 @@ func (m UIModel) renderSSHPromptView() string {
 	return overlay
 }
 
+// renderDetailPane renders everything known about the selected host.
+func (m UIModel) renderDetailPane() string {
+	h, ok := m.selectedHost()
+	if !ok {
+		return detailStyle.Render("No host selected")
+	}
+	h = m.withDeepResult(h)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", orPlaceholder(h.IPString()))
+	e := m.marks().entry(h)
+	if e.Alias != "" {
+		fmt.Fprintf(&b, "Alias:      %s\n", e.Alias)
+	}
+	if e.Note != "" {
+		fmt.Fprintf(&b, "Note:       %s\n", e.Note)
+	}
+	if t := m.marks().tags(h); len(t) > 0 {
+		fmt.Fprintf(&b, "Tags:       %s\n", strings.Join(t, ", "))
+	}
+	fmt.Fprintf(&b, "MAC:        %s\n", orPlaceholder(h.MACString()))
+	fmt.Fprintf(&b, "Vendor:     %s\n", orPlaceholder(h.Vendor))
+	latency := formatRTT(h.RTT)
+	if latency != "" && h.Reason != "" {
+		latency += " (" + h.Reason + ")"
+	}
+	fmt.Fprintf(&b, "Latency:    %s\n", orPlaceholder(latency))
+	if h.OS != "" {
+		fmt.Fprintf(&b, "OS:         %s\n", h.OS)
+	}
+	if m.known != nil {
+		writeKnownDevice(&b, m.known, h)
+	}
+	if c, ok := m.changes[h.Key()]; ok {
+		change := c.Kind.String() + " since the last scan"
+		if detail := c.Detail(); detail != "" {
+			change = detail
+		}
+		fmt.Fprintf(&b, "Change:     %s\n", change)
+	}
+	device, known := m.inventory.Lookup(h.Key())
+	if known {
+		fmt.Fprintf(&b, "First seen: %s\n", device.FirstSeen.Format(time.DateTime))
+		fmt.Fprintf(&b, "Last seen:  %s\n", device.LastSeen.Format(time.DateTime))
+	}
+	b.WriteString("\nAddresses:\n")
+	writeDetailList(&b, addrStrings(h.Addresses), h.IPString())
+	b.WriteString("\nHostnames:\n")
+	writeDetailList(&b, h.Hostnames, h.Hostname)
+	if previous := previouslyUsed(device.IPs, h.IPString(), addrStrings(h.Addresses)); len(previous) > 0 {
+		b.WriteString("\nPreviously used addresses:\n")
+		writeDetailList(&b, previous, "")
+	}
+	if previous := previouslyUsed(device.Hostnames, h.Hostname, h.Hostnames); len(previous) > 0 {
+		b.WriteString("\nPreviously used hostnames:\n")
+		writeDetailList(&b, previous, "")
+	}
+	b.WriteString("\nOpen ports:\n")
+	ports := make([]string, 0, len(h.Ports))
+	for _, p := range h.Ports {
+		ports = append(ports, strings.TrimSpace(fmt.Sprintf("%-9s %s", p, describeService(p))))
+	}
+	writeDetailList(&b, ports, "")
+	b.WriteString("\n[enter/esc: close]")
+
+	return detailStyle.Render(b.String())
+}
+
+// writeKnownDevice writes what the known-devices list says about h.
+func writeKnownDevice(b *strings.Builder, list *known.List, h scanner.HostInfo) {
+	d, ok := list.Lookup(h)
+	if !ok {
+		fmt.Fprintf(b, "Label:      %s (not in the known-devices file)\n", labelUnknown)
+		return
+	}
+	fmt.Fprintf(b, "Label:      %s\n", d.Label)
+	if d.Owner != "" {
+		fmt.Fprintf(b, "Owner:      %s\n", d.Owner)
+	}
+	if d.IP.IsValid() {
+		expected := d.IP.String()
+		if d.Unexpected(h) {
+			expected += " (not here!)"
+		}
+		fmt.Fprintf(b, "Expected:   %s\n", expected)
+	}
+}
+
+// writeDetailList writes one indented line per item, marking the primary
+// one. Hosts from scanners that do not fill the list show primary alone.
+func writeDetailList(b *strings.Builder, items []string, primary string) {
+	if len(items) == 0 && primary != "" {
+		items = []string{primary}
+	}
+	if len(items) == 0 {
+		b.WriteString("  " + placeholder + "\n")
+		return
+	}
+	for _, item := range items {
+		if item == primary {
+			fmt.Fprintf(b, "  %s (primary)\n", item)
+		} else {
+			fmt.Fprintf(b, "  %s\n", item)
+		}
+	}
+}
+
+// previouslyUsed returns the items of history that are neither primary nor
+// in current.
+func previouslyUsed(history []string, primary string, current []string) []string {
+	var previous []string
+	for _, item := range history {
+		if item != primary && !slices.Contains(current, item) {
+			previous = append(previous, item)
+		}
+	}
+	return previous
+}
+
 // renderNormalView renders the standard table view with footer.
 func (m UIModel) renderNormalView() string {
-	baseView := baseStyle.Render(m.table.View())
+	baseView := baseStyle.Render(m.highlightMatches(m.styleRows(m.table.View())))
+	switch {
+	case m.detailBeside():
+		baseView = lipgloss.JoinHorizontal(lipgloss.Top, baseView, m.renderDetailPane())
+	case m.showDetail:
+		baseView = m.renderDetailPane()
+	}
 
 	// Build footer with all shortcuts
 	footer := "[?: help] [/: search] [1-4: sort] [r: rescan] [c: copy IP] [s: ssh] [q: quit]"
+	if _, ok := m.sourceFile(); ok {
+		footer = strings.Replace(footer, "[r: rescan]", "[r: reload]", 1)
+	}
 
 	// Show scanning indicator if in progress
 	if m.isScanning {
-		footer = "⏳ Scanning network... " + footer
+		footer = m.renderScanIndicator() + " " + footer
+	}
+
+	if m.deepTarget.IsValid() {
+		footer = fmt.Sprintf("🔍 Deep scan of %s... [d: cancel] ", m.deepTarget) + footer
+	}
+
+	if m.autoRefresh {
+		footer = fmt.Sprintf("[Auto-refresh: %s] ", formatInterval(m.refreshInterval)) + footer
 	}
 
 	// Show active filter indicator
+	if m.unknownOnly {
+		footer = "[Unknown only] " + footer
+	}
+	if m.changesOnly {
+		footer = "[Changes only] " + footer
+	}
 	if m.searchActive {
 		footer = fmt.Sprintf("[Filter: %s] ", m.searchQuery) + footer
 	}
@@ -99,9 +252,211 @@ func (m UIModel) renderNormalView() string {
 		footer = m.statusMessage + "  " + footer
 	}
 
+	// The search input replaces the footer while typing
+	if m.mode == modeSearch {
+		footer = m.searchInput.View() + "  [enter: keep filter] [esc: cancel]"
+		if m.searchErr != nil {
+			footer = m.searchInput.View() + "  " + searchErrStyle.Render("Invalid query: "+m.searchErr.Error())
+		}
+	}
+
 	var b strings.Builder
 	b.WriteString(baseView)
 	b.WriteString("\n")
 	b.WriteString(footer)
 	return b.String()
 }
+
+// tableHeaderLines is the height of the table header: the titles and the
+// border under them (see tableStyles).
+const tableHeaderLines = 2
+
+// styleRows styles the rows of the rendered table after the hosts on them:
+// the rows of gone hosts are greyed out and those of hosts missing from
+// the known-devices list flagged. The selected row keeps its own style.
+// The table holds the rows in view only, so line k of its body shows
+// host m.offset+k.
+func (m UIModel) styleRows(rendered string) string {
+	hosts := m.displayedHosts()
+	lines := strings.Split(rendered, "\n")
+	for k := tableHeaderLines; k < len(lines); k++ {
+		i := m.offset + k - tableHeaderLines
+		if i >= len(hosts) {
+			break
+		}
+		if i == m.cursor {
+			continue
+		}
+		_, listed := m.known.Lookup(hosts[i])
+		switch {
+		case m.changes[hosts[i].Key()].Kind == diff.Removed:
+			lines[k] = styleLine(lines[k], goneStyle)
+		case m.known != nil && !listed:
+			lines[k] = styleLine(lines[k], unknownStyle)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// styleLine renders line in style, restarting the style after every reset
+// within the line so that styled cells do not end it early.
+func styleLine(line string, style lipgloss.Style) string {
+	on, _, _ := strings.Cut(style.Render("x"), "x")
+	if on == "" {
+		return line
+	}
+	return on + strings.ReplaceAll(line, ansiReset, ansiReset+on) + ansiReset
+}
+
+// highlightMatches marks, in the rows of the rendered table, the parts of
+// each cell the search query matched (see query.Query.Highlights).
+func (m UIModel) highlightMatches(rendered string) string {
+	on, _, _ := strings.Cut(matchStyle.Render("x"), "x")
+	if !m.searchActive || on == "" {
+		return rendered
+	}
+	q, _ := query.Parse(m.searchQuery)
+	columns := m.table.Columns()
+	ids := append([]int{colIP, colMAC, colVendor, colHostname}, m.optionalColumns()...)
+
+	lines := strings.Split(rendered, "\n")
+	for i := tableHeaderLines; i < len(lines); i++ {
+		plain := stripANSI(lines[i])
+		var spans []query.Span
+		start := 0
+		for c, col := range columns {
+			if col.Width <= 0 {
+				continue
+			}
+			// Cells are padded by one space on each side
+			from := advance(plain, start, 1)
+			to := advance(plain, from, col.Width)
+			start = advance(plain, to, 1)
+			field, ok := columnFields[ids[min(c, len(ids)-1)]]
+			if !ok {
+				continue
+			}
+			for _, s := range q.Highlights(field, strings.TrimRight(plain[from:to], " ")) {
+				spans = append(spans, query.Span{Start: from + s.Start, End: from + s.End})
+			}
+		}
+		lines[i] = highlightLine(lines[i], spans, on)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// columnFields maps the table columns to the query fields they show.
+var columnFields = map[int]query.Field{
+	colIP:       query.FieldIP,
+	colMAC:      query.FieldMAC,
+	colVendor:   query.FieldVendor,
+	colHostname: query.FieldHost,
+	colPorts:    query.FieldPort,
+	colLabel:    query.FieldLabel,
+	colAlias:    query.FieldAlias,
+	colTags:     query.FieldTag,
+}
+
+// advance returns the byte offset in s that is width terminal columns
+// after offset from, or len(s).
+func advance(s string, from, width int) int {
+	i := from
+	for i < len(s) && width > 0 {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		width -= lipgloss.Width(string(r))
+		i += size
+	}
+	return i
+}
+
+// ansiReset ends every style.
+const ansiReset = "\x1b[0m"
+
+// escapeAt returns the ANSI escape sequence starting at s[i], or "".
+func escapeAt(s string, i int) string {
+	if !strings.HasPrefix(s[i:], "\x1b[") {
+		return ""
+	}
+	for j := i + 2; j < len(s); j++ {
+		if s[j] >= 0x40 && s[j] <= 0x7e {
+			return s[i : j+1]
+		}
+	}
+	return s[i:]
+}
+
+// stripANSI returns s without its ANSI escape sequences.
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if seq := escapeAt(s, i); seq != "" {
+			i += len(seq)
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// highlightLine wraps the spans of line, byte ranges of its text without
+// escape sequences, in the on sequence. The style of the line, e.g. of the
+// selected row, is restored after each span, and on is repeated after the
+// line's own sequences inside a span so that they do not end it early.
+func highlightLine(line string, spans []query.Span, on string) string {
+	if len(spans) == 0 {
+		return line
+	}
+	var b strings.Builder
+	pos, k, in := 0, 0, false
+	style := "" // the last sequence of the line, unless it was a reset
+	for i := 0; i < len(line); {
+		if seq := escapeAt(line, i); seq != "" {
+			b.WriteString(seq)
+			i += len(seq)
+			if style = seq; seq == ansiReset {
+				style = ""
+			}
+			if in {
+				b.WriteString(on)
+			}
+			continue
+		}
+		if !in && k < len(spans) && pos == spans[k].Start {
+			b.WriteString(on)
+			in = true
+		}
+		b.WriteByte(line[i])
+		i++
+		pos++
+		if in && pos == spans[k].End {
+			b.WriteString(ansiReset + style)
+			in = false
+			k++
+		}
+	}
+	if in {
+		b.WriteString(ansiReset)
+	}
+	return b.String()
+}
+
+// renderScanIndicator describes the running scan, including percentage
+// and ETA once the scanner reports determinate progress.
+func (m UIModel) renderScanIndicator() string {
+	if name, ok := m.sourceFile(); ok {
+		return fmt.Sprintf("⏳ Reading %s... [%d found]", name, len(m.allHosts))
+	}
+
+	indicator := "⏳ Scanning network..."
+	if m.progress != nil {
+		state := m.progress.Snapshot()
+		if pct, ok := state.Percent(); ok && state.Active {
+			indicator = fmt.Sprintf("⏳ Scanning network... %.0f%%", pct)
+			if state.ETA > 0 {
+				indicator += fmt.Sprintf(" (ETA %s)", state.ETA.Round(time.Second))
+			}
+		}
+	}
+	return fmt.Sprintf("%s [%d found]", indicator, len(m.allHosts))
+}
@@ -0,0 +1,37 @@
+// Package xdg locates the files nls keeps between runs, following the XDG
+// base directory specification.
+package xdg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DataPath returns the path of the data file name: $XDG_DATA_HOME/nls/name,
+// or ~/.local/share/nls/name when XDG_DATA_HOME is not set.
+func DataPath(name string) (string, error) {
+	return path("XDG_DATA_HOME", filepath.Join(".local", "share"), "data", name)
+}
+
+// ConfigPath returns the path of the configuration file name:
+// $XDG_CONFIG_HOME/nls/name, or ~/.config/nls/name when XDG_CONFIG_HOME is
+// not set.
+func ConfigPath(name string) (string, error) {
+	return path("XDG_CONFIG_HOME", ".config", "config", name)
+}
+
+// path returns name in the nls directory under the directory in the
+// environment variable env, or under fallback in the home directory.
+// kind names the directory in errors.
+func path(env, fallback, kind, name string) (string, error) {
+	dir := os.Getenv(env)
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("find %s directory: %w", kind, err)
+		}
+		dir = filepath.Join(home, fallback)
+	}
+	return filepath.Join(dir, "nls", name), nil
+}
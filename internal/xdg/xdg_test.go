@@ -0,0 +1,32 @@
+package xdg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDataPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	if got, _ := DataPath("notes.jsonl"); got != filepath.Join("/data", "nls", "notes.jsonl") {
+		t.Errorf("DataPath() with XDG_DATA_HOME = %q", got)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if got, _ := DataPath("notes.jsonl"); got != filepath.Join("/home/me", ".local", "share", "nls", "notes.jsonl") {
+		t.Errorf("DataPath() without XDG_DATA_HOME = %q", got)
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/config")
+	if got, _ := ConfigPath("known-devices.csv"); got != filepath.Join("/config", "nls", "known-devices.csv") {
+		t.Errorf("ConfigPath() with XDG_CONFIG_HOME = %q", got)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if got, _ := ConfigPath("known-devices.csv"); got != filepath.Join("/home/me", ".config", "nls", "known-devices.csv") {
+		t.Errorf("ConfigPath() without XDG_CONFIG_HOME = %q", got)
+	}
+}